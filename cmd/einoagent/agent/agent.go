@@ -18,34 +18,61 @@ package agent
 
 import (
 	"Eino-example/einoagent"
+	"Eino-example/pkg/chatmodel"
 	"Eino-example/pkg/mem"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/cloudwego/eino-ext/callbacks/langfuse"
 	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/embedding"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
 
-var memory = mem.GetDefaultMemory()
+// memories keeps the conversations and the facts of every user apart.
+var memories = newUserMemories(mem.DefaultConfig(), nil)
+
+// titler names the conversations after their first exchange, nil disables it.
+var titler mem.Titler
+
+// factExtractor learns the facts of the long-term memory after every turn,
+// nil disables it.
+var factExtractor mem.FactExtractor
 
 var cbHandler callbacks.Handler
 
 var once sync.Once
 
+var initErr error
+
+// agentRuntime holds the agent graph compiled at startup, shared by all requests.
+var agentRuntime *einoagent.Runtime
+
+// Init sets up the callbacks and builds the agent graph, build errors are
+// reported here at boot instead of on every chat.
+// The graph is rebuilt when .env changes, polled every AGENT_RELOAD_INTERVAL
+// (default 5s, 0 disables it).
+// The conversations are stored by MEMORY_BACKEND (jsonl or sqlite), the history
+// older than MEMORY_MAX_TOKENS is folded into a summary by the chat model,
+// unless MEMORY_SUMMARY=false, new conversations are named by the chat
+// model, unless MEMORY_TITLE=false, and, with MEMORY_FACTS=true, the facts about
+// the user are learned into the long-term memory and the ones related to the
+// question, above MEMORY_FACTS_SCORE_THRESHOLD, are recalled in the prompt.
+// Every user has their own conversations and facts.
 func Init() error {
-	var err error
 	once.Do(func() {
 		os.MkdirAll("log", 0755)
-		var f *os.File
-		f, err = os.OpenFile("log/eino.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		f, err := os.OpenFile("log/eino.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 		if err != nil {
+			initErr = err
 			return
 		}
 
@@ -79,70 +106,292 @@ func Init() error {
 		if len(callbackHandlers) > 0 {
 			callbacks.AppendGlobalHandlers(callbackHandlers...)
 		}
+
+		ctx := context.Background()
+		memCfg := mem.DefaultConfig()
+		summary := memCfg.MaxTokens > 0 && os.Getenv("MEMORY_SUMMARY") != "false"
+		title := os.Getenv("MEMORY_TITLE") != "false"
+		learn := os.Getenv("MEMORY_FACTS") == "true"
+		if summary || title || learn {
+			cm, err := chatmodel.NewFromEnv(ctx)
+			if err != nil {
+				initErr = fmt.Errorf("failed to create memory model: %w", err)
+				return
+			}
+			if summary {
+				memCfg.Summarizer = mem.NewChatModelSummarizer(cm)
+			}
+			if title {
+				titler = mem.NewChatModelTitler(cm)
+			}
+			if learn {
+				factExtractor = mem.NewChatModelFactExtractor(cm)
+			}
+		}
+		var (
+			agentOpts []einoagent.Option
+			embedder  embedding.Embedder
+		)
+		if learn {
+			embedder, err = einoagent.NewEmbedding(ctx)
+			if err != nil {
+				initErr = err
+				return
+			}
+		}
+		memories = newUserMemories(memCfg, embedder)
+		if learn {
+			agentOpts = append(agentOpts, einoagent.WithFactRetriever(&factRetriever{memories: memories}))
+		}
+		// the conversations stored before are opened, and imported, at boot
+		if _, err := memories.Memory(ctx); err != nil {
+			initErr = err
+			return
+		}
+
+		agentRuntime, err = einoagent.NewRuntime(ctx, agentOpts...)
+		if err != nil {
+			initErr = err
+			return
+		}
+
+		interval := 5 * time.Second
+		if v := os.Getenv("AGENT_RELOAD_INTERVAL"); v != "" {
+			interval, err = time.ParseDuration(v)
+			if err != nil {
+				initErr = fmt.Errorf("invalid env AGENT_RELOAD_INTERVAL=%s: %w", v, err)
+				return
+			}
+		}
+		if interval > 0 {
+			go agentRuntime.WatchEnvFile(ctx, ".env", interval)
+		}
 	})
-	return err
+	return initErr
 }
 
-func RunAgent(ctx context.Context, id string, msg string) (*schema.StreamReader[*schema.Message], error) {
+// RunAgent runs the agent graph with the conversation history of id and saves
+// the question, the tool steps and the answer to the memory,
+// opts are appended to the default call options, e.g. extra callbacks of a request.
+// The turn is saved before the returned stream ends.
+func RunAgent(ctx context.Context, id string, msg string, opts ...compose.Option) (*schema.StreamReader[*schema.Message], error) {
+	memory, err := memories.Memory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conversation, err := memory.GetConversation(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	firstTurn := len(conversation.Nodes()) == 0
+	return runTurn(ctx, conversation, schema.UserMessage(msg), false, firstTurn, opts...)
+}
 
-	runner, err := einoagent.BuildEinoAgent(ctx)
+// EditAgent sends msg in place of the user message messageID of the
+// conversation id, the new turn is saved on a branch beside the previous one.
+func EditAgent(ctx context.Context, id string, messageID string, msg string, opts ...compose.Option) (*schema.StreamReader[*schema.Message], error) {
+	conversation, err := getConversation(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build agent graph: %w", err)
+		return nil, err
+	}
+	if err := conversation.Edit(messageID); err != nil {
+		return nil, err
 	}
+	return runTurn(ctx, conversation, schema.UserMessage(msg), false, false, opts...)
+}
 
-	conversation := memory.GetConversation(id, true)
+// RegenerateAgent answers the last user message of the conversation id again,
+// the new answer is saved on a branch beside the previous one.
+func RegenerateAgent(ctx context.Context, id string, opts ...compose.Option) (*schema.StreamReader[*schema.Message], error) {
+	conversation, err := getConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := conversation.Regenerate()
+	if err != nil {
+		return nil, err
+	}
+	return runTurn(ctx, conversation, msg, true, false, opts...)
+}
+
+// getConversation returns mem.ErrConversationNotFound if id does not exist.
+func getConversation(ctx context.Context, id string) (*mem.Conversation, error) {
+	memory, err := memories.Memory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conversation, err := memory.GetConversation(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return nil, mem.ErrConversationNotFound
+	}
+	return conversation, nil
+}
 
+// runTurn answers user with the history of the conversation, user is already
+// the head of the conversation when regenerate is set.
+func runTurn(ctx context.Context, conversation *mem.Conversation, user *schema.Message, regenerate, firstTurn bool, opts ...compose.Option) (*schema.StreamReader[*schema.Message], error) {
+	history := conversation.GetMessages(ctx)
+	if regenerate {
+		history = history[:len(history)-1]
+	}
 	userMessage := &einoagent.UserMessage{
-		ID:      id,
-		Query:   msg,
-		History: conversation.GetMessages(),
+		ID:      conversation.ID,
+		Query:   user.Content,
+		History: history,
 	}
-	sr, err := runner.Stream(ctx, userMessage, compose.WithCallbacks(cbHandler))
+	trace := &TraceCollector{}
+	// the sources cited by the answer are saved with it
+	var (
+		sourcesMu sync.Mutex
+		sources   []*einoagent.Source
+	)
+	sourcesHandler := SourcesCallback(func(s []*einoagent.Source) {
+		sourcesMu.Lock()
+		sources = s
+		sourcesMu.Unlock()
+	})
+	opts = append([]compose.Option{
+		einoagent.WithAgentCallbacks(trace.Handler()),
+		compose.WithCallbacks(sourcesHandler).DesignateNode(einoagent.DocumentsNode),
+	}, opts...)
+	sr, err := StreamAgent(ctx, userMessage, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stream: %w", err)
+		return nil, err
 	}
 
-	srs := sr.Copy(2)
+	// the chunks are forwarded through a pipe, which is closed once the turn is saved
+	out, sw := schema.Pipe[*schema.Message](0)
 
 	go func() {
 		// for save to memory
 		fullMsgs := make([]*schema.Message, 0)
 
+		defer sw.Close()
 		defer func() {
-			// close stream if you used it
-			srs[1].Close()
+			sr.Close()
 
 			// add user input to history
-			conversation.Append(schema.UserMessage(msg))
+			if !regenerate {
+				conversation.Append(user)
+			}
 
+			// without an answer only the user input is saved
 			fullMsg, err := schema.ConcatMessages(fullMsgs)
 			if err != nil {
 				fmt.Println("error concatenating messages: ", err.Error())
+				return
+			}
+			sourcesMu.Lock()
+			if cited := einoagent.CitedSources(fullMsg.Content, sources); len(cited) > 0 {
+				mem.SetCitations(fullMsg, citations(cited))
+			}
+			sourcesMu.Unlock()
+			// add the tool calls and results of the agent to history
+			for _, step := range toolSteps(trace.Messages()) {
+				conversation.Append(step)
 			}
 			// add agent response to history
 			conversation.Append(fullMsg)
+
+			if firstTurn && titler != nil {
+				// the request may be done already, and the stream should not wait for it
+				go nameConversation(context.WithoutCancel(ctx), conversation.ID, []*schema.Message{user, fullMsg})
+			}
+			if factExtractor != nil {
+				go learnFacts(context.WithoutCancel(ctx), conversation.ID, []*schema.Message{user, fullMsg})
+			}
 		}()
 
-	outer:
 		for {
 			select {
 			case <-ctx.Done():
 				fmt.Println("context done", ctx.Err())
 				return
 			default:
-				chunk, err := srs[1].Recv()
-				if err != nil {
-					if errors.Is(err, io.EOF) {
-						break outer
-					}
+			}
+
+			chunk, err := sr.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					fmt.Println("error receiving message: ", err.Error())
+					sw.Send(nil, err)
 				}
+				return
+			}
 
-				fullMsgs = append(fullMsgs, chunk)
+			fullMsgs = append(fullMsgs, chunk)
+			if closed := sw.Send(chunk, nil); closed {
+				return
 			}
 		}
 	}()
 
-	return srs[0], nil
+	return out, nil
+}
+
+// nameConversation replaces the title derived from the first message with one
+// generated from the first exchange, unless the user renamed the conversation
+// while the title was generated.
+func nameConversation(ctx context.Context, id string, msgs []*schema.Message) {
+	title, err := titler.Title(ctx, msgs)
+	if err != nil {
+		log.Printf("[Memory] Error generating title of %s: %v\n", id, err)
+		return
+	}
+	memory, err := memories.Memory(ctx)
+	if err == nil {
+		_, err = memory.ReplaceDefaultTitle(ctx, id, title)
+	}
+	if err != nil {
+		log.Printf("[Memory] Error setting title of %s: %v\n", id, err)
+	}
+}
+
+func citations(sources []*einoagent.Source) []*mem.Citation {
+	citations := make([]*mem.Citation, 0, len(sources))
+	for _, s := range sources {
+		citations = append(citations, &mem.Citation{Index: s.Index, ID: s.ID, URI: s.URI, Headings: s.Headings})
+	}
+	return citations
+}
+
+// learnFacts remembers the new facts about the user stated in msgs.
+func learnFacts(ctx context.Context, id string, msgs []*schema.Message) {
+	facts, err := memories.Facts(ctx)
+	if err != nil {
+		log.Printf("[Memory] Error opening facts of %s: %v\n", id, err)
+		return
+	}
+	learned, err := facts.Learn(ctx, factExtractor, id, msgs)
+	if err != nil {
+		log.Printf("[Memory] Error learning facts of %s: %v\n", id, err)
+		return
+	}
+	if len(learned) > 0 {
+		log.Printf("[Memory] Learned %d facts from %s\n", len(learned), id)
+	}
+}
+
+// toolSteps drops the final answer from the trace of the agent, it is saved
+// from the output stream instead.
+func toolSteps(trace []*schema.Message) []*schema.Message {
+	if n := len(trace); n > 0 && trace[n-1].Role == schema.Assistant && len(trace[n-1].ToolCalls) == 0 {
+		return trace[:n-1]
+	}
+	return trace
+}
+
+// StreamAgent runs the agent graph with the history carried by userMessage,
+// nothing is saved to the memory.
+func StreamAgent(ctx context.Context, userMessage *einoagent.UserMessage, opts ...compose.Option) (*schema.StreamReader[*schema.Message], error) {
+	sr, err := agentRuntime.Runner().Stream(ctx, userMessage, append([]compose.Option{compose.WithCallbacks(cbHandler)}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream: %w", err)
+	}
+	return sr, nil
 }
 
 type LogCallbackConfig struct {
@@ -181,3 +430,16 @@ func LogCallback(config *LogCallbackConfig) callbacks.Handler {
 	})
 	return builder.Build()
 }
+
+// SourcesCallback reports the numbered sources of the documents rendered into the prompt,
+// it should be designated to einoagent.DocumentsNode.
+func SourcesCallback(fn func(sources []*einoagent.Source)) callbacks.Handler {
+	return callbacks.NewHandlerBuilder().
+		OnStartFn(func(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+			if docs, ok := input.([]*schema.Document); ok {
+				fn(einoagent.DocumentSources(docs))
+			}
+			return ctx
+		}).
+		Build()
+}
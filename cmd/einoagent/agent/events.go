@@ -0,0 +1,265 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"Eino-example/einoagent"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/hertz-contrib/sse"
+)
+
+// SSE event types of /api/chat, the data of every event is a json object.
+const (
+	// EventToken is a chunk of the answer, data: {"content": "..."}
+	EventToken = "token"
+	// EventToolCall is sent when the agent starts calling a tool, data: ToolCallEvent
+	EventToolCall = "tool_call"
+	// EventToolResult is sent when a tool returns, data: ToolResultEvent
+	EventToolResult = "tool_result"
+	// EventRetrieval lists the documents rendered into the prompt, data: {"sources": [...]}
+	EventRetrieval = "retrieval"
+	// EventSources lists the documents cited by the answer, data: {"sources": [...]}
+	EventSources = "sources"
+	// EventUsage is the token usage of one chat model call, data: UsageEvent
+	EventUsage = "usage"
+	// EventError ends the stream with an error, data: {"message": "..."}
+	EventError = "error"
+	// EventDone ends the stream normally, data: {"id": "..."}
+	EventDone = "done"
+)
+
+type TokenEvent struct {
+	Content string `json:"content"`
+}
+
+type ToolCallEvent struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type ToolResultEvent struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type SourcesEvent struct {
+	Sources []*einoagent.Source `json:"sources"`
+}
+
+type UsageEvent struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type ErrorEvent struct {
+	Message string `json:"message"`
+}
+
+type DoneEvent struct {
+	ID string `json:"id"`
+	// UserMessageID and MessageID are the saved question and answer of the turn.
+	UserMessageID string `json:"user_message_id,omitempty"`
+	MessageID     string `json:"message_id,omitempty"`
+}
+
+// EventStream publishes typed events to a sse stream, it is safe to be used
+// concurrently by the callbacks of the agent and the handler.
+type EventStream struct {
+	mu     sync.Mutex
+	stream *sse.Stream
+	closed bool
+}
+
+func NewEventStream(stream *sse.Stream) *EventStream {
+	return &EventStream{stream: stream}
+}
+
+// Emit publishes an event with payload encoded as json.
+func (e *EventStream) Emit(event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return errors.New("event stream closed")
+	}
+	return e.stream.Publish(&sse.Event{
+		Event: event,
+		Data:  data,
+	})
+}
+
+// Close stops publishing, events emitted by callbacks still running are dropped.
+func (e *EventStream) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+}
+
+// AgentCallback emits tool_call and tool_result events of the tools run by the agent.
+func AgentCallback(events *EventStream) callbacks.Handler {
+	emit := func(event string, payload any) {
+		if err := events.Emit(event, payload); err != nil {
+			log.Printf("[Chat] Error publishing %s event: %v\n", event, err)
+		}
+	}
+
+	builder := callbacks.NewHandlerBuilder()
+	builder.OnStartFn(func(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+		if info.Component != components.ComponentOfTool {
+			return ctx
+		}
+		if in := tool.ConvCallbackInput(input); in != nil {
+			emit(EventToolCall, &ToolCallEvent{
+				ID:        compose.GetToolCallID(ctx),
+				Name:      info.Name,
+				Arguments: in.ArgumentsInJSON,
+			})
+		}
+		return ctx
+	})
+	builder.OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+		if info.Component != components.ComponentOfTool {
+			return ctx
+		}
+		if out := tool.ConvCallbackOutput(output); out != nil {
+			emit(EventToolResult, &ToolResultEvent{
+				ID:     compose.GetToolCallID(ctx),
+				Name:   info.Name,
+				Result: out.Response,
+			})
+		}
+		return ctx
+	})
+	builder.OnErrorFn(func(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+		if info.Component == components.ComponentOfTool {
+			emit(EventToolResult, &ToolResultEvent{
+				ID:    compose.GetToolCallID(ctx),
+				Name:  info.Name,
+				Error: err.Error(),
+			})
+		}
+		return ctx
+	})
+	return builder.Build()
+}
+
+// UsageCollector sums the token usage of all the chat model calls of one run,
+// including query rewriting and reranking.
+type UsageCollector struct {
+	mu    sync.Mutex
+	wg    sync.WaitGroup
+	total model.TokenUsage
+
+	// OnUsage is called with the usage of every chat model call if set.
+	OnUsage func(usage *model.TokenUsage)
+}
+
+// Handler returns the callback handler which collects the usage.
+func (u *UsageCollector) Handler() callbacks.Handler {
+	builder := callbacks.NewHandlerBuilder()
+	builder.OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+		if info.Component != components.ComponentOfChatModel {
+			return ctx
+		}
+		if out := model.ConvCallbackOutput(output); out != nil && out.TokenUsage != nil {
+			u.add(out.TokenUsage)
+		}
+		return ctx
+	})
+	builder.OnEndWithStreamOutputFn(func(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+		if info.Component != components.ComponentOfChatModel {
+			output.Close()
+			return ctx
+		}
+
+		// read the usage from the copy of the model stream, it must be closed after reading
+		u.wg.Add(1)
+		go func() {
+			defer u.wg.Done()
+			defer output.Close()
+
+			var usage *model.TokenUsage
+			for {
+				chunk, err := output.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					return
+				}
+				if out := model.ConvCallbackOutput(chunk); out != nil && out.TokenUsage != nil {
+					usage = out.TokenUsage
+				}
+			}
+			if usage != nil {
+				u.add(usage)
+			}
+		}()
+		return ctx
+	})
+	return builder.Build()
+}
+
+// Total waits for the model streams being read and returns the summed usage,
+// it should be called after the output of the agent is fully received.
+func (u *UsageCollector) Total() model.TokenUsage {
+	u.wg.Wait()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.total
+}
+
+func (u *UsageCollector) add(usage *model.TokenUsage) {
+	u.mu.Lock()
+	u.total.PromptTokens += usage.PromptTokens
+	u.total.CompletionTokens += usage.CompletionTokens
+	u.total.TotalTokens += usage.TotalTokens
+	u.mu.Unlock()
+
+	if u.OnUsage != nil {
+		u.OnUsage(usage)
+	}
+}
+
+func newUsageEvent(usage *model.TokenUsage) *UsageEvent {
+	return &UsageEvent{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"Eino-example/pkg/auth"
+	"Eino-example/pkg/mem"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sync"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+)
+
+// userMemories keeps the conversations and the facts of every user apart, by
+// the user of the request context. The default user keeps the ones at the root
+// of the memory dir, so the data stored before AUTH_TOKENS was set belongs to
+// it, the other users keep theirs under users/<user>, as the task namespaces do.
+type userMemories struct {
+	cfg mem.Config
+	// embedder embeds the facts, nil disables the long-term memory.
+	embedder embedding.Embedder
+
+	mu       sync.Mutex
+	memories map[string]mem.Memory
+	facts    map[string]*mem.FactStore
+}
+
+func newUserMemories(cfg mem.Config, embedder embedding.Embedder) *userMemories {
+	return &userMemories{
+		cfg:      cfg,
+		embedder: embedder,
+		memories: map[string]mem.Memory{},
+		facts:    map[string]*mem.FactStore{},
+	}
+}
+
+func (u *userMemories) dir(user string) (string, error) {
+	if user == auth.DefaultUser {
+		return u.cfg.Dir, nil
+	}
+	name := url.PathEscape(user)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid user: %q", user)
+	}
+	return filepath.Join(u.cfg.Dir, "users", name), nil
+}
+
+// Memory returns the conversations of the user of ctx.
+func (u *userMemories) Memory(ctx context.Context) (mem.Memory, error) {
+	user := auth.UserFrom(ctx)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if m, ok := u.memories[user]; ok {
+		return m, nil
+	}
+	dir, err := u.dir(user)
+	if err != nil {
+		return nil, err
+	}
+	cfg := u.cfg
+	if user != auth.DefaultUser {
+		cfg.Dir, cfg.Path = dir, ""
+	}
+	m, err := mem.NewMemory(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	u.memories[user] = m
+	return m, nil
+}
+
+// Facts returns the long-term memory of the user of ctx, nil if it is disabled.
+func (u *userMemories) Facts(ctx context.Context) (*mem.FactStore, error) {
+	if u.embedder == nil {
+		return nil, nil
+	}
+	user := auth.UserFrom(ctx)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if s, ok := u.facts[user]; ok {
+		return s, nil
+	}
+	dir, err := u.dir(user)
+	if err != nil {
+		return nil, err
+	}
+	s, err := mem.NewFactStore(filepath.Join(dir, "facts.json"), u.embedder)
+	if err != nil {
+		return nil, err
+	}
+	u.facts[user] = s
+	return s, nil
+}
+
+// factRetriever retrieves the facts of the user of the context.
+type factRetriever struct {
+	memories *userMemories
+}
+
+func (r *factRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	facts, err := r.memories.Facts(ctx)
+	if err != nil || facts == nil {
+		return nil, err
+	}
+	return facts.Retrieve(ctx, query, opts...)
+}
@@ -17,18 +17,28 @@
 package agent
 
 import (
+	"Eino-example/einoagent"
+	"Eino-example/pkg/auth"
 	"Eino-example/pkg/mem"
 	"bufio"
 	"context"
 	"embed"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"mime"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"github.com/cloudwego/hertz/pkg/route"
@@ -48,11 +58,26 @@ func BindRoutes(r *route.RouterGroup) error {
 		return err
 	}
 
-	// API 路由
-	r.GET("/api/chat", HandleChat)
-	r.GET("/api/log", HandleLog)
-	r.GET("/api/history", HandleHistory)
-	r.DELETE("/api/history", HandleDeleteHistory)
+	// API 路由，设置 AUTH_TOKENS 后需要认证，会话历史和长期记忆按认证用户隔离，
+	// agent 调用的 task_manager 工具访问认证用户的 task
+	tokens, err := auth.TokensFromEnv()
+	if err != nil {
+		return err
+	}
+	api := r.Group("/api", tokens.Middleware())
+	api.GET("/chat", HandleChat)
+	api.GET("/chat/edit", HandleEditChat)
+	api.GET("/chat/regenerate", HandleRegenerateChat)
+	api.GET("/log", HandleLog)
+	api.GET("/history", HandleHistory)
+	api.GET("/history/search", HandleSearchHistory)
+	api.PATCH("/history", HandleUpdateHistory)
+	api.POST("/history/checkout", HandleCheckoutHistory)
+	api.GET("/history/export", HandleExportHistory)
+	api.POST("/history/import", HandleImportHistory)
+	api.DELETE("/history", HandleDeleteHistory)
+	api.GET("/facts", HandleFacts)
+	api.DELETE("/facts", HandleDeleteFact)
 
 	// 静态文件服务
 	r.GET("/", func(ctx context.Context, c *app.RequestContext) {
@@ -94,80 +119,462 @@ func HandleChat(ctx context.Context, c *app.RequestContext) {
 		})
 		return
 	}
+	if !validID(c, id) {
+		return
+	}
 
 	log.Printf("[Chat] Starting chat with ID: %s, Message: %s\n", id, message)
 
-	sr, err := RunAgent(ctx, id, message)
-	if err != nil {
-		log.Printf("[Chat] Error running agent: %v\n", err)
-		c.JSON(consts.StatusInternalServerError, map[string]string{
+	streamChat(ctx, c, id, func(opts ...compose.Option) (*schema.StreamReader[*schema.Message], error) {
+		return RunAgent(ctx, id, message, opts...)
+	})
+}
+
+// HandleEditChat sends message in place of the user message message_id, the
+// new turn is a branch beside the previous one.
+func HandleEditChat(ctx context.Context, c *app.RequestContext) {
+	id := c.Query("id")
+	messageID := c.Query("message_id")
+	message := c.Query("message")
+	if id == "" || messageID == "" || message == "" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
 			"status": "error",
-			"error":  err.Error(),
+			"error":  "missing id, message_id or message parameter",
 		})
 		return
 	}
+	if !validID(c, id) {
+		return
+	}
 
-	s := sse.NewStream(c)
+	log.Printf("[Chat] Editing message %s of chat ID: %s, Message: %s\n", messageID, id, message)
+
+	streamChat(ctx, c, id, func(opts ...compose.Option) (*schema.StreamReader[*schema.Message], error) {
+		return EditAgent(ctx, id, messageID, message, opts...)
+	})
+}
+
+// HandleRegenerateChat answers the last user message again, the new answer is
+// a branch beside the previous one.
+func HandleRegenerateChat(ctx context.Context, c *app.RequestContext) {
+	id := c.Query("id")
+	if id == "" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"status": "error",
+			"error":  "missing id parameter",
+		})
+		return
+	}
+	if !validID(c, id) {
+		return
+	}
+
+	log.Printf("[Chat] Regenerating the answer of chat ID: %s\n", id)
+
+	streamChat(ctx, c, id, func(opts ...compose.Option) (*schema.StreamReader[*schema.Message], error) {
+		return RegenerateAgent(ctx, id, opts...)
+	})
+}
+
+// streamChat sends the events of the turn run by run as sse, run is called with
+// the callbacks of the events.
+func streamChat(ctx context.Context, c *app.RequestContext, id string, run func(opts ...compose.Option) (*schema.StreamReader[*schema.Message], error)) {
+	events := NewEventStream(sse.NewStream(c))
 	defer func() {
-		sr.Close()
+		events.Close()
 		c.Flush()
 
 		log.Printf("[Chat] Finished chat with ID: %s\n", id)
 	}()
 
-outer:
+	emit := func(event string, payload any) bool {
+		if err := events.Emit(event, payload); err != nil {
+			log.Printf("[Chat] Error publishing %s event: %v\n", event, err)
+			return false
+		}
+		return true
+	}
+
+	// the numbered sources of the retrieved documents are sent as a retrieval event,
+	// the ones cited by the answer are sent again after the answer.
+	var (
+		sourcesMu sync.Mutex
+		sources   []*einoagent.Source
+	)
+	sourcesHandler := SourcesCallback(func(s []*einoagent.Source) {
+		sourcesMu.Lock()
+		sources = s
+		sourcesMu.Unlock()
+		emit(EventRetrieval, &SourcesEvent{Sources: s})
+	})
+
+	usage := &UsageCollector{
+		OnUsage: func(u *model.TokenUsage) {
+			emit(EventUsage, newUsageEvent(u))
+		},
+	}
+
+	sr, err := run(
+		compose.WithCallbacks(AgentCallback(events), usage.Handler()),
+		compose.WithCallbacks(sourcesHandler).DesignateNode(einoagent.DocumentsNode),
+	)
+	if err != nil {
+		log.Printf("[Chat] Error running agent: %v\n", err)
+		emit(EventError, &ErrorEvent{Message: err.Error()})
+		return
+	}
+	defer sr.Close()
+
+	var answer strings.Builder
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf("[Chat] Context done for chat ID: %s\n", id)
 			return
 		default:
-			msg, err := sr.Recv()
-			if errors.Is(err, io.EOF) {
-				log.Printf("[Chat] EOF received for chat ID: %s\n", id)
-				break outer
-			}
-			if err != nil {
-				log.Printf("[Chat] Error receiving message: %v\n", err)
-				break outer
-			}
+		}
 
-			err = s.Publish(&sse.Event{
-				Data: []byte(msg.Content),
-			})
-			if err != nil {
-				log.Printf("[Chat] Error publishing message: %v\n", err)
-				break outer
-			}
+		msg, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			log.Printf("[Chat] EOF received for chat ID: %s\n", id)
+			break
+		}
+		if err != nil {
+			log.Printf("[Chat] Error receiving message: %v\n", err)
+			emit(EventError, &ErrorEvent{Message: err.Error()})
+			return
+		}
+
+		answer.WriteString(msg.Content)
+		if msg.Content == "" {
+			continue
+		}
+		if !emit(EventToken, &TokenEvent{Content: msg.Content}) {
+			return
+		}
+	}
+
+	sourcesMu.Lock()
+	cited := einoagent.CitedSources(answer.String(), sources)
+	sourcesMu.Unlock()
+	emit(EventSources, &SourcesEvent{Sources: cited})
+	// the turn is saved once the stream ends
+	done := &DoneEvent{ID: id}
+	done.UserMessageID, done.MessageID = lastTurn(ctx, id)
+	emit(EventDone, done)
+}
+
+// validID writes a 400 and returns false if id can not be a conversation id.
+func validID(c *app.RequestContext, id string) bool {
+	if err := mem.ValidateConversationID(id); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// lastTurn returns the IDs of the last user message and of the answer on the
+// active branch of the conversation id.
+func lastTurn(ctx context.Context, id string) (string, string) {
+	conversation, err := getConversation(ctx, id)
+	if err != nil {
+		log.Printf("[Chat] Error getting conversation %s: %v\n", id, err)
+		return "", ""
+	}
+	path := conversation.Path()
+	if len(path) == 0 {
+		return "", ""
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].Message.Role == schema.User {
+			return path[i].ID, path[len(path)-1].ID
 		}
 	}
+	return "", path[len(path)-1].ID
 }
 
 func HandleHistory(ctx context.Context, c *app.RequestContext) {
-	// query: id => get history, none => list all
+	// query: id => get history, none => list a page of limit and offset
+	// tool_steps: collapse (default) => user and answer messages only,
+	// expand => with the tool calls and tool results of the agent
 	id := c.Query("id")
+	memory := userMemory(ctx, c)
+	if memory == nil {
+		return
+	}
 
 	if id == "" {
-		ids := mem.GetDefaultMemory().ListConversations()
+		opts, err := listOptions(c)
+		if err != nil {
+			c.JSON(consts.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		infos, total, err := memory.ListConversations(ctx, opts)
+		if err != nil {
+			c.JSON(consts.StatusInternalServerError, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
 
+		ids := make([]string, 0, len(infos))
+		for _, info := range infos {
+			ids = append(ids, info.ID)
+		}
 		c.JSON(consts.StatusOK, map[string]interface{}{
-			"ids": ids,
+			"ids":           ids,
+			"conversations": infos,
+			"total":         total,
 		})
 		return
 	}
 
-	conversation := mem.GetDefaultMemory().GetConversation(id, false)
+	if !validID(c, id) {
+		return
+	}
+	conversation, err := memory.GetConversation(ctx, id, false)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
 	if conversation == nil {
 		c.JSON(consts.StatusNotFound, map[string]string{
 			"error": "conversation not found",
 		})
 		return
 	}
+	writeConversation(c, conversation)
+}
+
+// HandleCheckoutHistory switches the conversation id to the branch through
+// message_id and returns it as HandleHistory does.
+func HandleCheckoutHistory(ctx context.Context, c *app.RequestContext) {
+	id := c.Query("id")
+	messageID := c.Query("message_id")
+	if id == "" || messageID == "" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "missing id or message_id parameter",
+		})
+		return
+	}
+	if !validID(c, id) {
+		return
+	}
+
+	conversation, err := getConversation(ctx, id)
+	if err == nil {
+		err = conversation.Checkout(messageID)
+	}
+	if err != nil {
+		status := consts.StatusInternalServerError
+		if errors.Is(err, mem.ErrConversationNotFound) || errors.Is(err, mem.ErrMessageNotFound) {
+			status = consts.StatusNotFound
+		}
+		c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	writeConversation(c, conversation)
+}
+
+// HandleExportHistory downloads the conversation id.
+// format: markdown (default) => the active branch, with the tool steps folded
+// and the cited sources, json => a mem.Bundle with all the branches, which
+// HandleImportHistory takes back
+func HandleExportHistory(ctx context.Context, c *app.RequestContext) {
+	id := c.Query("id")
+	if id == "" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "missing id parameter",
+		})
+		return
+	}
+	if !validID(c, id) {
+		return
+	}
+	format := c.DefaultQuery("format", "markdown")
+	if format != "markdown" && format != "json" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "invalid format parameter: " + format,
+		})
+		return
+	}
+
+	conversation, err := getConversation(ctx, id)
+	if err != nil {
+		status := consts.StatusInternalServerError
+		if errors.Is(err, mem.ErrConversationNotFound) {
+			status = consts.StatusNotFound
+		}
+		c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, id))
+		c.JSON(consts.StatusOK, conversation.Bundle())
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, id))
+	c.Data(consts.StatusOK, "text/markdown; charset=utf-8", []byte(conversation.Markdown()))
+}
+
+// HandleImportHistory recreates a conversation from the mem.Bundle in the body
+// under a new id.
+func HandleImportHistory(ctx context.Context, c *app.RequestContext) {
+	var bundle mem.Bundle
+	if err := json.Unmarshal(c.Request.Body(), &bundle); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "invalid request body: " + err.Error(),
+		})
+		return
+	}
+	memory := userMemory(ctx, c)
+	if memory == nil {
+		return
+	}
+
+	conversation, err := mem.ImportBundle(ctx, memory, &bundle)
+	if err != nil {
+		status := consts.StatusInternalServerError
+		if errors.Is(err, mem.ErrInvalidBundle) {
+			status = consts.StatusBadRequest
+		}
+		c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]string{
+		"status": "success",
+		"id":     conversation.ID,
+	})
+}
+
+// writeConversation writes the active branch of the conversation, the
+// messages carry their IDs and siblings.
+// tool_steps: collapse (default) => user and answer messages only,
+// expand => with the tool calls and tool results of the agent
+func writeConversation(c *app.RequestContext, conversation *mem.Conversation) {
+	var collapse bool
+	switch steps := c.DefaultQuery("tool_steps", "collapse"); steps {
+	case "collapse":
+		collapse = true
+	case "expand":
+	default:
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "invalid tool_steps parameter: " + steps,
+		})
+		return
+	}
 
 	c.JSON(consts.StatusOK, map[string]interface{}{
-		"conversation": conversation,
+		"conversation": map[string]interface{}{
+			"id":       conversation.ID,
+			"title":    conversation.Title,
+			"messages": conversation.View(collapse),
+			"summary":  conversation.Summary,
+		},
 	})
+}
+
+func HandleSearchHistory(ctx context.Context, c *app.RequestContext) {
+	// query: q => messages containing q, a page of limit and offset
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "missing q parameter",
+		})
+		return
+	}
+	opts, err := listOptions(c)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	memory := userMemory(ctx, c)
+	if memory == nil {
+		return
+	}
+	results, err := memory.SearchMessages(ctx, query, opts)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
+
+// UpdateHistoryRequest renames or pins a conversation, nil fields are unchanged.
+type UpdateHistoryRequest struct {
+	Title  *string `json:"title"`
+	Pinned *bool   `json:"pinned"`
+}
+
+func HandleUpdateHistory(ctx context.Context, c *app.RequestContext) {
+	id := c.Query("id")
+	if id == "" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "missing id parameter",
+		})
+		return
+	}
+	if !validID(c, id) {
+		return
+	}
+	var req UpdateHistoryRequest
+	if err := json.Unmarshal(c.Request.Body(), &req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "invalid request body: " + err.Error(),
+		})
+		return
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "title must not be empty",
+		})
+		return
+	}
 
+	memory := userMemory(ctx, c)
+	if memory == nil {
+		return
+	}
+	var err error
+	if req.Title != nil {
+		err = memory.SetTitle(ctx, id, *req.Title)
+	}
+	if err == nil && req.Pinned != nil {
+		err = memory.SetPinned(ctx, id, *req.Pinned)
+	}
+	if err != nil {
+		status := consts.StatusInternalServerError
+		if errors.Is(err, mem.ErrConversationNotFound) {
+			status = consts.StatusNotFound
+		}
+		c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]string{
+		"status": "success",
+	})
 }
 
 func HandleDeleteHistory(ctx context.Context, c *app.RequestContext) {
@@ -178,13 +585,117 @@ func HandleDeleteHistory(ctx context.Context, c *app.RequestContext) {
 		})
 		return
 	}
+	if !validID(c, id) {
+		return
+	}
 
-	mem.GetDefaultMemory().DeleteConversation(id)
+	memory := userMemory(ctx, c)
+	if memory == nil {
+		return
+	}
+	if err := memory.DeleteConversation(ctx, id); err != nil {
+		status := consts.StatusInternalServerError
+		if errors.Is(err, mem.ErrConversationNotFound) {
+			status = consts.StatusNotFound
+		}
+		c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	// the facts learned from the conversation are forgotten with it
+	facts, err := memories.Facts(ctx)
+	if err == nil && facts != nil {
+		_, err = facts.ForgetConversation(id)
+	}
+	if err != nil {
+		log.Printf("[Memory] Error forgetting facts of %s: %v\n", id, err)
+	}
 	c.JSON(consts.StatusOK, map[string]string{
 		"status": "success",
 	})
 }
 
+// HandleFacts lists the facts of the long-term memory, in the order learned.
+func HandleFacts(ctx context.Context, c *app.RequestContext) {
+	facts, err := memories.Facts(ctx)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	list := []*mem.Fact{}
+	if facts != nil {
+		list = facts.Facts()
+	}
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"facts": list,
+	})
+}
+
+// HandleDeleteFact makes the long-term memory forget the fact id.
+func HandleDeleteFact(ctx context.Context, c *app.RequestContext) {
+	id := c.Query("id")
+	if id == "" {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "missing id parameter",
+		})
+		return
+	}
+
+	facts, err := memories.Facts(ctx)
+	if err == nil {
+		err = mem.ErrFactNotFound
+		if facts != nil {
+			err = facts.Forget(id)
+		}
+	}
+	if err != nil {
+		status := consts.StatusInternalServerError
+		if errors.Is(err, mem.ErrFactNotFound) {
+			status = consts.StatusNotFound
+		}
+		c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]string{
+		"status": "success",
+	})
+}
+
+// userMemory returns the conversations of the authenticated user, it writes the
+// error and returns nil if they cannot be opened.
+func userMemory(ctx context.Context, c *app.RequestContext) mem.Memory {
+	memory, err := memories.Memory(ctx)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	return memory
+}
+
+// listOptions reads the page of a list from the limit and offset query.
+func listOptions(c *app.RequestContext) (*mem.ListOptions, error) {
+	opts := &mem.ListOptions{}
+	for name, v := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		q := c.Query(name)
+		if q == "" {
+			continue
+		}
+		n, err := strconv.Atoi(q)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s parameter: %s", name, q)
+		}
+		*v = n
+	}
+	return opts, nil
+}
+
 func HandleLog(ctx context.Context, c *app.RequestContext) {
 	file, err := os.Open("log/eino.log")
 	if err != nil {
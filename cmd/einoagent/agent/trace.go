@@ -0,0 +1,140 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// TraceCollector records the messages produced inside the ReAct agent in
+// order: the assistant messages with tool calls, the tool results and the
+// final answer. Its handler is meant for einoagent.WithAgentCallbacks.
+type TraceCollector struct {
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+	msgs []*schema.Message
+}
+
+// Handler returns the callback handler which records the messages.
+func (t *TraceCollector) Handler() callbacks.Handler {
+	builder := callbacks.NewHandlerBuilder()
+	builder.OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+		switch info.Component {
+		case components.ComponentOfChatModel:
+			if out := model.ConvCallbackOutput(output); out != nil && out.Message != nil {
+				t.set(t.reserve(), out.Message)
+			}
+		case components.ComponentOfTool:
+			if out := tool.ConvCallbackOutput(output); out != nil {
+				t.set(t.reserve(), toolMessage(ctx, info, out.Response))
+			}
+		}
+		return ctx
+	})
+	builder.OnEndWithStreamOutputFn(func(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+		if info.Component != components.ComponentOfChatModel && info.Component != components.ComponentOfTool {
+			output.Close()
+			return ctx
+		}
+
+		// the slot is taken now to keep the order, it is filled once the copy
+		// of the stream is read, which must be closed after reading
+		i := t.reserve()
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			defer output.Close()
+
+			var chunks []*schema.Message
+			var response strings.Builder
+			for {
+				chunk, err := output.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					return
+				}
+				if info.Component == components.ComponentOfTool {
+					if out := tool.ConvCallbackOutput(chunk); out != nil {
+						response.WriteString(out.Response)
+					}
+				} else if out := model.ConvCallbackOutput(chunk); out != nil && out.Message != nil {
+					chunks = append(chunks, out.Message)
+				}
+			}
+
+			if info.Component == components.ComponentOfTool {
+				t.set(i, toolMessage(ctx, info, response.String()))
+				return
+			}
+			if msg, err := schema.ConcatMessages(chunks); err == nil {
+				t.set(i, msg)
+			}
+		}()
+		return ctx
+	})
+	return builder.Build()
+}
+
+// Messages waits for the streams being read and returns the recorded messages,
+// it should be called after the output of the agent is fully received.
+func (t *TraceCollector) Messages() []*schema.Message {
+	t.wg.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	msgs := make([]*schema.Message, 0, len(t.msgs))
+	for _, msg := range t.msgs {
+		// nil if the stream failed
+		if msg != nil {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func (t *TraceCollector) reserve() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.msgs = append(t.msgs, nil)
+	return len(t.msgs) - 1
+}
+
+func (t *TraceCollector) set(i int, msg *schema.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.msgs[i] = msg
+}
+
+func toolMessage(ctx context.Context, info *callbacks.RunInfo, response string) *schema.Message {
+	return schema.ToolMessage(response, compose.GetToolCallID(ctx), schema.WithToolName(info.Name))
+}
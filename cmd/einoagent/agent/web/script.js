@@ -1,3 +1,14 @@
+// 设置 AUTH_TOKENS 后 API 需要认证，令牌保存在 cookie 中，EventSource 也会带上
+const nativeFetch = window.fetch.bind(window);
+window.fetch = async (input, init) => {
+    const response = await nativeFetch(input, init);
+    if (response.status !== 401) return response;
+    const token = prompt('请输入访问令牌');
+    if (!token) return response;
+    document.cookie = `eino_token=${token}; path=/; SameSite=Strict`;
+    return nativeFetch(input, init);
+};
+
 document.addEventListener('DOMContentLoaded', () => {
     const messageInput = document.getElementById('message-input');
     const sendButton = document.getElementById('send-button');
@@ -53,6 +64,33 @@ document.addEventListener('DOMContentLoaded', () => {
         return content;  // 直接返回原始内容
     }
 
+    // 转义 HTML 特殊字符
+    function escapeHtml(text) {
+        const div = document.createElement('div');
+        div.textContent = text;
+        return div.innerHTML;
+    }
+
+    // 渲染回答引用的来源列表，编号与回答中的 [n] 对应
+    function renderSources(sources) {
+        if (!sources || sources.length === 0) {
+            return '';
+        }
+        const items = sources.map(source => {
+            const title = (source.headings && source.headings.length > 0)
+                ? source.headings.join(' > ')
+                : (source.uri || source.id);
+            const label = `[${source.index}] ${escapeHtml(title)}`;
+            const uri = source.uri || '';
+            const link = /^https?:\/\//.test(uri)
+                ? `<a href="${escapeHtml(uri)}" target="_blank" rel="noopener">${label}</a>`
+                : `<span title="${escapeHtml(uri)}">${label}</span>`;
+            const path = uri ? ` <span class="source-uri">${escapeHtml(uri)}</span>` : '';
+            return `<li>${link}${path}</li>`;
+        });
+        return `<div class="sources"><div class="sources-title">Sources</div><ol>${items.join('')}</ol></div>`;
+    }
+
     // 添加复制按钮到代码块
     function addCopyButtons() {
         // 只选择包含 code 标签的 pre 元素
@@ -134,30 +172,7 @@ document.addEventListener('DOMContentLoaded', () => {
         messageInput.value = '';
 
         // 创建新的历史记录项
-        const historyItem = document.createElement('div');
-        historyItem.className = 'chat-item p-3 hover:bg-gray-100 cursor-pointer rounded-lg mb-2 transition-colors flex justify-between items-start';
-        historyItem.dataset.chatId = chatId;
-        historyItem.innerHTML = `
-            <div class="flex-1 min-w-0 mr-2" onclick="event.stopPropagation()">
-                <div class="font-medium text-gray-900 truncate">Empty</div>
-                <div class="text-sm text-gray-500">ID: ${chatId.substring(0, 8)}...</div>
-            </div>
-            <button class="delete-chat p-1 hover:bg-red-100 rounded-lg transition-colors" onclick="event.stopPropagation()">
-                <svg class="w-5 h-5 text-red-500" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-                    <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16" />
-                </svg>
-            </button>
-        `;
-        
-        // 添加删除按钮事件
-        const deleteButton = historyItem.querySelector('.delete-chat');
-        deleteButton.addEventListener('click', (e) => {
-            e.stopPropagation();
-            deleteConversation(chatId, historyItem);
-        });
-        
-        // 添加点击事件
-        historyItem.querySelector('.flex-1').addEventListener('click', () => loadConversation(chatId));
+        const historyItem = createHistoryItem(chatId, 'Empty');
 
         // 将新对话添加到列表顶部
         if (chatHistory.firstChild) {
@@ -231,6 +246,118 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     }
 
+    // 创建历史记录项，标题用 textContent 写入，避免被当作 HTML
+    function createHistoryItem(id, title, pinned = false) {
+        const historyItem = document.createElement('div');
+        historyItem.className = 'chat-item p-3 hover:bg-gray-100 cursor-pointer rounded-lg mb-2 transition-colors flex justify-between items-start';
+        historyItem.dataset.chatId = id;
+        historyItem.innerHTML = `
+            <div class="flex-1 min-w-0 mr-2" onclick="event.stopPropagation()">
+                <div class="font-medium text-gray-900 truncate"></div>
+                <div class="text-sm text-gray-500">ID: ${id.substring(0, 8)}...</div>
+            </div>
+            <button class="pin-chat p-1 hover:bg-gray-200 rounded-lg transition-colors" title="Pin" onclick="event.stopPropagation()">
+                <svg class="w-5 h-5" stroke="currentColor" viewBox="0 0 24 24">
+                    <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M5 5a2 2 0 012-2h10a2 2 0 012 2v16l-7-3.5L5 21V5z" />
+                </svg>
+            </button>
+            <button class="export-chat p-1 hover:bg-gray-200 rounded-lg transition-colors" title="Export as Markdown" onclick="event.stopPropagation()">
+                <svg class="w-5 h-5 text-gray-500" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                    <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M4 16v1a3 3 0 003 3h10a3 3 0 003-3v-1m-4-4l-4 4m0 0l-4-4m4 4V4" />
+                </svg>
+            </button>
+            <button class="rename-chat p-1 hover:bg-gray-200 rounded-lg transition-colors" title="Rename" onclick="event.stopPropagation()">
+                <svg class="w-5 h-5 text-gray-500" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                    <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M11 5H6a2 2 0 00-2 2v11a2 2 0 002 2h11a2 2 0 002-2v-5m-1.414-9.414a2 2 0 112.828 2.828L11.828 15H9v-2.828l8.586-8.586z" />
+                </svg>
+            </button>
+            <button class="delete-chat p-1 hover:bg-red-100 rounded-lg transition-colors" onclick="event.stopPropagation()">
+                <svg class="w-5 h-5 text-red-500" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                    <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16" />
+                </svg>
+            </button>
+        `;
+        historyItem.querySelector('.font-medium').textContent = title;
+        setPinned(historyItem, pinned);
+
+        historyItem.querySelector('.pin-chat').addEventListener('click', (e) => {
+            e.stopPropagation();
+            pinConversation(id, historyItem);
+        });
+        historyItem.querySelector('.export-chat').addEventListener('click', (e) => {
+            e.stopPropagation();
+            window.location.href = `/agent/api/history/export?id=${id}&format=markdown`;
+        });
+        historyItem.querySelector('.rename-chat').addEventListener('click', (e) => {
+            e.stopPropagation();
+            renameConversation(id, historyItem);
+        });
+        historyItem.querySelector('.delete-chat').addEventListener('click', (e) => {
+            e.stopPropagation();
+            deleteConversation(id, historyItem);
+        });
+        historyItem.querySelector('.flex-1').addEventListener('click', () => loadConversation(id));
+        return historyItem;
+    }
+
+    function setPinned(historyItem, pinned) {
+        historyItem.dataset.pinned = pinned;
+        const icon = historyItem.querySelector('.pin-chat svg');
+        icon.setAttribute('fill', pinned ? 'currentColor' : 'none');
+        icon.classList.toggle('text-blue-500', pinned);
+        icon.classList.toggle('text-gray-400', !pinned);
+    }
+
+    async function updateConversation(id, fields) {
+        const response = await fetch(`/agent/api/history?id=${id}`, {
+            method: 'PATCH',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(fields)
+        });
+        if (!response.ok) {
+            const data = await response.json().catch(() => ({}));
+            throw new Error(data.error || response.statusText);
+        }
+    }
+
+    // 置顶或取消置顶，按服务端顺序重新排列列表
+    async function pinConversation(id, element) {
+        try {
+            await updateConversation(id, { pinned: element.dataset.pinned !== 'true' });
+            await loadHistory(false);
+        } catch (error) {
+            console.error('Error pinning conversation:', error);
+        }
+    }
+
+    // 重命名对话
+    async function renameConversation(id, element) {
+        const titleElement = element.querySelector('.font-medium');
+        const title = prompt('Rename conversation', titleElement.textContent);
+        if (title === null || !title.trim()) return;
+        try {
+            await updateConversation(id, { title: title.trim() });
+            titleElement.textContent = title.trim();
+        } catch (error) {
+            console.error('Error renaming conversation:', error);
+        }
+    }
+
+    // 刷新对话标题，首轮对话结束后服务端会生成标题
+    async function refreshTitle(id) {
+        try {
+            const response = await fetch('/agent/api/history');
+            const data = await response.json();
+            const conversation = (data.conversations || []).find(c => c.id === id);
+            const historyItem = document.querySelector(`[data-chat-id="${id}"]`);
+            if (conversation && historyItem) {
+                historyItem.querySelector('.font-medium').textContent = conversation.title;
+            }
+        } catch (error) {
+            console.error('Error refreshing title:', error);
+        }
+    }
+
     // 删除对话
     async function deleteConversation(id, element) {
         try {
@@ -260,26 +387,116 @@ document.addEventListener('DOMContentLoaded', () => {
             const data = await response.json();
             
             if (data.conversation) {
-                chatId = id;
-                currentConversation = data.conversation;
-                chatMessages.innerHTML = '';
-                
-                data.conversation.messages.forEach(msg => {
-                    appendMessage(msg.content, msg.role === 'user', false);
-                });
-                
-                highlightCurrentChat();
+                renderConversation(data.conversation);
             }
         } catch (error) {
             console.error('Error loading conversation:', error);
         }
     }
 
+    // 渲染对话的当前分支
+    function renderConversation(conversation) {
+        chatId = conversation.id;
+        currentConversation = conversation;
+        chatMessages.innerHTML = '';
+
+        conversation.messages.forEach((msg, i) => {
+            const messageDiv = appendMessage(msg.content, msg.role === 'user', false);
+            if (msg.extra && msg.extra.citations) {
+                messageDiv.querySelector('.message').insertAdjacentHTML('beforeend', renderSources(msg.extra.citations));
+            }
+            addMessageActions(messageDiv, msg, i === conversation.messages.length - 1);
+        });
+
+        highlightCurrentChat();
+    }
+
+    // 消息操作：在兄弟分支间切换、编辑用户消息、重新生成最后的回答
+    function addMessageActions(messageDiv, msg, isLast) {
+        const actions = document.createElement('div');
+        actions.className = 'message-actions';
+
+        if (msg.siblings && msg.siblings.length > 1) {
+            const prev = document.createElement('button');
+            prev.textContent = '‹';
+            prev.disabled = msg.branch === 0;
+            prev.addEventListener('click', () => checkoutBranch(msg.siblings[msg.branch - 1]));
+            const label = document.createElement('span');
+            label.textContent = `${msg.branch + 1}/${msg.siblings.length}`;
+            const next = document.createElement('button');
+            next.textContent = '›';
+            next.disabled = msg.branch === msg.siblings.length - 1;
+            next.addEventListener('click', () => checkoutBranch(msg.siblings[msg.branch + 1]));
+            actions.append(prev, label, next);
+        }
+
+        if (msg.role === 'user') {
+            const edit = document.createElement('button');
+            edit.textContent = 'Edit';
+            edit.addEventListener('click', () => editMessage(messageDiv, msg));
+            actions.appendChild(edit);
+        } else if (isLast) {
+            // 只有最后的回答可以重新生成
+            chatMessages.querySelectorAll('.regenerate-message').forEach(button => button.remove());
+            const regenerate = document.createElement('button');
+            regenerate.className = 'regenerate-message';
+            regenerate.textContent = 'Regenerate';
+            regenerate.addEventListener('click', regenerateAnswer);
+            actions.appendChild(regenerate);
+        }
+
+        messageDiv.querySelector('.message').appendChild(actions);
+    }
+
+    // 切换到消息所在的分支
+    async function checkoutBranch(messageId) {
+        try {
+            const response = await fetch(`/agent/api/history/checkout?id=${chatId}&message_id=${messageId}`, {
+                method: 'POST'
+            });
+            const data = await response.json();
+            if (!response.ok) {
+                throw new Error(data.error || response.statusText);
+            }
+            renderConversation(data.conversation);
+        } catch (error) {
+            console.error('Error switching branch:', error);
+        }
+    }
+
+    // 移除 messageDiv 及其后的消息
+    function removeMessagesFrom(messageDiv) {
+        while (messageDiv.nextSibling) {
+            messageDiv.nextSibling.remove();
+        }
+        messageDiv.remove();
+    }
+
+    // 编辑用户消息后重新发送，新的一轮成为原消息旁的分支
+    async function editMessage(messageDiv, msg) {
+        const message = prompt('Edit message', msg.content);
+        if (message === null || !message.trim()) return;
+        removeMessagesFrom(messageDiv);
+        await streamChat(`/agent/api/chat/edit?id=${chatId}&message_id=${msg.id}&message=${encodeURIComponent(message.trim())}`, message.trim(), false, true);
+    }
+
+    // 重新生成最后一个用户消息的回答
+    async function regenerateAnswer() {
+        const userDivs = chatMessages.querySelectorAll('[data-role="user"]');
+        if (userDivs.length === 0) return;
+        const lastUserDiv = userDivs[userDivs.length - 1];
+        while (lastUserDiv.nextSibling) {
+            lastUserDiv.nextSibling.remove();
+        }
+        await streamChat(`/agent/api/chat/regenerate?id=${chatId}`, null, false, true);
+    }
+
     // 添加消息到聊天区域
     function appendMessage(content, isUser, animate = true) {
         const processedContent = processMessageContent(content);
         const messageDiv = document.createElement('div');
         messageDiv.className = 'flex items-start gap-3 mb-4';
+        messageDiv.dataset.role = isUser ? 'user' : 'assistant';
 
         // 添加头像
         const avatar = document.createElement('div');
@@ -309,6 +526,7 @@ document.addEventListener('DOMContentLoaded', () => {
         messageDiv.appendChild(contentDiv);
         chatMessages.appendChild(messageDiv);
         chatMessages.scrollTop = chatMessages.scrollHeight;
+        return messageDiv;
     }
 
     async function sendMessage() {
@@ -317,13 +535,21 @@ document.addEventListener('DOMContentLoaded', () => {
 
         // 如果是新对话的第一条消息，更新历史记录标题
         const historyItem = document.querySelector(`[data-chat-id="${chatId}"]`);
-        if (historyItem && historyItem.querySelector('.font-medium').textContent === 'Empty') {
+        const firstMessage = historyItem && historyItem.querySelector('.font-medium').textContent === 'Empty';
+        if (firstMessage) {
             historyItem.querySelector('.font-medium').textContent = message;
         }
-
-        appendMessage(message, true);
         messageInput.value = '';
-        
+        await streamChat(`/agent/api/chat?id=${chatId}&message=${encodeURIComponent(message)}`, message, firstMessage, false);
+    }
+
+    // 流式显示一轮对话，message 为 null 时不显示用户消息（重新生成），
+    // reload 时结束后重新加载对话以显示新的分支
+    async function streamChat(url, message, firstMessage, reload) {
+        const currentChatId = chatId;
+
+        const userDiv = message === null ? null : appendMessage(message, true);
+
         // 禁用输入框和发送按钮，显示取消按钮
         messageInput.disabled = true;
         sendButton.disabled = true;
@@ -334,18 +560,21 @@ document.addEventListener('DOMContentLoaded', () => {
         try {
             console.log('Starting chat with ID:', chatId);
             
-            let hasReceivedMessage = false;
-            let currentMessageDiv = null;
-            let contentDiv = null;
+            let answerMessageDiv = null;
+            let stepsDiv = null;      // 工具调用与检索过程
+            let answerDiv = null;     // 回答内容
+            let footerDiv = null;     // 引用来源与 token 用量
             let accumulatedContent = '';
-            let isFirstChunk = true;
             let lastRenderTime = 0;
+            let sources = [];
+            let usage = {prompt_tokens: 0, completion_tokens: 0, total_tokens: 0};
+            const toolSteps = {};     // tool call id -> 步骤元素
 
             // 创建新的 AbortController
             abortController = new AbortController();
 
             // 使用 fetch 替代 EventSource，添加 signal
-            const response = await fetch(`/agent/api/chat?id=${chatId}&message=${encodeURIComponent(message)}`, {
+            const response = await fetch(url, {
                 signal: abortController.signal
             });
 
@@ -354,9 +583,140 @@ document.addEventListener('DOMContentLoaded', () => {
                 throw new Error(`HTTP error! status: ${response.status}`);
             }
 
+            // 收到第一个事件时创建新的消息框
+            function ensureMessageBox() {
+                if (answerDiv) return;
+
+                const messageDiv = document.createElement('div');
+                messageDiv.className = 'flex items-start gap-3 mb-4';
+                messageDiv.dataset.role = 'assistant';
+                answerMessageDiv = messageDiv;
+
+                // 添加头像
+                const avatar = document.createElement('div');
+                avatar.className = 'w-8 h-8 flex items-center justify-center rounded-full bg-gray-100 flex-shrink-0';
+                avatar.textContent = '🤖';
+                messageDiv.appendChild(avatar);
+
+                // 消息内容
+                const contentDiv = document.createElement('div');
+                contentDiv.className = 'message rounded-lg p-4 bg-gray-50';
+                stepsDiv = document.createElement('div');
+                stepsDiv.className = 'agent-steps';
+                answerDiv = document.createElement('div');
+                answerDiv.className = 'markdown-body';
+                footerDiv = document.createElement('div');
+                contentDiv.appendChild(stepsDiv);
+                contentDiv.appendChild(answerDiv);
+                contentDiv.appendChild(footerDiv);
+                messageDiv.appendChild(contentDiv);
+                chatMessages.appendChild(messageDiv);
+            }
+
+            function addStep(className, html) {
+                const step = document.createElement('div');
+                step.className = `agent-step ${className}`;
+                step.innerHTML = html;
+                stepsDiv.appendChild(step);
+                chatMessages.scrollTop = chatMessages.scrollHeight;
+                return step;
+            }
+
+            function renderContent() {
+                answerDiv.innerHTML = marked.parse(accumulatedContent);
+                addCopyButtons();
+                chatMessages.scrollTop = chatMessages.scrollHeight;
+                lastRenderTime = Date.now();
+            }
+
+            function renderFooter() {
+                let html = renderSources(sources);
+                if (usage.total_tokens > 0) {
+                    html += `<div class="usage">tokens: ${usage.prompt_tokens} prompt + ${usage.completion_tokens} completion = ${usage.total_tokens}</div>`;
+                }
+                footerDiv.innerHTML = html;
+            }
+
+            // 处理一个完整的 SSE 事件，data 为 JSON
+            function handleEvent(event, data) {
+                let payload;
+                try {
+                    payload = JSON.parse(data);
+                } catch (e) {
+                    console.error(`Failed to parse ${event} event:`, e);
+                    return;
+                }
+                ensureMessageBox();
+
+                switch (event) {
+                    case 'token': {
+                        accumulatedContent += payload.content;
+                        // 限制渲染频率
+                        const now = Date.now();
+                        if (now - lastRenderTime >= 100) {
+                            renderContent();
+                        } else {
+                            clearTimeout(window.renderTimeout);
+                            window.renderTimeout = setTimeout(renderContent, 100 - (now - lastRenderTime));
+                        }
+                        break;
+                    }
+                    case 'retrieval':
+                        addStep('step-retrieval', `📚 Retrieved ${(payload.sources || []).length} documents`);
+                        break;
+                    case 'tool_call': {
+                        const step = addStep('step-tool', `<details><summary>🔧 ${escapeHtml(payload.name)} <span class="step-status">running...</span></summary><pre>${escapeHtml(payload.arguments || '')}</pre></details>`);
+                        toolSteps[payload.id || payload.name] = step;
+                        break;
+                    }
+                    case 'tool_result': {
+                        const step = toolSteps[payload.id || payload.name];
+                        if (!step) break;
+                        step.querySelector('.step-status').textContent = payload.error ? 'failed' : 'done';
+                        const result = payload.error || payload.result || '';
+                        const maxLength = 2000;
+                        const text = result.length > maxLength ? result.slice(0, maxLength) + '...' : result;
+                        step.querySelector('details').insertAdjacentHTML('beforeend', `<pre class="${payload.error ? 'step-error' : ''}">${escapeHtml(text)}</pre>`);
+                        break;
+                    }
+                    case 'usage':
+                        usage.prompt_tokens += payload.prompt_tokens;
+                        usage.completion_tokens += payload.completion_tokens;
+                        usage.total_tokens += payload.total_tokens;
+                        break;
+                    case 'sources':
+                        sources = payload.sources || [];
+                        break;
+                    case 'error':
+                        addStep('step-error', `⚠️ ${escapeHtml(payload.message)}`);
+                        break;
+                    case 'done':
+                        clearTimeout(window.renderTimeout);
+                        renderContent();
+                        renderFooter();
+                        if (firstMessage) {
+                            // 标题在回答保存后异步生成
+                            setTimeout(() => refreshTitle(currentChatId), 3000);
+                        }
+                        if (reload) {
+                            loadConversation(currentChatId);
+                        } else {
+                            if (userDiv && payload.user_message_id) {
+                                addMessageActions(userDiv, {id: payload.user_message_id, role: 'user', content: message});
+                            }
+                            if (payload.message_id) {
+                                addMessageActions(answerMessageDiv, {id: payload.message_id, role: 'assistant'}, true);
+                            }
+                        }
+                        break;
+                }
+            }
+
             const reader = response.body.getReader();
             const decoder = new TextDecoder();
             let buffer = '';  // 用于存储不完整的 SSE 消息
+            let eventName = '';
+            let dataLines = [];
 
             try {
                 while (true) {
@@ -372,62 +732,21 @@ document.addEventListener('DOMContentLoaded', () => {
                     buffer = lines.pop() || '';
 
                     for (const line of lines) {
-                        // 解析 SSE 格式的行
-                        if (line.startsWith('data:')) {
-                            // 保留 data: 后的所有内容，包括前导空格
-                            const rawData = line.slice(5);  // 直接截取 'data:' 后的内容
-                            // console.log(`Raw SSE data: |${rawData}|`);
-                            hasReceivedMessage = true;
-
-                            // 如果是第一个 chunk，创建新的消息框
-                            if (isFirstChunk) {
-                                const messageDiv = document.createElement('div');
-                                messageDiv.className = 'flex items-start gap-3 mb-4';
-
-                                // 添加头像
-                                const avatar = document.createElement('div');
-                                avatar.className = 'w-8 h-8 flex items-center justify-center rounded-full bg-gray-100 flex-shrink-0';
-                                avatar.textContent = '🤖';
-                                messageDiv.appendChild(avatar);
-
-                                // 消息内容
-                                contentDiv = document.createElement('div');
-                                contentDiv.className = 'message markdown-body rounded-lg p-4 bg-gray-50';
-                                messageDiv.appendChild(contentDiv);
-                                chatMessages.appendChild(messageDiv);
-                                
-                                currentMessageDiv = contentDiv;
-                                isFirstChunk = false;
-                                accumulatedContent = rawData;
-                            } else {
-                                if (rawData === '') {
-                                    // 如果是空数据，添加换行符
-                                    accumulatedContent += '\n';
-                                } else {
-                                    // 否则直接拼接数据
-                                    accumulatedContent += rawData;
-                                }
-                            }
-
-                            // 限制渲染频率
-                            const now = Date.now();
-                            if (now - lastRenderTime >= 100) {
-                                renderContent();
-                            } else {
-                                clearTimeout(window.renderTimeout);
-                                window.renderTimeout = setTimeout(renderContent, 100 - (now - lastRenderTime));
+                        // 空行表示一个事件结束
+                        if (line === '') {
+                            if (dataLines.length > 0) {
+                                handleEvent(eventName || 'message', dataLines.join('\n'));
                             }
+                            eventName = '';
+                            dataLines = [];
+                        } else if (line.startsWith('event:')) {
+                            eventName = line.slice(6).trim();
+                        } else if (line.startsWith('data:')) {
+                            dataLines.push(line.slice(5));
                         }
                     }
                 }
 
-                function renderContent() {
-                    currentMessageDiv.innerHTML = marked.parse(accumulatedContent);
-                    addCopyButtons();
-                    chatMessages.scrollTop = chatMessages.scrollHeight;
-                    lastRenderTime = Date.now();
-                }
-
                 // 请求完成后，隐藏取消按钮，显示发送按钮
                 cancelButton.classList.add('hidden');
                 sendButton.classList.remove('hidden');
@@ -463,62 +782,28 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     });
 
-    // 加载历史对话列表
-    fetch('/agent/api/history')
-        .then(response => response.json())
-        .then(data => {
-            if (data.ids && data.ids.length > 0) {
-                chatHistory.innerHTML = ''; // 清空现有历史
-                let firstConversationId = null;
-
-                const loadPromises = data.ids.map(id => 
-                    fetch(`/agent/api/history?id=${id}`)
-                        .then(response => response.json())
-                        .then(convData => {
-                            if (convData.conversation) {
-                                const firstMessage = convData.conversation.messages.length > 0 
-                                    ? convData.conversation.messages[0].content 
-                                    : 'Empty';
-
-                                const historyItem = document.createElement('div');
-                                historyItem.className = 'chat-item p-3 hover:bg-gray-100 cursor-pointer rounded-lg mb-2 transition-colors flex justify-between items-start';
-                                historyItem.dataset.chatId = id;
-                                historyItem.innerHTML = `
-                                    <div class="flex-1 min-w-0 mr-2" onclick="event.stopPropagation()">
-                                        <div class="font-medium text-gray-900 truncate">${firstMessage}</div>
-                                        <div class="text-sm text-gray-500">ID: ${id.substring(0, 8)}...</div>
-                                    </div>
-                                    <button class="delete-chat p-1 hover:bg-red-100 rounded-lg transition-colors" onclick="event.stopPropagation()">
-                                        <svg class="w-5 h-5 text-red-500" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-                                            <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16" />
-                                        </svg>
-                                    </button>
-                                `;
-                                
-                                const deleteButton = historyItem.querySelector('.delete-chat');
-                                deleteButton.addEventListener('click', (e) => {
-                                    e.stopPropagation();
-                                    deleteConversation(id, historyItem);
-                                });
-                                
-                                historyItem.querySelector('.flex-1').addEventListener('click', () => loadConversation(id));
-                                chatHistory.appendChild(historyItem);
-
-                                // 记录第一个对话的ID
-                                if (firstConversationId === null) {
-                                    firstConversationId = id;
-                                }
-                            }
-                        })
-                );
+    // 加载历史对话列表，置顶的在前，其余按更新时间排列
+    async function loadHistory(selectFirst) {
+        try {
+            const response = await fetch('/agent/api/history');
+            const data = await response.json();
+            const conversations = data.conversations || [];
+            if (conversations.length === 0) return;
 
-                // 等待所有历史记录加载完成后，加载第一个对话
-                Promise.all(loadPromises).then(() => {
-                    if (firstConversationId) {
-                        loadConversation(firstConversationId);
-                    }
-                });
+            chatHistory.innerHTML = ''; // 清空现有历史
+            conversations.forEach(conversation => {
+                chatHistory.appendChild(createHistoryItem(conversation.id, conversation.title || 'Empty', conversation.pinned));
+            });
+
+            if (selectFirst) {
+                loadConversation(conversations[0].id);
+            } else {
+                highlightCurrentChat();
             }
-        })
-        .catch(error => console.error('Error loading history:', error));
+        } catch (error) {
+            console.error('Error loading history:', error);
+        }
+    }
+
+    loadHistory(true);
 }); 
\ No newline at end of file
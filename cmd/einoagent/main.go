@@ -18,6 +18,7 @@ package main
 
 import (
 	"Eino-example/cmd/einoagent/agent"
+	"Eino-example/cmd/einoagent/openai"
 	"Eino-example/cmd/einoagent/task"
 	"Eino-example/pkg/env"
 	"context"
@@ -66,6 +67,12 @@ func main() {
 		log.Fatal("failed to bind agent routes:", err)
 	}
 
+	// 注册 OpenAI 兼容接口路由组，OpenAI SDK 客户端使用 http://host:port/v1 作为 base url
+	openaiGroup := h.Group("/v1")
+	if err := openai.BindRoutes(openaiGroup); err != nil {
+		log.Fatal("failed to bind openai routes:", err)
+	}
+
 	// Redirect root path to /agent
 	h.GET("/", func(ctx context.Context, c *app.RequestContext) {
 		c.Redirect(302, []byte("/agent"))
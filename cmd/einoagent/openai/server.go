@@ -0,0 +1,286 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openai
+
+import (
+	"Eino-example/cmd/einoagent/agent"
+	"Eino-example/einoagent"
+	"Eino-example/pkg/auth"
+	"Eino-example/pkg/mem"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/google/uuid"
+	"github.com/hertz-contrib/sse"
+)
+
+// ModelID is the model listed by /v1/models, any model name in the request is accepted.
+const ModelID = "eino-agent"
+
+// HeaderConversationID selects the pkg/mem conversation, it takes precedence over the user field.
+const HeaderConversationID = "X-Conversation-ID"
+
+// BindRoutes registers the OpenAI compatible api, clients use <host>/v1 as the base url.
+// If AUTH_TOKENS is set, requests must carry the token of a user as the api key and the agent
+// runs as that user, otherwise if OPENAI_COMPAT_API_KEY is set, requests must carry it as a bearer token.
+func BindRoutes(r *route.RouterGroup) error {
+	if err := agent.Init(); err != nil {
+		return err
+	}
+
+	tokens, err := auth.TokensFromEnv()
+	if err != nil {
+		return err
+	}
+	if tokens.Enabled() {
+		r.Use(userMiddleware(tokens))
+	} else if apiKey := os.Getenv("OPENAI_COMPAT_API_KEY"); apiKey != "" {
+		r.Use(authMiddleware(apiKey))
+	}
+
+	r.GET("/models", HandleModels)
+	r.POST("/chat/completions", HandleChatCompletions)
+
+	return nil
+}
+
+func authMiddleware(apiKey string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		auth := string(c.GetHeader("Authorization"))
+		key := strings.TrimPrefix(auth, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			writeError(c, consts.StatusUnauthorized, "invalid_request_error", "invalid api key")
+			c.Abort()
+			return
+		}
+		c.Next(ctx)
+	}
+}
+
+func userMiddleware(tokens auth.Tokens) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		user, ok := tokens.Authenticate(c)
+		if !ok {
+			writeError(c, consts.StatusUnauthorized, "invalid_request_error", "invalid api key")
+			c.Abort()
+			return
+		}
+		c.Next(auth.WithUser(ctx, user))
+	}
+}
+
+func HandleModels(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{
+			{
+				"id":       ModelID,
+				"object":   "model",
+				"created":  0,
+				"owned_by": "eino",
+			},
+		},
+	})
+}
+
+// HandleChatCompletions answers with the Eino agent. With a conversation id
+// (X-Conversation-ID header or the user field) the history is read from and
+// saved to pkg/mem, and only the last user message of the request is used;
+// without it the request messages are used as the history and nothing is saved.
+func HandleChatCompletions(ctx context.Context, c *app.RequestContext) {
+	var req ChatCompletionRequest
+	if err := json.Unmarshal(c.Request.Body(), &req); err != nil {
+		writeError(c, consts.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	query, history, err := splitMessages(req.Messages)
+	if err != nil {
+		writeError(c, consts.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	id := string(c.GetHeader(HeaderConversationID))
+	if id == "" {
+		id = req.User
+	}
+	if id != "" {
+		if err := mem.ValidateConversationID(id); err != nil {
+			writeError(c, consts.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
+	}
+
+	usage := &agent.UsageCollector{}
+	opts := []compose.Option{compose.WithCallbacks(usage.Handler())}
+
+	log.Printf("[OpenAI] Starting chat completion, conversation ID: %s, stream: %v\n", id, req.Stream)
+
+	var sr *schema.StreamReader[*schema.Message]
+	if id != "" {
+		sr, err = agent.RunAgent(ctx, id, query, opts...)
+	} else {
+		sr, err = agent.StreamAgent(ctx, &einoagent.UserMessage{
+			ID:      uuid.New().String(),
+			Query:   query,
+			History: history,
+		}, opts...)
+	}
+	if err != nil {
+		log.Printf("[OpenAI] Error running agent: %v\n", err)
+		writeError(c, consts.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	defer sr.Close()
+
+	resp := &ChatCompletionResponse{
+		ID:      "chatcmpl-" + uuid.New().String(),
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+	}
+	if resp.Model == "" {
+		resp.Model = ModelID
+	}
+
+	if req.Stream {
+		streamCompletion(ctx, c, sr, resp, usage, req.StreamOptions != nil && req.StreamOptions.IncludeUsage)
+		return
+	}
+
+	var content strings.Builder
+	for {
+		msg, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Printf("[OpenAI] Error receiving message: %v\n", err)
+			writeError(c, consts.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		content.WriteString(msg.Content)
+	}
+
+	resp.Object = "chat.completion"
+	resp.Choices = []Choice{{
+		Message:      &ResponseMessage{Role: string(schema.Assistant), Content: content.String()},
+		FinishReason: of("stop"),
+	}}
+	resp.Usage = toUsage(usage)
+	c.JSON(consts.StatusOK, resp)
+}
+
+// streamCompletion sends chat.completion.chunk events ending with "[DONE]",
+// the usage chunk with empty choices is sent before it if includeUsage.
+func streamCompletion(ctx context.Context, c *app.RequestContext, sr *schema.StreamReader[*schema.Message],
+	resp *ChatCompletionResponse, usage *agent.UsageCollector, includeUsage bool) {
+	s := sse.NewStream(c)
+	defer c.Flush()
+
+	resp.Object = "chat.completion.chunk"
+	publish := func(chunk *ChatCompletionResponse) bool {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			log.Printf("[OpenAI] Error marshaling chunk: %v\n", err)
+			return false
+		}
+		if err := s.Publish(&sse.Event{Data: data}); err != nil {
+			log.Printf("[OpenAI] Error publishing chunk: %v\n", err)
+			return false
+		}
+		return true
+	}
+	chunk := func(delta *ResponseMessage, finishReason *string) *ChatCompletionResponse {
+		ck := *resp
+		ck.Choices = []Choice{{Delta: delta, FinishReason: finishReason}}
+		return &ck
+	}
+
+	if !publish(chunk(&ResponseMessage{Role: string(schema.Assistant)}, nil)) {
+		return
+	}
+
+	finishReason := "stop"
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		msg, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// the status code is already sent, report the error in the content
+			log.Printf("[OpenAI] Error receiving message: %v\n", err)
+			publish(chunk(&ResponseMessage{Content: "\n\nError: " + err.Error()}, nil))
+			break
+		}
+		if msg.Content == "" {
+			continue
+		}
+		if !publish(chunk(&ResponseMessage{Content: msg.Content}, nil)) {
+			return
+		}
+	}
+
+	if !publish(chunk(&ResponseMessage{}, &finishReason)) {
+		return
+	}
+	if includeUsage {
+		ck := *resp
+		ck.Choices = []Choice{}
+		ck.Usage = toUsage(usage)
+		if !publish(&ck) {
+			return
+		}
+	}
+	if err := s.Publish(&sse.Event{Data: []byte("[DONE]")}); err != nil {
+		log.Printf("[OpenAI] Error publishing done: %v\n", err)
+	}
+}
+
+func toUsage(usage *agent.UsageCollector) *Usage {
+	total := usage.Total()
+	return &Usage{
+		PromptTokens:     total.PromptTokens,
+		CompletionTokens: total.CompletionTokens,
+		TotalTokens:      total.TotalTokens,
+	}
+}
+
+func writeError(c *app.RequestContext, status int, typ, message string) {
+	c.JSON(status, &ErrorResponse{Error: ErrorDetail{Message: message, Type: typ}})
+}
+
+func of[T any](v T) *T {
+	return &v
+}
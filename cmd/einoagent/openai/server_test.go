@@ -0,0 +1,84 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "正确的 api key", header: "Bearer secret", want: true},
+		{name: "错误的 api key", header: "Bearer secreT"},
+		{name: "前缀相同的 api key", header: "Bearer secret2"},
+		{name: "没有 api key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			c := app.NewContext(0)
+			if tt.header != "" {
+				c.Request.Header.Set("Authorization", tt.header)
+			}
+			c.SetHandlers(app.HandlersChain{authMiddleware("secret"), func(ctx context.Context, c *app.RequestContext) {
+				called = true
+			}})
+			c.Next(context.Background())
+			assert.Equal(t, tt.want, called)
+			if !tt.want {
+				assert.Equal(t, 401, c.Response.StatusCode())
+			}
+		})
+	}
+}
+
+func TestHandleChatCompletionsInvalidConversationID(t *testing.T) {
+	body := `{"messages":[{"role":"user","content":"hi"}]}`
+	tests := []struct {
+		name   string
+		header string
+		user   string
+	}{
+		{name: "请求头中的 ../", header: "../c"},
+		{name: "user 字段中的 ../", user: "../c"},
+		{name: "包含路径分隔符", header: "a/b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.NewContext(0)
+			if tt.header != "" {
+				c.Request.Header.Set(HeaderConversationID, tt.header)
+			}
+			req := body
+			if tt.user != "" {
+				req = `{"user":"` + tt.user + `","messages":[{"role":"user","content":"hi"}]}`
+			}
+			c.Request.SetBody([]byte(req))
+			HandleChatCompletions(context.Background(), c)
+			assert.Equal(t, 400, c.Response.StatusCode())
+			assert.Contains(t, string(c.Response.Body()), "invalid conversation id")
+		})
+	}
+}
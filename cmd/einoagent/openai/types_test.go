@@ -0,0 +1,104 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Content
+		wantErr bool
+	}{
+		{name: "字符串", data: `"hello"`, want: "hello"},
+		{name: "空字符串", data: `""`, want: ""},
+		{name: "只保留文本片段", data: `[{"type":"text","text":"a"},{"type":"image_url","image_url":{"url":"x"}},{"type":"text","text":"b"}]`, want: "a\nb"},
+		{name: "空数组", data: `[]`, want: ""},
+		{name: "数字", data: `1`, wantErr: true},
+		{name: "对象", data: `{"type":"text","text":"a"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Content
+			err := json.Unmarshal([]byte(tt.data), &c)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, c)
+		})
+	}
+}
+
+func TestSplitMessages(t *testing.T) {
+	tests := []struct {
+		name        string
+		messages    []Message
+		wantQuery   string
+		wantHistory []*schema.Message
+		wantErr     bool
+	}{
+		{
+			name:      "只有一条用户消息",
+			messages:  []Message{{Role: "user", Content: "q"}},
+			wantQuery: "q",
+		},
+		{
+			name: "之前的用户和助手消息作为历史，system 和 tool 消息丢弃",
+			messages: []Message{
+				{Role: "system", Content: "be nice"},
+				{Role: "user", Content: "q1"},
+				{Role: "assistant", Content: "a1"},
+				{Role: "tool", Content: "r1"},
+				{Role: "user", Content: "q2"},
+			},
+			wantQuery:   "q2",
+			wantHistory: []*schema.Message{schema.UserMessage("q1"), schema.AssistantMessage("a1", nil)},
+		},
+		{
+			name: "最后一条用户消息之后的消息忽略",
+			messages: []Message{
+				{Role: "user", Content: "q1"},
+				{Role: "assistant", Content: "a1"},
+			},
+			wantQuery: "q1",
+		},
+		{name: "没有消息", wantErr: true},
+		{name: "没有用户消息", messages: []Message{{Role: "system", Content: "s"}}, wantErr: true},
+		{name: "用户消息为空白", messages: []Message{{Role: "user", Content: " \n"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, history, err := splitMessages(tt.messages)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantQuery, query)
+			assert.Equal(t, tt.wantHistory, history)
+		})
+	}
+}
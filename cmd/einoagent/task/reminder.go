@@ -0,0 +1,213 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"Eino-example/pkg/auth"
+	"Eino-example/pkg/tool/task"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/hertz-contrib/sse"
+)
+
+const (
+	defaultReminderInterval = time.Minute
+	defaultRemindBefore     = 15 * time.Minute
+
+	// heartbeatInterval 是没有提醒时发送心跳的间隔，用于及时发现断开的连接
+	heartbeatInterval = 30 * time.Second
+)
+
+// ReminderEvent 是推送给 Web 客户端的截止提醒
+type ReminderEvent struct {
+	Task    *task.Task `json:"task"`
+	Overdue bool       `json:"overdue"`
+	// ProjectID 是 task 所在的 project，用户自己的 task 为空
+	ProjectID string `json:"project_id,omitempty"`
+}
+
+// Scheduler 定期扫描所有用户和 project 即将截止的 task，每个截止时间只提醒一次，
+// 推送给 task 所有者或 project 成员已连接的客户端
+type Scheduler struct {
+	namespaces *task.Namespaces
+	interval   time.Duration
+	before     time.Duration
+
+	mu sync.Mutex
+	// subscribers 记录每个订阅 channel 所属的用户
+	subscribers map[chan *ReminderEvent]string
+}
+
+// NewScheduler 创建提醒调度器
+// 参数:
+//   - namespaces: 所有用户和 project 的 task 存储
+//   - interval: 扫描间隔
+//   - before: 截止前多久提醒
+func NewScheduler(namespaces *task.Namespaces, interval, before time.Duration) *Scheduler {
+	return &Scheduler{
+		namespaces:  namespaces,
+		interval:    interval,
+		before:      before,
+		subscribers: make(map[chan *ReminderEvent]string),
+	}
+}
+
+// newSchedulerFromEnv 根据 TASK_REMINDER_INTERVAL 和 TASK_REMIND_BEFORE 创建调度器，
+// TASK_REMINDER_INTERVAL=0 时不启用提醒
+func newSchedulerFromEnv(namespaces *task.Namespaces) (*Scheduler, error) {
+	interval, err := durationEnv("TASK_REMINDER_INTERVAL", defaultReminderInterval)
+	if err != nil {
+		return nil, err
+	}
+	before, err := durationEnv("TASK_REMIND_BEFORE", defaultRemindBefore)
+	if err != nil {
+		return nil, err
+	}
+	return NewScheduler(namespaces, interval, before), nil
+}
+
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid env %s=%s: %w", key, v, err)
+	}
+	return d, nil
+}
+
+// Run 按扫描间隔检查截止提醒，直到 ctx 结束
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.check(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.check(now)
+		}
+	}
+}
+
+// check 推送在 now+before 之前截止且尚未提醒过的 task
+func (s *Scheduler) check(now time.Time) {
+	all, err := s.namespaces.All()
+	if err != nil {
+		log.Printf("[task] open task storages failed, err=%v", err)
+		return
+	}
+	for _, ns := range all {
+		event := &ReminderEvent{}
+		if ns.Project != nil {
+			event.ProjectID = ns.Project.ID
+		}
+		members := ns.Members()
+		for _, t := range ns.Storage.DueTasks(now.Add(s.before)) {
+			event := *event
+			event.Task, event.Overdue = t, t.DueAt.Before(now)
+			// 没有送达任何相关用户（不在线或订阅 channel 已满）时保留提醒，下次扫描时再推送
+			if !s.publish(&event, members) {
+				break
+			}
+			if err := ns.Storage.MarkReminded(t.ID, *t.DueAt); err != nil {
+				log.Printf("[task] mark task %s reminded failed, err=%v", t.ID, err)
+			}
+		}
+	}
+}
+
+// publish 将提醒非阻塞地发给 users 的订阅者，返回是否至少送达了一个订阅者，
+// channel 已满而丢弃的不算送达
+func (s *Scheduler) publish(event *ReminderEvent, users []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivered := false
+	for ch, user := range s.subscribers {
+		if !slices.Contains(users, user) {
+			continue
+		}
+		select {
+		case ch <- event:
+			delivered = true
+		default:
+			log.Printf("[task] reminder subscriber is full, drop reminder of task %s", event.Task.ID)
+		}
+	}
+	return delivered
+}
+
+// Subscribe 订阅 user 可以看到的提醒，返回提醒 channel 和取消订阅函数
+func (s *Scheduler) Subscribe(user string) (<-chan *ReminderEvent, func()) {
+	ch := make(chan *ReminderEvent, 16)
+
+	s.mu.Lock()
+	s.subscribers[ch] = user
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+}
+
+// HandleReminders 以 SSE 推送截止提醒，事件名为 reminder，数据为 ReminderEvent
+func (s *Scheduler) HandleReminders(ctx context.Context, c *app.RequestContext) {
+	events, unsubscribe := s.Subscribe(auth.UserFrom(ctx))
+	defer unsubscribe()
+
+	stream := sse.NewStream(c)
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var event *sse.Event
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			event = &sse.Event{Event: "ping", Data: []byte("{}")}
+		case reminder := <-events:
+			data, err := json.Marshal(reminder)
+			if err != nil {
+				log.Printf("[task] marshal reminder failed, err=%v", err)
+				continue
+			}
+			event = &sse.Event{Event: "reminder", Data: data}
+		}
+		if err := stream.Publish(event); err != nil {
+			return
+		}
+	}
+}
@@ -17,11 +17,14 @@
 package task
 
 import (
+	"Eino-example/pkg/auth"
 	"Eino-example/pkg/tool/task"
 	"context"
 	"embed"
+	"errors"
 	"mime"
 	"path/filepath"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
@@ -35,15 +38,34 @@ var webContent embed.FS
 func BindRoutes(r *route.RouterGroup) error {
 	ctx := context.Background()
 
+	namespaces := task.GetDefaultNamespaces()
+	if namespaces == nil {
+		return errors.New("failed to open task storage")
+	}
 	taskTool, err := task.NewTaskToolImpl(ctx, &task.TaskToolConfig{
-		Storage: task.GetDefaultStorage(),
+		Namespaces: namespaces,
 	})
 	if err != nil {
 		return err
 	}
 
+	// 设置 AUTH_TOKENS 后 API 需要认证，每个用户访问自己的 task 和共享给自己的 project
+	tokens, err := auth.TokensFromEnv()
+	if err != nil {
+		return err
+	}
+	api := r.Group("/api", tokens.Middleware())
+
+	// 截止提醒
+	scheduler, err := newSchedulerFromEnv(namespaces)
+	if err != nil {
+		return err
+	}
+	go scheduler.Run(ctx)
+	api.GET("/reminders", scheduler.HandleReminders)
+
 	// API 处理
-	r.POST("/api", func(ctx context.Context, c *app.RequestContext) {
+	api.POST("", func(ctx context.Context, c *app.RequestContext) {
 		var req task.TaskRequest
 		if err := c.Bind(&req); err != nil {
 			c.JSON(consts.StatusBadRequest, map[string]string{
@@ -65,6 +87,32 @@ func BindRoutes(r *route.RouterGroup) error {
 		c.JSON(consts.StatusOK, resp)
 	})
 
+	// 导出有截止时间的 task 为 iCalendar，供日历应用订阅或导入
+	api.GET("/export.ics", func(ctx context.Context, c *app.RequestContext) {
+		storage, err := namespaces.Resolve(ctx, c.Query("project_id"), task.RoleViewer)
+		if err != nil {
+			status := consts.StatusInternalServerError
+			if errors.Is(err, task.ErrForbidden) {
+				status = consts.StatusForbidden
+			}
+			c.JSON(status, map[string]string{
+				"status": "error",
+				"error":  err.Error(),
+			})
+			return
+		}
+		tasks, err := storage.List(&task.ListParams{SortBy: task.SortDeadline})
+		if err != nil {
+			c.JSON(consts.StatusInternalServerError, map[string]string{
+				"status": "error",
+				"error":  err.Error(),
+			})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="tasks.ics"`)
+		c.Data(consts.StatusOK, "text/calendar; charset=utf-8", task.ExportICS(tasks, time.Now()))
+	})
+
 	// 静态文件服务
 	r.GET("/", func(ctx context.Context, c *app.RequestContext) {
 		content, err := webContent.ReadFile("web/index.html")
@@ -1,3 +1,25 @@
+// 设置 AUTH_TOKENS 后 API 需要认证，令牌保存在 cookie 中，EventSource 也会带上
+const nativeFetch = window.fetch.bind(window);
+window.fetch = async (input, init) => {
+    const response = await nativeFetch(input, init);
+    if (response.status !== 401) return response;
+    const token = prompt('请输入访问令牌');
+    if (!token) return response;
+    document.cookie = `eino_token=${token}; path=/; SameSite=Strict`;
+    return nativeFetch(input, init);
+};
+
+// 调用 task API，请求当前选中的项目，未选中项目时是自己的任务
+async function callTaskApi(body) {
+    const project = getQueryParams().project;
+    const response = await fetch('/task/api', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(project ? { ...body, project_id: project } : body)
+    });
+    return response.json();
+}
+
 // URL 参数处理
 function getQueryParams() {
     const params = new URLSearchParams(window.location.search);
@@ -5,7 +27,9 @@ function getQueryParams() {
         query: params.get('q') || '',
         is_done: params.get('done') === null ? null : params.get('done') === 'true',
         limit: parseInt(params.get('limit')) || 10,
-        sort: params.get('sort') || 'urgency'
+        sort: params.get('sort') || 'urgency',
+        tag: params.get('tag') || '',
+        project: params.get('project') || ''
     };
 }
 
@@ -22,6 +46,12 @@ function updateQueryParams(params) {
     
     if (params.sort !== 'urgency') url.searchParams.set('sort', params.sort);
     else url.searchParams.delete('sort');
+
+    if (params.tag) url.searchParams.set('tag', params.tag);
+    else url.searchParams.delete('tag');
+
+    if (params.project) url.searchParams.set('project', params.project);
+    else url.searchParams.delete('project');
     
     window.history.pushState({}, '', url);
 }
@@ -56,6 +86,22 @@ function calculateUrgency(task) {
     return hoursLeft;
 }
 
+const priorityLabels = {
+    low: { text: '低', class: 'bg-gray-100 text-gray-600' },
+    medium: { text: '中', class: 'bg-blue-100 text-blue-600' },
+    high: { text: '高', class: 'bg-orange-100 text-orange-600' },
+    urgent: { text: '紧急', class: 'bg-red-100 text-red-600' }
+};
+
+function parseTags(value) {
+    return value.split(/[,，]/).map(tag => tag.trim()).filter(tag => tag);
+}
+
+// 紧急度按截止时间在服务端排序，再在客户端细排
+function serverSortKey(sort) {
+    return sort === 'urgency' ? 'deadline' : sort;
+}
+
 function formatTimeDiff(diffMs) {
     const hours = Math.floor(Math.abs(diffMs) / (1000 * 60 * 60));
     const minutes = Math.floor((Math.abs(diffMs) % (1000 * 60 * 60)) / (1000 * 60));
@@ -109,15 +155,16 @@ function updateCountdown(element, deadline) {
 async function loadTasks() {
     const params = getQueryParams();
     try {
-        const response = await fetch('/task/api', {
-            method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({
-                action: 'list',
-                list: params
-            })
+        const data = await callTaskApi({
+            action: 'list',
+            list: {
+                query: params.query,
+                is_done: params.is_done,
+                limit: params.limit,
+                tags: params.tag ? [params.tag] : null,
+                sort_by: serverSortKey(params.sort)
+            }
         });
-        const data = await response.json();
         if (data.status === 'success') {
             renderTasks(data.task_list);
         }
@@ -146,11 +193,14 @@ function renderTasks(tasks) {
             if (!a.deadline) return 1;
             if (!b.deadline) return -1;
             return new Date(a.deadline) - new Date(b.deadline);
-        } else { // created
+        } else if (sortType === 'created') {
             return new Date(b.created_at) - new Date(a.created_at);
         }
+        return 0; // priority、updated 保持服务端顺序
     });
 
+    const titles = Object.fromEntries(tasks.map(task => [task.id, task.title]));
+
     tasks.forEach(task => {
         const clone = template.content.cloneNode(true);
         const item = clone.querySelector('.task-item');
@@ -188,6 +238,28 @@ function renderTasks(tasks) {
             }
         }
 
+        if (task.repeat) {
+            item.querySelector('.task-repeat').textContent = `重复: ${task.repeat}`;
+        }
+
+        const priority = priorityLabels[task.priority || 'medium'];
+        const priorityEl = item.querySelector('.task-priority');
+        priorityEl.textContent = priority.text;
+        priorityEl.classList.add(...priority.class.split(' '));
+
+        if (task.parent_id) {
+            item.querySelector('.task-parent').textContent = `子任务: ${titles[task.parent_id] || task.parent_id}`;
+        }
+
+        const tagsEl = item.querySelector('.task-tags');
+        (task.tags || []).forEach(tag => {
+            const tagEl = document.createElement('button');
+            tagEl.className = 'tag-btn px-2 rounded bg-green-100 text-green-700 hover:bg-green-200';
+            tagEl.textContent = `#${tag}`;
+            tagEl.dataset.tag = tag;
+            tagsEl.appendChild(tagEl);
+        });
+
         if (task.completed) {
             item.querySelector('.task-title').classList.add('line-through', 'text-gray-500');
             item.querySelector('.task-content').classList.add('line-through', 'text-gray-500');
@@ -197,6 +269,201 @@ function renderTasks(tasks) {
     });
 }
 
+// 项目处理
+let projects = [];
+
+async function loadProjects() {
+    try {
+        const data = await callTaskApi({ action: 'projects' });
+        if (data.status !== 'success') return;
+        projects = data.projects || [];
+
+        const current = getQueryParams().project;
+        const select = document.getElementById('projectSelect');
+        select.innerHTML = '<option value="">我的任务</option>';
+        projects.forEach(project => {
+            const option = document.createElement('option');
+            option.value = project.id;
+            option.textContent = project.name;
+            select.appendChild(option);
+        });
+        select.value = current;
+        updateProjectControls();
+    } catch (error) {
+        console.error('Failed to load projects:', error);
+    }
+}
+
+function updateProjectControls() {
+    const project = getQueryParams().project;
+    document.getElementById('shareBtn').classList.toggle('hidden', !project);
+    document.getElementById('exportLink').href = '/task/api/export.ics' + (project ? `?project_id=${encodeURIComponent(project)}` : '');
+}
+
+function switchProject(project) {
+    const params = getQueryParams();
+    params.project = project;
+    params.tag = '';
+    updateQueryParams(params);
+    updateProjectControls();
+    loadTasks();
+}
+
+async function createProject() {
+    const name = prompt('项目名称');
+    if (!name) return;
+    try {
+        const data = await callTaskApi({ action: 'create_project', project: { name } });
+        if (data.status !== 'success') {
+            alert(data.error);
+            return;
+        }
+        const params = getQueryParams();
+        params.project = data.projects[0].id;
+        updateQueryParams(params);
+        await loadProjects();
+        loadTasks();
+    } catch (error) {
+        console.error('Failed to create project:', error);
+    }
+}
+
+async function shareProject() {
+    const project = projects.find(p => p.id === getQueryParams().project);
+    if (!project) return;
+    const members = Object.entries(project.members).map(([user, role]) => `${user}: ${role}`).join('\n');
+    const user = prompt(`当前成员:\n${members}\n\n输入要共享的用户`);
+    if (!user) return;
+    const role = prompt('角色: owner、editor、viewer，none 表示移除成员', 'editor');
+    if (!role) return;
+    try {
+        const data = await callTaskApi({ action: 'share', share: { user, role } });
+        if (data.status !== 'success') {
+            alert(data.error);
+            return;
+        }
+        loadProjects();
+    } catch (error) {
+        console.error('Failed to share project:', error);
+    }
+}
+
+// 提醒处理
+function showReminder(reminder) {
+    const task = reminder.task;
+    const project = projects.find(p => p.id === reminder.project_id);
+    const title = project ? `${project.name}: ${task.title}` : task.title;
+    const text = reminder.overdue
+        ? `「${title}」已超过截止时间`
+        : `「${title}」将于 ${formatDate(task.deadline)} 截止`;
+
+    const toast = document.createElement('div');
+    toast.className = `p-4 rounded-lg shadow-lg text-white ${reminder.overdue ? 'bg-red-500' : 'bg-yellow-500'}`;
+    toast.textContent = text;
+    toast.addEventListener('click', () => toast.remove());
+    document.getElementById('reminders').appendChild(toast);
+    setTimeout(() => toast.remove(), 30000);
+
+    if ('Notification' in window && Notification.permission === 'granted') {
+        new Notification('任务提醒', { body: text });
+    }
+}
+
+function subscribeReminders() {
+    if ('Notification' in window && Notification.permission === 'default') {
+        Notification.requestPermission();
+    }
+
+    // EventSource 断开后会自动重连
+    const source = new EventSource('/task/api/reminders');
+    source.addEventListener('reminder', (e) => {
+        const reminder = JSON.parse(e.data);
+        showReminder(reminder);
+        if ((reminder.project_id || '') === getQueryParams().project) {
+            loadTasks();
+        }
+    });
+    return source;
+}
+
+// 历史和撤销
+const eventLabels = {
+    created: '创建',
+    updated: '修改',
+    completed: '完成',
+    deleted: '删除',
+    reminded: '提醒',
+    undone: '撤销',
+    migrated: '迁移'
+};
+
+// 列出快照之间变化的字段
+function describeChanges(prev, task) {
+    if (!prev || !task) return '';
+    const fields = { title: '标题', content: '内容', deadline: '截止', repeat: '重复', priority: '优先级', tags: '标签', completed: '完成', parent_id: '父任务' };
+    return Object.entries(fields)
+        .filter(([key]) => JSON.stringify(prev[key] ?? '') !== JSON.stringify(task[key] ?? ''))
+        .map(([, label]) => label)
+        .join('、');
+}
+
+async function openHistoryDialog(id) {
+    try {
+        const data = await callTaskApi({ action: 'history', task: { id } });
+        if (data.status !== 'success') {
+            alert(data.error);
+            return;
+        }
+
+        const list = document.getElementById('historyList');
+        list.innerHTML = '';
+        data.history.slice().reverse().forEach(event => {
+            const li = document.createElement('li');
+            li.className = 'flex justify-between gap-4 border-b pb-2';
+            const changes = describeChanges(event.prev, event.task);
+            li.innerHTML = `<span class="font-medium"></span><span class="text-gray-500 flex-none"></span>`;
+            li.firstChild.textContent = eventLabels[event.type] + (changes ? `: ${changes}` : '');
+            li.lastChild.textContent = formatDate(event.at);
+            list.appendChild(li);
+        });
+        document.getElementById('historyDialog').classList.remove('hidden');
+    } catch (error) {
+        console.error('Failed to load history:', error);
+    }
+}
+
+function closeHistoryDialog() {
+    document.getElementById('historyDialog').classList.add('hidden');
+}
+
+async function undoLastChange() {
+    try {
+        const data = await callTaskApi({ action: 'undo' });
+        if (data.status === 'success') {
+            loadTasks();
+        } else {
+            alert(data.error);
+        }
+    } catch (error) {
+        console.error('Failed to undo:', error);
+    }
+}
+
+// 导入 iCalendar 文件，按 UID 合并到已有任务
+async function importCalendar(file) {
+    try {
+        const data = await callTaskApi({ action: 'import', ics: await file.text() });
+        if (data.status === 'success') {
+            alert(`已导入 ${(data.task_list || []).length} 个任务`);
+            loadTasks();
+        } else {
+            alert(data.error);
+        }
+    } catch (error) {
+        console.error('Failed to import calendar:', error);
+    }
+}
+
 // 对话框处理
 function openAddDialog() {
     document.getElementById('addDialog').classList.remove('hidden');
@@ -215,6 +482,9 @@ function openEditDialog(task) {
     form.title.value = task.title;
     form.content.value = task.content;
     form.deadline.value = task.deadline ? task.deadline.slice(0, 16) : '';
+    form.repeat.value = task.repeat || '';
+    form.priority.value = task.priority || 'medium';
+    form.tags.value = (task.tags || []).join(', ');
     
     dialog.classList.remove('hidden');
 }
@@ -237,7 +507,9 @@ const handleFilterChange = debounce(() => {
         query: document.getElementById('searchInput').value,
         is_done: document.getElementById('statusFilter').value === '' ? null : document.getElementById('statusFilter').value === 'true',
         limit: parseInt(document.getElementById('limitFilter').value),
-        sort: document.getElementById('sortFilter').value
+        sort: document.getElementById('sortFilter').value,
+        tag: getQueryParams().tag,
+        project: getQueryParams().project
     };
     updateQueryParams(params);
     loadTasks();
@@ -247,32 +519,46 @@ const handleFilterChange = debounce(() => {
 let autoRefreshTimer;
 
 // 初始化事件监听
-document.addEventListener('DOMContentLoaded', () => {
+document.addEventListener('DOMContentLoaded', async () => {
+    // 项目
+    document.getElementById('projectSelect').addEventListener('change', (e) => switchProject(e.target.value));
+    document.getElementById('newProjectBtn').addEventListener('click', createProject);
+    document.getElementById('shareBtn').addEventListener('click', shareProject);
+
     // 添加任务
     document.getElementById('addTaskBtn').addEventListener('click', openAddDialog);
+    document.getElementById('undoBtn').addEventListener('click', undoLastChange);
+    document.getElementById('importBtn').addEventListener('click', () => {
+        document.getElementById('importInput').click();
+    });
+    document.getElementById('importInput').addEventListener('change', (e) => {
+        const file = e.target.files[0];
+        e.target.value = '';
+        if (file) importCalendar(file);
+    });
     document.getElementById('addForm').addEventListener('submit', async (e) => {
         e.preventDefault();
         const form = e.target;
         const task = {
             title: form.title.value,
             content: form.content.value,
-            deadline: form.deadline.value
+            deadline: form.deadline.value,
+            repeat: form.repeat.value.trim(),
+            priority: form.priority.value,
+            tags: parseTags(form.tags.value)
         };
 
         try {
-            const response = await fetch('/task/api', {
-                method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({
-                    action: 'add',
-                    task: task
-                })
+            const data = await callTaskApi({
+                action: 'add',
+                task: task
             });
-            const data = await response.json();
             if (data.status === 'success') {
                 closeAddDialog();
                 form.reset();
                 loadTasks();
+            } else {
+                alert(data.error);
             }
         } catch (error) {
             console.error('Failed to add task:', error);
@@ -287,15 +573,10 @@ document.addEventListener('DOMContentLoaded', () => {
             const completed = e.target.checked;
 
             try {
-                const response = await fetch('/task/api', {
-                    method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify({
-                        action: 'update',
-                        task: { id, completed }
-                    })
+                const data = await callTaskApi({
+                    action: 'update',
+                    task: { id, completed }
                 });
-                const data = await response.json();
                 if (data.status === 'success') {
                     loadTasks();
                 }
@@ -314,15 +595,10 @@ document.addEventListener('DOMContentLoaded', () => {
             if (!confirm('确定要删除这个任务吗？')) return;
 
             try {
-                const response = await fetch('/task/api', {
-                    method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify({
-                        action: 'delete',
-                        task: { id }
-                    })
+                const data = await callTaskApi({
+                    action: 'delete',
+                    task: { id }
                 });
-                const data = await response.json();
                 if (data.status === 'success') {
                     loadTasks();
                 }
@@ -332,6 +608,24 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     });
 
+    // 点击标签按标签筛选，再次点击取消
+    document.getElementById('taskList').addEventListener('click', (e) => {
+        const tagEl = e.target.closest('.tag-btn');
+        if (tagEl) {
+            const params = getQueryParams();
+            params.tag = params.tag === tagEl.dataset.tag ? '' : tagEl.dataset.tag;
+            updateQueryParams(params);
+            loadTasks();
+        }
+    });
+
+    // 查看变更历史
+    document.getElementById('taskList').addEventListener('click', (e) => {
+        if (e.target.closest('.history-btn')) {
+            openHistoryDialog(e.target.closest('.task-item').dataset.id);
+        }
+    });
+
     // 编辑任务
     document.getElementById('taskList').addEventListener('click', async (e) => {
         if (e.target.closest('.edit-btn')) {
@@ -348,22 +642,23 @@ document.addEventListener('DOMContentLoaded', () => {
             id: form.id.value,
             title: form.title.value,
             content: form.content.value,
-            deadline: form.deadline.value
+            deadline: form.deadline.value,
+            // 清空重复规则时需要显式传 none
+            repeat: form.repeat.value.trim() || 'none',
+            priority: form.priority.value,
+            tags: parseTags(form.tags.value)
         };
 
         try {
-            const response = await fetch('/task/api', {
-                method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({
-                    action: 'update',
-                    task: task
-                })
+            const data = await callTaskApi({
+                action: 'update',
+                task: task
             });
-            const data = await response.json();
             if (data.status === 'success') {
                 closeEditDialog();
                 loadTasks();
+            } else {
+                alert(data.error);
             }
         } catch (error) {
             console.error('Failed to update task:', error);
@@ -379,6 +674,8 @@ document.addEventListener('DOMContentLoaded', () => {
     // 浏览器前进/后退
     window.addEventListener('popstate', () => {
         initializeFormValues();
+        document.getElementById('projectSelect').value = getQueryParams().project;
+        updateProjectControls();
         loadTasks();
     });
 
@@ -395,7 +692,12 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     });
 
-    // 初始化
+    // 初始化，需要认证时先通过 fetch 取得令牌
     initializeFormValues();
+    await loadProjects();
     loadTasks();
+
+    // 订阅截止提醒
+    const reminderSource = subscribeReminders();
+    window.addEventListener('beforeunload', () => reminderSource.close());
 }); 
\ No newline at end of file
@@ -1,20 +1,12 @@
 package main
 
 import (
+	"Eino-example/pkg/chatmodel"
 	"context"
-	"github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/model"
-	"os"
 )
 
 func newModel(ctx context.Context, modelName string) (tmd model.ToolCallingChatModel, err error) {
-	// 读取环境变量
-	baseUrl := os.Getenv("OPENAI_BASE_URL")
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	tmd, err = openai.NewChatModel(ctx, &openai.ChatModelConfig{
-		Model:   modelName, // 使用的模型版本
-		APIKey:  apiKey,    // OpenAI API 密钥
-		BaseURL: baseUrl,
-	})
-	return tmd, err
+	// 使用主 profile 的提供方与密钥，模型替换为指定的模型
+	return chatmodel.NewFromEnv(ctx, chatmodel.WithModel(modelName))
 }
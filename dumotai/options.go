@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/cloudwego/eino/components/model"
+)
+
+// Option BuildWenGoAgent 的可选配置
+type Option func(*options)
+
+type options struct {
+	chatModel model.BaseChatModel
+}
+
+// WithChatModel 指定 OCR、重排序与批改三个模型节点使用的聊天模型，
+// 替换按 OCR_MODEL_NAME、REORDER_MODEL_NAME、FeedBack_Model_NAME 创建的模型，
+// 例如在测试中注入 pkg/fake 提供的模型
+func WithChatModel(cm model.BaseChatModel) Option {
+	return func(o *options) {
+		o.chatModel = cm
+	}
+}
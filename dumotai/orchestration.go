@@ -10,7 +10,7 @@ import (
 
 // BuildWenGoAgent 构建作文批改AI代理
 // 该函数创建一个包含OCR识别、作文重排序和作文批改三个步骤的图结构
-func BuildWenGoAgent(ctx context.Context) (compose.Runnable[[]string, *schema.Message], error) {
+func BuildWenGoAgent(ctx context.Context, opts ...Option) (compose.Runnable[[]string, *schema.Message], error) {
 	const (
 		// 图节点名称常量
 		imagePathsToQuery = "ImagePathsToQuery" // 图片路径查询节点
@@ -24,6 +24,11 @@ func BuildWenGoAgent(ctx context.Context) (compose.Runnable[[]string, *schema.Me
 		feedbackModel     = "FeedbackModel"     // 反馈模型节点
 	)
 
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	// 创建一个新的图结构，输入类型为 []string，输出类型为 *schema.Message
 	g := compose.NewGraph[[]string, *schema.Message]()
 
@@ -43,7 +48,7 @@ func BuildWenGoAgent(ctx context.Context) (compose.Runnable[[]string, *schema.Me
 	}
 
 	// 添加OCR模型节点
-	if err := addModelNode(ctx, g, os.Getenv("OCR_MODEL_NAME"), ocrModel); err != nil {
+	if err := addModelNode(ctx, g, o, os.Getenv("OCR_MODEL_NAME"), ocrModel); err != nil {
 		return nil, fmt.Errorf("failed to create OCR model node: %w", err)
 	}
 
@@ -64,7 +69,7 @@ func BuildWenGoAgent(ctx context.Context) (compose.Runnable[[]string, *schema.Me
 	}
 
 	// 添加重排序模型节点
-	if err := addModelNode(ctx, g, os.Getenv("REORDER_MODEL_NAME"), reorderModel); err != nil {
+	if err := addModelNode(ctx, g, o, os.Getenv("REORDER_MODEL_NAME"), reorderModel); err != nil {
 		return nil, fmt.Errorf("failed to create reorder model node: %w", err)
 	}
 
@@ -84,7 +89,7 @@ func BuildWenGoAgent(ctx context.Context) (compose.Runnable[[]string, *schema.Me
 	}
 
 	// 添加反馈模型节点
-	if err := addModelNode(ctx, g, os.Getenv("FeedBack_Model_NAME"), feedbackModel); err != nil {
+	if err := addModelNode(ctx, g, o, os.Getenv("FeedBack_Model_NAME"), feedbackModel); err != nil {
 		return nil, fmt.Errorf("failed to create feedback model node: %w", err)
 	}
 
@@ -120,18 +125,22 @@ func BuildWenGoAgent(ctx context.Context) (compose.Runnable[[]string, *schema.Me
 }
 
 // addModelNode 是一个辅助函数，用于创建模型节点
-// 它封装了模型创建、Lambda创建和节点添加的逻辑
-func addModelNode(ctx context.Context, g *compose.Graph[[]string, *schema.Message], modelName, nodeName string) error {
-	if modelName == "" {
-		return fmt.Errorf("model name is required for node %s", nodeName)
-	}
+// 它封装了模型创建、Lambda创建和节点添加的逻辑，通过 WithChatModel 指定模型时不再创建模型
+func addModelNode(ctx context.Context, g *compose.Graph[[]string, *schema.Message], o *options, modelName, nodeName string) error {
+	cm := o.chatModel
+	if cm == nil {
+		if modelName == "" {
+			return fmt.Errorf("model name is required for node %s", nodeName)
+		}
 
-	model, err := newModel(ctx, modelName)
-	if err != nil {
-		return fmt.Errorf("failed to create model %s: %w", modelName, err)
+		model, err := newModel(ctx, modelName)
+		if err != nil {
+			return fmt.Errorf("failed to create model %s: %w", modelName, err)
+		}
+		cm = model
 	}
 
-	lambda, err := compose.AnyLambda(model.Generate, model.Stream, nil, nil)
+	lambda, err := compose.AnyLambda(cm.Generate, cm.Stream, nil, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create lambda for model %s: %w", modelName, err)
 	}
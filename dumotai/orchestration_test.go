@@ -1,6 +1,7 @@
 package main
 
 import (
+	"Eino-example/pkg/fake"
 	"context"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
@@ -62,3 +63,50 @@ func TestBuildWenGoAgent(t *testing.T) {
 		})
 	}
 }
+
+func TestWenGoAgentInvoke(t *testing.T) {
+	originalConverter := imageConverter
+	defer func() {
+		imageConverter = originalConverter
+	}()
+	imageConverter = &MockImageConverter{
+		responses: map[string]struct {
+			mimeType string
+			data     string
+			err      error
+		}{
+			"page1.jpg": {"image/jpeg", "base64data1", nil},
+		},
+	}
+
+	ctx := context.Background()
+	feedback := `{"score":90,"overall_comment":"结构清晰"}`
+	cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{
+		fake.Text("缅怀先烈 逐梦未来\n清明节，我们去烈士陵园扫墓。"),
+		fake.Text(`{"title":"缅怀先烈 逐梦未来","content":"清明节，我们去烈士陵园扫墓。"}`),
+		fake.Text(feedback),
+	}})
+
+	r, err := BuildWenGoAgent(ctx, WithChatModel(cm))
+	assert.NoError(t, err)
+
+	out, err := r.Invoke(ctx, []string{"page1.jpg"})
+	assert.NoError(t, err)
+	assert.Equal(t, feedback, out.Content)
+
+	inputs := cm.Inputs()
+	assert.Len(t, inputs, 3)
+	// OCR 模型收到图片，重排序模型收到 OCR 结果，批改模型收到重排后的标题与正文
+	last := inputs[0][len(inputs[0])-1]
+	assert.Equal(t, "base64data1", *last.UserInputMultiContent[0].Image.Base64Data)
+	assert.Contains(t, inputs[1][len(inputs[1])-1].Content, "清明节，我们去烈士陵园扫墓。")
+	assert.Contains(t, inputs[2][len(inputs[2])-1].Content, "作文题目:缅怀先烈 逐梦未来")
+
+	t.Run("重排结果不是 JSON 时报错", func(t *testing.T) {
+		cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{fake.Text("not json")}})
+		r, err := BuildWenGoAgent(ctx, WithChatModel(cm))
+		assert.NoError(t, err)
+		_, err = r.Invoke(ctx, []string{"page1.jpg"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,132 @@
+package einoagent
+
+import (
+	"context"
+	"fmt"
+	"github.com/cloudwego/eino/schema"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DocumentsNode 将检索结果渲染为带编号文档的节点，可通过 compose.WithCallbacks(...).DesignateNode 监听其输入
+const DocumentsNode = "DocumentsFormatter"
+
+// metaKeySource 文件加载器写入文档元数据的文件路径键
+const metaKeySource = "_source"
+
+// headingKeys 标题分割器写入文档元数据的各级标题键，顺序即标题层级
+var headingKeys = []string{"title", "subtitle", "subsubtitle"}
+
+// citationPattern 匹配回答中的引用标记，如 [1]、[1, 3]
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Source 回答引用的来源文档
+type Source struct {
+	// Index 文档在提示词中的编号，从 1 开始，与回答中的 [n] 对应
+	Index int `json:"index"`
+	// ID 文档 ID
+	ID string `json:"id"`
+	// URI 文档来源文件
+	URI string `json:"uri,omitempty"`
+	// Headings 文档所在的标题路径，如 ["Eino", "Graph", "Branch"]
+	Headings []string `json:"headings,omitempty"`
+	// Score 检索（或重排序）得分
+	Score float64 `json:"score"`
+}
+
+// DocumentSources 按检索结果的顺序为文档编号并提取来源信息
+//
+// 参数:
+//
+//	docs - 检索结果，顺序与提示词中的编号一致
+//
+// 返回值:
+//
+//	与文档一一对应的来源列表
+func DocumentSources(docs []*schema.Document) []*Source {
+	sources := make([]*Source, 0, len(docs))
+	for i, doc := range docs {
+		source := &Source{
+			Index: i + 1,
+			ID:    doc.ID,
+			Score: doc.Score(),
+		}
+		if uri, ok := doc.MetaData[metaKeySource].(string); ok {
+			source.URI = uri
+		}
+		for _, key := range headingKeys {
+			if heading, ok := doc.MetaData[key].(string); ok && heading != "" {
+				source.Headings = append(source.Headings, heading)
+			}
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// CitedSources 解析回答中的 [n] 引用标记，按首次引用的顺序返回被引用的来源，忽略不存在的编号
+//
+// 参数:
+//
+//	answer - 模型的完整回答
+//	sources - DocumentSources 返回的来源列表
+//
+// 返回值:
+//
+//	被引用的来源列表
+func CitedSources(answer string, sources []*Source) []*Source {
+	byIndex := make(map[int]*Source, len(sources))
+	for _, source := range sources {
+		byIndex[source.Index] = source
+	}
+
+	cited := make([]*Source, 0)
+	seen := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, s := range strings.Split(match[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || seen[n] {
+				continue
+			}
+			if source, ok := byIndex[n]; ok {
+				seen[n] = true
+				cited = append(cited, source)
+			}
+		}
+	}
+	return cited
+}
+
+// formatDocuments 将检索结果渲染为带编号的文档文本，作为聊天模板的 documents 变量
+// 每篇文档以 [n] 开头并附带来源与标题路径，模型据此在回答中标注引用
+//
+// 参数:
+//
+//	ctx - 上下文对象
+//	docs - 检索（或重排序）后的文档
+//
+// 返回值:
+//
+//	output - 渲染后的文档文本
+//	err - 当前实现始终返回 nil
+func formatDocuments(ctx context.Context, docs []*schema.Document) (output string, err error) {
+	if len(docs) == 0 {
+		return "(no related documents)", nil
+	}
+
+	var sb strings.Builder
+	for _, source := range DocumentSources(docs) {
+		fmt.Fprintf(&sb, "[%d]", source.Index)
+		if source.URI != "" {
+			fmt.Fprintf(&sb, " source: %s", source.URI)
+		}
+		if len(source.Headings) > 0 {
+			fmt.Fprintf(&sb, " section: %s", strings.Join(source.Headings, " > "))
+		}
+		sb.WriteString("\n")
+		sb.WriteString(strings.TrimSpace(docs[source.Index-1].Content))
+		sb.WriteString("\n\n")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,47 @@
+package einoagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCitation(t *testing.T) {
+	docs := []*schema.Document{
+		(&schema.Document{ID: "a", Content: "Graph 支持分支。", MetaData: map[string]any{
+			"_source":  "eino-docs/graph.md",
+			"title":    "Graph",
+			"subtitle": "Branch",
+		}}).WithScore(0.9),
+		{ID: "b", Content: "Chain 是简化的 Graph。", MetaData: map[string]any{"title": "Chain"}},
+	}
+
+	sources := DocumentSources(docs)
+	assert.Len(t, sources, 2)
+	assert.Equal(t, &Source{Index: 1, ID: "a", URI: "eino-docs/graph.md", Headings: []string{"Graph", "Branch"}, Score: 0.9}, sources[0])
+
+	output, err := formatDocuments(context.Background(), docs)
+	assert.NoError(t, err)
+	assert.Equal(t, "[1] source: eino-docs/graph.md section: Graph > Branch\nGraph 支持分支。\n\n[2] section: Chain\nChain 是简化的 Graph。", output)
+
+	tests := []struct {
+		name   string
+		answer string
+		want   []string
+	}{
+		{name: "没有引用", answer: "Graph 支持分支。", want: []string{}},
+		{name: "按首次引用顺序去重", answer: "Chain 更简单 [2]，Graph 支持分支 [1][2]。", want: []string{"b", "a"}},
+		{name: "合并引用与不存在的编号", answer: "参考 [1, 3]，以及数组下标 arr[0]。", want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := make([]string, 0)
+			for _, source := range CitedSources(tt.answer, sources) {
+				ids = append(ids, source.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package einoagent
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// envInt 读取整数类型的环境变量，未设置时返回默认值
+func envInt(key string, defaultValue int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid env %s=%s: %w", key, v, err)
+	}
+	return i, nil
+}
+
+// envFloat 读取浮点数类型的环境变量，未设置时返回默认值
+func envFloat(key string, defaultValue float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid env %s=%s: %w", key, v, err)
+	}
+	return f, nil
+}
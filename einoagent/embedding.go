@@ -8,7 +8,7 @@ import (
 	"os"
 )
 
-// newEmbedding creates a new embedding embedder instance using DashScope API.
+// NewEmbedding creates a new embedding embedder instance using DashScope API.
 // It initializes the embedder with the API key from environment variable and default model configuration.
 //
 // Parameters:
@@ -19,7 +19,7 @@ import (
 //
 //	embedding.Embedder - the created embedder instance
 //	error - error if embedder creation fails, nil otherwise
-func newEmbedding(ctx context.Context) (embedding.Embedder, error) {
+func NewEmbedding(ctx context.Context) (embedding.Embedder, error) {
 	// Get API key from environment variable
 	apiKey := os.Getenv("DASHSCOPE_API_KEY")
 
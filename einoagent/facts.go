@@ -0,0 +1,50 @@
+package einoagent
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/cloudwego/eino/components/retriever"
+)
+
+// FactsNode 从长期记忆中检索与问题相关事实的节点，输出键为 "facts"
+const FactsNode = "Facts"
+
+// defaultFactsScoreThreshold 是渲染到提示词中的事实与问题的最低相似度，
+// 可以通过 MEMORY_FACTS_SCORE_THRESHOLD 修改
+const defaultFactsScoreThreshold = 0.5
+
+// newFactsLambda 创建检索长期记忆的 Lambda 函数，将与用户问题相关的事实渲染为列表
+// 长期记忆只是辅助信息，检索失败时记录日志并按没有事实处理，不中断对话
+//
+// 参数:
+//
+//	facts - 长期记忆的检索器，为 nil 时不检索
+//	scoreThreshold - 事实与问题的最低相似度，低于它的事实不渲染
+//
+// 返回值:
+//
+//	渲染后的事实列表
+func newFactsLambda(facts retriever.Retriever, scoreThreshold float64) func(ctx context.Context, input *UserMessage) (string, error) {
+	return func(ctx context.Context, input *UserMessage) (string, error) {
+		if facts == nil {
+			return "(no remembered facts)", nil
+		}
+		docs, err := facts.Retrieve(ctx, input.Query, retriever.WithScoreThreshold(scoreThreshold))
+		if err != nil {
+			log.Printf("[Facts] retrieve facts failed: %v\n", err)
+		}
+		if len(docs) == 0 {
+			return "(no remembered facts)", nil
+		}
+
+		var sb strings.Builder
+		for _, doc := range docs {
+			sb.WriteString("- ")
+			sb.WriteString(strings.TrimSpace(doc.Content))
+			sb.WriteString("\n")
+		}
+		return strings.TrimSpace(sb.String()), nil
+	}
+}
@@ -1,24 +1,37 @@
 package einoagent
 
 import (
+	"Eino-example/pkg/vectorstore"
 	"context"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent"
 	"github.com/cloudwego/eino/flow/agent/react"
 )
 
-func newLambda1(ctx context.Context) (lba *compose.Lambda, err error) {
+// ReactAgent ReAct Agent 在图中的节点名
+const ReactAgent = "ReactAgent"
+
+// WithAgentCallbacks 为 ReAct Agent 内部的模型与工具调用注册回调。
+// 与 compose.WithCallbacks 不同，查询改写、重排序等其他节点中的模型调用不会触发这些回调，
+// 可用于记录 Agent 的完整消息序列（带 tool_calls 的助手消息与工具消息）。
+func WithAgentCallbacks(handlers ...callbacks.Handler) compose.Option {
+	return compose.WithLambdaOption(agent.WithComposeOptions(compose.WithCallbacks(handlers...))).DesignateNode(ReactAgent)
+}
+
+func newLambda1(ctx context.Context, chatModel model.ToolCallingChatModel, tools []tool.BaseTool, store vectorstore.Store) (lba *compose.Lambda, err error) {
 	// TODO Modify component configuration here.
 	config := &react.AgentConfig{
 		MaxStep:            25,
 		ToolReturnDirectly: map[string]struct{}{}}
-	chatModelIns11, err := newModel(ctx)
-	if err != nil {
-		return nil, err
-	}
-	config.ToolCallingModel = chatModelIns11
-	tools, err := GetTools(ctx)
-	if err != nil {
-		return nil, err
+	config.ToolCallingModel = chatModel
+	if tools == nil {
+		tools, err = GetTools(ctx, store)
+		if err != nil {
+			return nil, err
+		}
 	}
 	config.ToolsConfig.Tools = tools
 	ins, err := react.NewAgent(ctx, config)
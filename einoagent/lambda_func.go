@@ -5,23 +5,6 @@ import (
 	"time"
 )
 
-// newLambda 创建一个新的 Lambda 函数处理器
-// 该函数接收用户消息并直接返回消息中的查询内容
-//
-// 参数:
-//
-//	ctx - 上下文对象，用于控制函数执行的生命周期
-//	input - 用户消息指针，包含待处理的查询数据
-//	opts - 可变参数，用于传递额外的配置选项
-//
-// 返回值:
-//
-//	output - 处理结果字符串，即原始查询内容
-//	err - 错误信息，当前实现始终返回 nil
-func newLambda(ctx context.Context, input *UserMessage, opts ...any) (output string, err error) {
-	return input.Query, nil
-}
-
 // newLambda2 创建一个新的lambda函数处理用户消息
 //
 // 参数:
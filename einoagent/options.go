@@ -0,0 +1,56 @@
+package einoagent
+
+import (
+	"Eino-example/pkg/vectorstore"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// Option BuildEinoAgent 的可选配置，用于替换默认根据环境变量创建的组件，
+// 例如在测试中注入 pkg/fake 提供的模型与嵌入模型
+type Option func(*options)
+
+type options struct {
+	chatModel model.ToolCallingChatModel
+	embedder  embedding.Embedder
+	store     vectorstore.Store
+	tools     []tool.BaseTool
+	facts     retriever.Retriever
+}
+
+// WithChatModel 指定 ReAct Agent、查询改写与重排序使用的聊天模型
+func WithChatModel(cm model.ToolCallingChatModel) Option {
+	return func(o *options) {
+		o.chatModel = cm
+	}
+}
+
+// WithEmbedder 指定向量库使用的嵌入模型，指定 WithVectorStore 时不生效
+func WithEmbedder(embedder embedding.Embedder) Option {
+	return func(o *options) {
+		o.embedder = embedder
+	}
+}
+
+// WithVectorStore 指定检索和 gitclone 工具索引仓库使用的向量库
+func WithVectorStore(store vectorstore.Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithTools 指定 ReAct Agent 可调用的工具，替换 GetTools 返回的默认工具
+func WithTools(tools []tool.BaseTool) Option {
+	return func(o *options) {
+		o.tools = tools
+	}
+}
+
+// WithFactRetriever 指定长期记忆的检索器，检索到的事实渲染在系统提示词的 {facts} 中，例如 mem.FactStore
+func WithFactRetriever(facts retriever.Retriever) Option {
+	return func(o *options) {
+		o.facts = facts
+	}
+}
@@ -12,24 +12,48 @@ import (
 //
 // 参数:
 //   - ctx: 上下文对象，用于控制生命周期和传递元数据。
+//   - opts: 可选配置，用于替换默认根据环境变量创建的模型、向量库与工具。
 //
 // 返回值:
 //   - r: 实现了 compose.Runnable 接口的对象，可用于执行整个流程。
 //   - err: 如果在构建过程中发生错误，则返回相应的错误信息；否则为 nil。
-func BuildEinoAgent(ctx context.Context) (r compose.Runnable[*UserMessage, *schema.Message], err error) {
+func BuildEinoAgent(ctx context.Context, opts ...Option) (r compose.Runnable[*UserMessage, *schema.Message], err error) {
 	const (
 		InputToQuery   = "InputToQuery"
 		ChatTemplate   = "ChatTemplate"
-		ReactAgent     = "ReactAgent"
 		RedisRetriever = "RedisRetriever"
+		Reranker       = "Reranker"
 		InputToHistory = "InputToHistory"
 	)
 
 	// 创建一个新的图结构，输入类型为 *UserMessage，输出类型为 *schema.Message
-	g := compose.NewGraph[*UserMessage, *schema.Message]()
+	// 图状态用于在节点之间共享检索查询等中间数据
+	g := compose.NewGraph[*UserMessage, *schema.Message](compose.WithGenLocalState(func(ctx context.Context) *agentState {
+		return &agentState{}
+	}))
 
-	// 添加将用户输入转换为查询语句的 Lambda 节点
-	_ = g.AddLambdaNode(InputToQuery, compose.InvokableLambdaWithOption(newLambda), compose.WithNodeName("UserMessageToQuery"))
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// 初始化聊天模型，ReAct Agent、查询改写与重排序共用同一个实例
+	chatModel := o.chatModel
+	if chatModel == nil {
+		chatModel, err = newModel(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 读取查询改写配置
+	rewriteConfig, err := defaultQueryRewriteConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 添加将用户输入转换为查询语句的 Lambda 节点，有历史记录时将追问改写为独立的检索查询
+	_ = g.AddLambdaNode(InputToQuery, compose.InvokableLambdaWithOption(newQueryRewriteLambda(chatModel, rewriteConfig)), compose.WithNodeName("UserMessageToQuery"))
 
 	// 初始化聊天模板，并将其作为 ChatTemplate 节点加入图中
 	chatTemplateKeyOfChatTemplate, err := newChatTemplate(ctx)
@@ -38,38 +62,100 @@ func BuildEinoAgent(ctx context.Context) (r compose.Runnable[*UserMessage, *sche
 	}
 	_ = g.AddChatTemplateNode(ChatTemplate, chatTemplateKeyOfChatTemplate)
 
+	// 初始化向量库，检索器与 gitclone 工具的仓库索引共用同一个实例
+	store := o.store
+	if store == nil {
+		store, err = newVectorStore(ctx, o.embedder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// 初始化 ReAct Agent 的 Lambda 函数，并添加到图中
-	reactAgentKeyOfLambda, err := newLambda1(ctx)
+	reactAgentKeyOfLambda, err := newLambda1(ctx, chatModel, o.tools, store)
 	if err != nil {
 		return nil, err
 	}
 	_ = g.AddLambdaNode(ReactAgent, reactAgentKeyOfLambda, compose.WithNodeName("ReAct Agent"))
 
-	// 初始化 Redis 检索器，并添加到图中，指定其输出键为 "documents"
-	redisRetrieverKeyOfRetriever, err := newRetriever(ctx)
+	// 读取检索与重排序配置，启用重排序时检索器需要过量召回候选文档
+	retrieverConfig, err := defaultRetrieverConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
-	_ = g.AddRetrieverNode(RedisRetriever, redisRetrieverKeyOfRetriever, compose.WithOutputKey("documents"))
+	rerankerConfig, err := defaultRerankerConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	factsThreshold, err := envFloat("MEMORY_FACTS_SCORE_THRESHOLD", defaultFactsScoreThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if rerankerConfig.Enabled() {
+		retrieverConfig.TopK = rerankerConfig.CandidateK
+	}
+
+	// 初始化检索器，检索前将查询记录到图状态中供重排序节点使用
+	redisRetrieverKeyOfRetriever, err := newRetriever(ctx, retrieverConfig, store)
+	if err != nil {
+		return nil, err
+	}
+	if rewriteConfig.SubQueries > 1 {
+		// 将查询拆分为多个子查询分别检索，合并后的结果再交给重排序或聊天模板
+		redisRetrieverKeyOfRetriever = &multiQueryRetriever{
+			retriever:  redisRetrieverKeyOfRetriever,
+			model:      chatModel,
+			maxQueries: rewriteConfig.SubQueries,
+			topK:       retrieverConfig.TopK,
+		}
+	}
+	_ = g.AddRetrieverNode(RedisRetriever, redisRetrieverKeyOfRetriever, compose.WithStatePreHandler(func(ctx context.Context, query string, state *agentState) (string, error) {
+		state.Query = query
+		return query, nil
+	}))
+
+	// 启用重排序时，添加重排序节点
+	if rerankerConfig.Enabled() {
+		reranker, err := newReranker(ctx, rerankerConfig, chatModel)
+		if err != nil {
+			return nil, err
+		}
+		_ = g.AddLambdaNode(Reranker, newRerankLambda(reranker, rerankerConfig), compose.WithNodeName("Reranker"))
+	}
+
+	// 添加将检索结果渲染为带编号文档的节点，指定其输出键为 "documents"，编号用于回答中的 [n] 引用
+	_ = g.AddLambdaNode(DocumentsNode, compose.InvokableLambda(formatDocuments), compose.WithNodeName("DocumentsFormatter"), compose.WithOutputKey("documents"))
+
+	// 添加检索长期记忆的 Lambda 节点，指定其输出键为 "facts"
+	_ = g.AddLambdaNode(FactsNode, compose.InvokableLambda(newFactsLambda(o.facts, factsThreshold)), compose.WithNodeName("FactsRetriever"), compose.WithOutputKey("facts"))
 
 	// 添加将用户输入转为历史变量的 Lambda 节点
 	_ = g.AddLambdaNode(InputToHistory, compose.InvokableLambdaWithOption(newLambda2), compose.WithNodeName("UserMessageToVariables"))
 
-	// 定义图中的边关系：START -> InputToQuery 和 START -> InputToHistory 表示流程开始时同时触发这两个节点
+	// 定义图中的边关系：START -> InputToQuery、InputToHistory 与 Facts 表示流程开始时同时触发这三个节点
 	_ = g.AddEdge(compose.START, InputToQuery)
 	_ = g.AddEdge(compose.START, InputToHistory)
+	_ = g.AddEdge(compose.START, FactsNode)
 
 	// 流程结束节点连接 ReactAgent
 	_ = g.AddEdge(ReactAgent, compose.END)
 
 	// 数据流定义：
 	// InputToQuery -> RedisRetriever：使用查询结果进行检索
-	// RedisRetriever -> ChatTemplate：将检索结果传入聊天模板
+	// RedisRetriever -> (Reranker ->) DocumentsFormatter -> ChatTemplate：将检索（并重排序）结果编号后传入聊天模板
 	// InputToHistory -> ChatTemplate：将历史上下文传入聊天模板
+	// Facts -> ChatTemplate：将长期记忆中的相关事实传入聊天模板
 	// ChatTemplate -> ReactAgent：最终由 ReactAgent 处理生成回复
 	_ = g.AddEdge(InputToQuery, RedisRetriever)
-	_ = g.AddEdge(RedisRetriever, ChatTemplate)
+	if rerankerConfig.Enabled() {
+		_ = g.AddEdge(RedisRetriever, Reranker)
+		_ = g.AddEdge(Reranker, DocumentsNode)
+	} else {
+		_ = g.AddEdge(RedisRetriever, DocumentsNode)
+	}
+	_ = g.AddEdge(DocumentsNode, ChatTemplate)
 	_ = g.AddEdge(InputToHistory, ChatTemplate)
+	_ = g.AddEdge(FactsNode, ChatTemplate)
 	_ = g.AddEdge(ChatTemplate, ReactAgent)
 
 	// 编译图结构为可执行的 Runnable 对象，设置图名称及节点触发模式为所有前驱完成后再触发
@@ -0,0 +1,198 @@
+package einoagent
+
+import (
+	"Eino-example/pkg/fake"
+	"Eino-example/pkg/mem"
+	"Eino-example/pkg/vectorstore"
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+type echoInput struct {
+	Text string `json:"text" jsonschema:"description=text to echo"`
+}
+
+// newTestAgentOptions 创建使用假模型、本地向量库与 echo 工具的 Agent 配置
+func newTestAgentOptions(t *testing.T, cm *fake.ChatModel) []Option {
+	ctx := context.Background()
+
+	// 避免开发环境中的环境变量影响图结构
+	t.Setenv("RETRIEVER_MODE", RetrieverModeDense)
+	t.Setenv("RERANKER", "")
+	t.Setenv("QUERY_REWRITE", "")
+	t.Setenv("QUERY_REWRITE_SUB_QUERIES", "0")
+
+	store, err := vectorstore.NewStore(ctx, &vectorstore.Config{
+		Backend:   vectorstore.BackendLocal,
+		Dir:       t.TempDir(),
+		Embedding: fake.NewEmbedder(0),
+	})
+	assert.NoError(t, err)
+	idr, err := store.NewIndexer(ctx, nil)
+	assert.NoError(t, err)
+	_, err = idr.Store(ctx, []*schema.Document{
+		{ID: "branch", Content: "Graph 通过 AddBranch 添加分支", MetaData: map[string]any{"title": "Graph", "subtitle": "Branch"}},
+		{ID: "chain", Content: "Chain 是线性的编排方式"},
+	})
+	assert.NoError(t, err)
+
+	echo, err := utils.InferTool("echo", "echo the text", func(ctx context.Context, input *echoInput) (string, error) {
+		return "echo: " + input.Text, nil
+	})
+	assert.NoError(t, err)
+
+	return []Option{
+		WithChatModel(cm),
+		WithVectorStore(store),
+		WithTools([]tool.BaseTool{echo}),
+	}
+}
+
+func TestBuildEinoAgent(t *testing.T) {
+	ctx := context.Background()
+	replies := []*schema.Message{
+		fake.ToolCall("call_1", "echo", `{"text":"branch"}`),
+		fake.Text("使用 AddBranch 添加分支 [1]。"),
+	}
+
+	t.Run("Invoke 检索文档并调用工具", func(t *testing.T) {
+		cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: replies})
+		r, err := BuildEinoAgent(ctx, newTestAgentOptions(t, cm)...)
+		assert.NoError(t, err)
+
+		out, err := r.Invoke(ctx, &UserMessage{ID: "test", Query: "如何给 Graph 添加分支"})
+		assert.NoError(t, err)
+		assert.Equal(t, "使用 AddBranch 添加分支 [1]。", out.Content)
+
+		inputs := cm.Inputs()
+		assert.Len(t, inputs, 2)
+		// 检索到的文档按编号渲染在系统提示词中
+		assert.Contains(t, inputs[0][0].Content, "[1] section: Graph > Branch\nGraph 通过 AddBranch 添加分支")
+		// 第二次调用模型时带上了工具结果
+		last := inputs[1][len(inputs[1])-1]
+		assert.Equal(t, schema.Tool, last.Role)
+		assert.Equal(t, "echo: branch", last.Content)
+	})
+
+	t.Run("Stream 分块输出回答", func(t *testing.T) {
+		cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: replies, ChunkSize: 2})
+		r, err := BuildEinoAgent(ctx, newTestAgentOptions(t, cm)...)
+		assert.NoError(t, err)
+
+		sr, err := r.Stream(ctx, &UserMessage{ID: "test", Query: "如何给 Graph 添加分支"})
+		assert.NoError(t, err)
+		defer sr.Close()
+
+		var chunks []*schema.Message
+		for {
+			chunk, err := sr.Recv()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			chunks = append(chunks, chunk)
+		}
+		assert.Greater(t, len(chunks), 1)
+		msg, err := schema.ConcatMessages(chunks)
+		assert.NoError(t, err)
+		assert.Equal(t, "使用 AddBranch 添加分支 [1]。", msg.Content)
+	})
+}
+
+func TestWithAgentCallbacks(t *testing.T) {
+	ctx := context.Background()
+	cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{
+		fake.Text("如何给 Graph 添加分支"),
+		fake.ToolCall("call_1", "echo", `{"text":"branch"}`),
+		fake.Text("使用 AddBranch 添加分支 [1]。"),
+	}})
+	opts := newTestAgentOptions(t, cm)
+	t.Setenv("QUERY_REWRITE", "true")
+	r, err := BuildEinoAgent(ctx, opts...)
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	var ended []components.Component
+	handler := callbacks.NewHandlerBuilder().OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+		mu.Lock()
+		defer mu.Unlock()
+		ended = append(ended, info.Component)
+		return ctx
+	}).Build()
+
+	// 有历史记录时查询改写也会调用模型，但不在 Agent 内部，不应触发回调
+	_, err = r.Invoke(ctx, &UserMessage{
+		ID:      "test",
+		Query:   "那分支呢",
+		History: []*schema.Message{schema.UserMessage("Graph 是什么"), schema.AssistantMessage("Graph 是有向图", nil)},
+	}, WithAgentCallbacks(handler))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cm.Calls())
+
+	mu.Lock()
+	defer mu.Unlock()
+	var models, tools int
+	for _, c := range ended {
+		switch c {
+		case components.ComponentOfChatModel:
+			models++
+		case components.ComponentOfTool:
+			tools++
+		}
+	}
+	assert.Equal(t, 2, models)
+	assert.Equal(t, 1, tools)
+}
+
+func TestWithFactRetriever(t *testing.T) {
+	ctx := context.Background()
+	facts, err := mem.NewFactStore(filepath.Join(t.TempDir(), "facts.json"), fake.NewEmbedder(0))
+	assert.NoError(t, err)
+	_, err = facts.Remember(ctx, "c1", []string{"用户的 Graph 使用 AddBranch 编排"})
+	assert.NoError(t, err)
+
+	t.Run("相关事实渲染在系统提示词中", func(t *testing.T) {
+		cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{fake.Text("好的")}})
+		r, err := BuildEinoAgent(ctx, append(newTestAgentOptions(t, cm), WithFactRetriever(facts))...)
+		assert.NoError(t, err)
+
+		_, err = r.Invoke(ctx, &UserMessage{ID: "test", Query: "如何给 Graph 添加分支"})
+		assert.NoError(t, err)
+		assert.Contains(t, cm.Inputs()[0][0].Content, "- 用户的 Graph 使用 AddBranch 编排")
+	})
+
+	t.Run("相似度低于阈值的事实不渲染", func(t *testing.T) {
+		unrelated, err := mem.NewFactStore(filepath.Join(t.TempDir(), "facts.json"), fake.NewEmbedder(0))
+		assert.NoError(t, err)
+		_, err = unrelated.Remember(ctx, "c1", []string{"用户每天早上跑步"})
+		assert.NoError(t, err)
+
+		cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{fake.Text("好的")}})
+		r, err := BuildEinoAgent(ctx, append(newTestAgentOptions(t, cm), WithFactRetriever(unrelated))...)
+		assert.NoError(t, err)
+
+		_, err = r.Invoke(ctx, &UserMessage{ID: "test", Query: "如何给 Graph 添加分支"})
+		assert.NoError(t, err)
+		assert.Contains(t, cm.Inputs()[0][0].Content, "(no remembered facts)")
+	})
+
+	t.Run("未指定时没有事实", func(t *testing.T) {
+		cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{fake.Text("好的")}})
+		r, err := BuildEinoAgent(ctx, newTestAgentOptions(t, cm)...)
+		assert.NoError(t, err)
+
+		_, err = r.Invoke(ctx, &UserMessage{ID: "test", Query: "如何给 Graph 添加分支"})
+		assert.NoError(t, err)
+		assert.Contains(t, cm.Inputs()[0][0].Content, "(no remembered facts)")
+	})
+}
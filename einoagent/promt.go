@@ -32,8 +32,14 @@ var systemPrompt = `
 
 - If the question is compound or complex, you need to think step by step, avoiding giving low-quality answers directly.
 
+- When citing the Related Documents:
+  • Each document starts with a numbered id like [1], cite it right after the statement it supports, e.g. "Graph supports branches [2]."
+  • Only cite documents you actually used, never make up ids that do not exist
+
 ## Context Information
 - Current Date: {date}
+- Remembered Facts about the user: |-
+  {facts}
 - Related Documents: |-
 ==== doc start ====
   {documents}
@@ -0,0 +1,180 @@
+package einoagent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"log"
+	"os"
+	"strings"
+)
+
+const condenseQueryPrompt = `Given the conversation history and a follow-up question, rewrite the follow-up question into a standalone search query
+that can be understood without the history. Resolve pronouns and references like "that", "it", "the above" to the concrete
+entities they refer to, keep exact identifiers (function names, package names, error messages) unchanged, and keep the language of the question.
+Output ONLY the rewritten query, nothing else.`
+
+const subQueriesPrompt = `You are helping a retrieval system search the Eino framework documentation.
+Break the user query into at most %d short, self-contained search queries that together cover the information needed to answer it.
+If the query is already simple, return it as the only item.
+Output ONLY a JSON array of strings, e.g. ["query one","query two"].`
+
+// QueryRewriteConfig 查询改写配置
+type QueryRewriteConfig struct {
+	// Enabled 是否根据对话历史将追问改写为独立的检索查询
+	Enabled bool
+	// SubQueries 大于 1 时，将查询拆分为至多该数量的查询（含原始查询）分别检索并用 RRF 合并结果
+	SubQueries int
+	// HistoryWindow 改写时参考的最近历史消息数量
+	HistoryWindow int
+}
+
+// defaultQueryRewriteConfig 从环境变量读取查询改写配置：
+// QUERY_REWRITE（默认关闭，设置为 true 开启，每次检索会多一次模型调用）、QUERY_REWRITE_SUB_QUERIES、QUERY_REWRITE_HISTORY_WINDOW。
+func defaultQueryRewriteConfig(ctx context.Context) (*QueryRewriteConfig, error) {
+	config := &QueryRewriteConfig{
+		Enabled: os.Getenv("QUERY_REWRITE") == "true",
+	}
+
+	var err error
+	if config.SubQueries, err = envInt("QUERY_REWRITE_SUB_QUERIES", 0); err != nil {
+		return nil, err
+	}
+	if config.HistoryWindow, err = envInt("QUERY_REWRITE_HISTORY_WINDOW", 6); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// newQueryRewriteLambda 创建将用户消息转换为检索查询的 Lambda 函数
+// 没有历史记录时直接返回原始查询；有历史记录时调用聊天模型，
+// 结合 UserMessage.History 将追问（如"用 graph 怎么做？"）改写为可独立检索的查询。
+// 改写失败时退回原始查询，避免影响后续对话。
+//
+// 参数:
+//
+//	cm - 用于改写查询的聊天模型
+//	config - 查询改写配置
+//
+// 返回值:
+//
+//	可用于 compose.InvokableLambdaWithOption 的处理函数
+func newQueryRewriteLambda(cm model.BaseChatModel, config *QueryRewriteConfig) func(ctx context.Context, input *UserMessage, opts ...any) (output string, err error) {
+	return func(ctx context.Context, input *UserMessage, opts ...any) (output string, err error) {
+		if !config.Enabled || len(input.History) == 0 {
+			return input.Query, nil
+		}
+
+		history := input.History
+		if len(history) > config.HistoryWindow {
+			history = history[len(history)-config.HistoryWindow:]
+		}
+
+		var sb strings.Builder
+		sb.WriteString("Conversation history:\n")
+		for _, msg := range history {
+			// 只参考用户与助手的文本内容，忽略工具调用过程
+			if (msg.Role != schema.User && msg.Role != schema.Assistant) || msg.Content == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, "%s: %s\n", msg.Role, truncateRunes(msg.Content, 500))
+		}
+		fmt.Fprintf(&sb, "\nFollow-up question: %s", input.Query)
+
+		resp, err := cm.Generate(ctx, []*schema.Message{
+			schema.SystemMessage(condenseQueryPrompt),
+			schema.UserMessage(sb.String()),
+		})
+		if err != nil {
+			log.Printf("[QueryRewrite] condense query failed, use the original query: %v\n", err)
+			return input.Query, nil
+		}
+
+		query := strings.TrimSpace(resp.Content)
+		if query == "" {
+			return input.Query, nil
+		}
+		return query, nil
+	}
+}
+
+// multiQueryRetriever 将查询拆分为多个子查询分别检索，并用 RRF 合并结果
+type multiQueryRetriever struct {
+	retriever  retriever.Retriever
+	model      model.BaseChatModel
+	maxQueries int
+	topK       int
+}
+
+func (m *multiQueryRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	queries := m.subQueries(ctx, query)
+
+	results := make([][]*schema.Document, 0, len(queries))
+	for _, q := range queries {
+		docs, err := m.retriever.Retrieve(ctx, q, opts...)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, docs)
+	}
+
+	options := retriever.GetCommonOptions(&retriever.Options{TopK: &m.topK}, opts...)
+	docs := fuseRRF(results, nil, 60)
+	if len(docs) > *options.TopK {
+		docs = docs[:*options.TopK]
+	}
+	return docs, nil
+}
+
+// subQueries 调用聊天模型拆分子查询，原始查询总是排在第一位；拆分失败时只使用原始查询
+func (m *multiQueryRetriever) subQueries(ctx context.Context, query string) []string {
+	queries := []string{query}
+
+	resp, err := m.model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(fmt.Sprintf(subQueriesPrompt, m.maxQueries)),
+		schema.UserMessage(query),
+	})
+	if err != nil {
+		log.Printf("[QueryRewrite] generate sub queries failed: %v\n", err)
+		return queries
+	}
+
+	content := resp.Content
+	start, end := strings.Index(content, "["), strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return queries
+	}
+	var subs []string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &subs); err != nil {
+		log.Printf("[QueryRewrite] invalid sub queries: %s\n", content)
+		return queries
+	}
+
+	for _, sub := range subs {
+		sub = strings.TrimSpace(sub)
+		if len(queries) >= m.maxQueries {
+			break
+		}
+		if sub == "" || sub == query {
+			continue
+		}
+		queries = append(queries, sub)
+	}
+	return queries
+}
+
+func (m *multiQueryRetriever) GetType() string {
+	return "MultiQueryRetriever"
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
@@ -0,0 +1,117 @@
+package einoagent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// replyModel 返回固定回复的聊天模型，用于测试
+type replyModel struct {
+	reply string
+	err   error
+	calls int
+}
+
+func (m *replyModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return schema.AssistantMessage(m.reply, nil), nil
+}
+
+func (m *replyModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not implemented")
+}
+
+// queryRetriever 以查询内容作为文档 ID 返回，用于测试
+type queryRetriever struct{}
+
+func (q *queryRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	return []*schema.Document{{ID: query}}, nil
+}
+
+func TestQueryRewriteLambda(t *testing.T) {
+	ctx := context.Background()
+	history := []*schema.Message{
+		schema.UserMessage("Eino 的 Graph 是什么？"),
+		schema.AssistantMessage("Graph 是 Eino 的编排方式之一。", nil),
+	}
+
+	tests := []struct {
+		name      string
+		model     *replyModel
+		config    *QueryRewriteConfig
+		input     *UserMessage
+		want      string
+		wantCalls int
+	}{
+		{
+			name:      "没有历史记录时不调用模型",
+			model:     &replyModel{reply: "unused"},
+			config:    &QueryRewriteConfig{Enabled: true, HistoryWindow: 6},
+			input:     &UserMessage{Query: "Graph 是什么？"},
+			want:      "Graph 是什么？",
+			wantCalls: 0,
+		},
+		{
+			name:      "有历史记录时改写为独立查询",
+			model:     &replyModel{reply: " 如何在 Eino Graph 中添加分支？\n"},
+			config:    &QueryRewriteConfig{Enabled: true, HistoryWindow: 6},
+			input:     &UserMessage{Query: "它怎么加分支？", History: history},
+			want:      "如何在 Eino Graph 中添加分支？",
+			wantCalls: 1,
+		},
+		{
+			name:      "模型调用失败时退回原始查询",
+			model:     &replyModel{err: errors.New("boom")},
+			config:    &QueryRewriteConfig{Enabled: true, HistoryWindow: 6},
+			input:     &UserMessage{Query: "它怎么加分支？", History: history},
+			want:      "它怎么加分支？",
+			wantCalls: 1,
+		},
+		{
+			name:      "关闭改写时直接返回原始查询",
+			model:     &replyModel{reply: "unused"},
+			config:    &QueryRewriteConfig{Enabled: false},
+			input:     &UserMessage{Query: "它怎么加分支？", History: history},
+			want:      "它怎么加分支？",
+			wantCalls: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newQueryRewriteLambda(tt.model, tt.config)(ctx, tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantCalls, tt.model.calls)
+		})
+	}
+}
+
+func TestMultiQueryRetriever(t *testing.T) {
+	ctx := context.Background()
+	r := &multiQueryRetriever{
+		retriever:  &queryRetriever{},
+		model:      &replyModel{reply: "```json\n[\"graph branch\", \"graph state\", \"graph callback\"]\n```"},
+		maxQueries: 3,
+		topK:       5,
+	}
+
+	docs, err := r.Retrieve(ctx, "graph")
+	assert.NoError(t, err)
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	// 原始查询总是保留，子查询数量受 maxQueries 限制
+	assert.Equal(t, "graph,graph branch,graph state", strings.Join(ids, ","))
+}
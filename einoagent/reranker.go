@@ -0,0 +1,100 @@
+package einoagent
+
+import (
+	"Eino-example/pkg/rerank"
+	"context"
+	"fmt"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"os"
+)
+
+const (
+	// RerankerLexical 按查询词覆盖率重排，无需调用模型
+	RerankerLexical = "lexical"
+	// RerankerLLM 使用聊天模型作为评审对候选文档打分
+	RerankerLLM = "llm"
+)
+
+// RerankerConfig 重排序配置
+type RerankerConfig struct {
+	// Type 重排序实现，为空表示不启用重排序，可选 lexical、llm
+	Type string
+	// CandidateK 启用重排序时检索器召回的候选文档数量
+	CandidateK int
+	// TopN 重排序后最多保留的文档数量
+	TopN int
+	// ScoreThreshold 低于该得分的文档会被丢弃，得分范围为 [0, 1]
+	ScoreThreshold float64
+}
+
+// Enabled 是否启用重排序
+func (c *RerankerConfig) Enabled() bool {
+	return c.Type != ""
+}
+
+// defaultRerankerConfig 从环境变量读取重排序配置：
+// RERANKER、RERANK_CANDIDATE_K、RERANK_TOP_N、RERANK_SCORE_THRESHOLD，未设置时使用默认值。
+func defaultRerankerConfig(ctx context.Context) (*RerankerConfig, error) {
+	config := &RerankerConfig{
+		Type: os.Getenv("RERANKER"),
+	}
+
+	var err error
+	if config.CandidateK, err = envInt("RERANK_CANDIDATE_K", 20); err != nil {
+		return nil, err
+	}
+	if config.TopN, err = envInt("RERANK_TOP_N", 5); err != nil {
+		return nil, err
+	}
+	if config.ScoreThreshold, err = envFloat("RERANK_SCORE_THRESHOLD", 0); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// newReranker 根据配置创建重排序器
+//
+// 参数:
+//
+//	ctx - 上下文对象
+//	config - 重排序配置
+//	cm - llm 重排序使用的聊天模型
+//
+// 返回值:
+//
+//	r - 重排序器实例
+//	err - 创建过程中可能发生的错误
+func newReranker(ctx context.Context, config *RerankerConfig, cm model.BaseChatModel) (r rerank.Reranker, err error) {
+	switch config.Type {
+	case RerankerLexical:
+		return rerank.NewLexicalReranker(), nil
+	case RerankerLLM:
+		return rerank.NewLLMReranker(ctx, &rerank.LLMRerankerConfig{Model: cm})
+	default:
+		return nil, fmt.Errorf("unknown reranker: %s", config.Type)
+	}
+}
+
+// newRerankLambda 创建重排序 Lambda 节点
+// 该节点从图状态中取出检索时使用的查询，对检索器过量召回的候选文档重新打分，
+// 丢弃低于阈值的文档，并只保留得分最高的 TopN 个文档传给聊天模板
+func newRerankLambda(r rerank.Reranker, config *RerankerConfig) *compose.Lambda {
+	return compose.InvokableLambda(func(ctx context.Context, docs []*schema.Document) ([]*schema.Document, error) {
+		var query string
+		err := compose.ProcessState(ctx, func(ctx context.Context, state *agentState) error {
+			query = state.Query
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		reranked, err := r.Rerank(ctx, query, docs)
+		if err != nil {
+			return nil, err
+		}
+		return rerank.Select(reranked, config.TopN, config.ScoreThreshold), nil
+	})
+}
@@ -1,121 +1,215 @@
 package einoagent
 
 import (
+	"Eino-example/pkg/vectorstore"
 	"context"
-	"encoding/json"
 	"fmt"
-	"github.com/cloudwego/eino-ext/components/retriever/es8"
-	"github.com/cloudwego/eino-ext/components/retriever/es8/search_mode"
+	"github.com/cloudwego/eino/components/embedding"
 	"github.com/cloudwego/eino/components/retriever"
 	"github.com/cloudwego/eino/schema"
-	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
-	"log"
 	"os"
+	"sort"
+	"sync"
 )
 
 const (
-	indexName          = "eino_example_index"
-	fieldContent       = "content"
-	fieldContentVector = "content_dense_vector"
-	metaData           = "meta"
+	// RetrieverModeDense 仅使用稠密向量相似度检索
+	RetrieverModeDense = "dense"
+	// RetrieverModeHybrid 同时使用 BM25 关键词检索与稠密向量检索，并用 RRF 融合结果
+	RetrieverModeHybrid = "hybrid"
 )
 
-// newRetriever 创建一个新的 Elasticsearch 检索器（Retriever），用于执行向量相似度搜索。
-// 它从环境变量中读取 Elasticsearch 的认证信息和地址，并初始化一个嵌入模型来支持语义检索。
+// RetrieverConfig 检索器配置
+type RetrieverConfig struct {
+	// Mode 检索模式，dense 或 hybrid
+	Mode string
+	// TopK 最终返回的文档数量
+	TopK int
+	// CandidateK hybrid 模式下每一路检索召回的候选文档数量
+	CandidateK int
+	// RRFK RRF 融合公式 1/(k+rank) 中的平滑常数 k
+	RRFK int
+	// DenseWeight 稠密向量检索结果的融合权重
+	DenseWeight float64
+	// KeywordWeight 关键词检索结果的融合权重
+	KeywordWeight float64
+}
+
+// defaultRetrieverConfig 从环境变量读取检索器配置：
+// RETRIEVER_MODE、RETRIEVER_TOP_K、RETRIEVER_CANDIDATE_K、RETRIEVER_RRF_K、
+// RETRIEVER_DENSE_WEIGHT、RETRIEVER_KEYWORD_WEIGHT，未设置时使用默认值。
+func defaultRetrieverConfig(ctx context.Context) (*RetrieverConfig, error) {
+	config := &RetrieverConfig{
+		Mode:          RetrieverModeDense,
+		TopK:          5,
+		RRFK:          60,
+		DenseWeight:   1,
+		KeywordWeight: 1,
+	}
+	if mode := os.Getenv("RETRIEVER_MODE"); mode != "" {
+		config.Mode = mode
+	}
+
+	var err error
+	if config.TopK, err = envInt("RETRIEVER_TOP_K", config.TopK); err != nil {
+		return nil, err
+	}
+	if config.CandidateK, err = envInt("RETRIEVER_CANDIDATE_K", config.TopK*4); err != nil {
+		return nil, err
+	}
+	if config.RRFK, err = envInt("RETRIEVER_RRF_K", config.RRFK); err != nil {
+		return nil, err
+	}
+	if config.DenseWeight, err = envFloat("RETRIEVER_DENSE_WEIGHT", config.DenseWeight); err != nil {
+		return nil, err
+	}
+	if config.KeywordWeight, err = envFloat("RETRIEVER_KEYWORD_WEIGHT", config.KeywordWeight); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// newRetriever 创建一个新的检索器（Retriever）。
+// 向量库后端由环境变量 VECTOR_STORE_BACKEND 选择：es8（默认）连接 Elasticsearch，
+// local 使用持久化在 data/ 目录下的内嵌向量库，无需 ES 集群即可运行。
+// 检索模式由 RETRIEVER_MODE 选择：dense（默认）仅做向量相似度检索，
+// hybrid 额外对 content 字段做 BM25 关键词检索，并用 RRF 融合两路结果，
+// 以便命中 AddLambdaNode、compose.AllPredecessor 这类精确标识符。
 //
 // 参数：
 //   - ctx: 上下文对象，用于控制请求的生命周期。
+//   - config: 检索器配置，为 nil 时从环境变量读取。
+//   - store: 向量库。
 //
 // 返回值：
 //   - rtr: 实现了 retriever.Retriever 接口的对象，可用于执行文档检索操作。
 //   - err: 如果在创建过程中发生错误，则返回相应的错误信息。
-func newRetriever(ctx context.Context) (rtr retriever.Retriever, err error) {
-
-	// 从环境变量获取 Elasticsearch 认证信息与主机地址
-	username := os.Getenv("ES_USERNAME")
-	password := os.Getenv("ES_PASSWORD")
-	host := os.Getenv("ES_URL")
-
-	// 初始化 Elasticsearch 客户端
-	client, err := elasticsearch.NewClient(elasticsearch.Config{
-		Addresses: []string{host},
-		Username:  username,
-		Password:  password,
-	})
-	if err != nil {
-		log.Fatalf("NewClient of es8 failed, err=%v", err)
+func newRetriever(ctx context.Context, config *RetrieverConfig, store vectorstore.Store) (rtr retriever.Retriever, err error) {
+	if config == nil {
+		config, err = defaultRetrieverConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// 初始化文本嵌入模型
-	embedder, err := newEmbedding(ctx)
-	if err != nil {
-		log.Fatalf("NewEmbedding failed, err=%v", err)
+	switch config.Mode {
+	case RetrieverModeDense:
+		return store.NewRetriever(ctx, &vectorstore.RetrieverConfig{
+			TopK:       config.TopK,
+			SearchMode: vectorstore.SearchModeDense,
+		})
+
+	case RetrieverModeHybrid:
+		// 每一路召回的候选数量不能少于最终返回的数量
+		candidateK := max(config.CandidateK, config.TopK)
+		dense, err := store.NewRetriever(ctx, &vectorstore.RetrieverConfig{
+			TopK:       candidateK,
+			SearchMode: vectorstore.SearchModeDense,
+		})
+		if err != nil {
+			return nil, err
+		}
+		keyword, err := store.NewRetriever(ctx, &vectorstore.RetrieverConfig{
+			TopK:       candidateK,
+			SearchMode: vectorstore.SearchModeKeyword,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &hybridRetriever{
+			retrievers: []retriever.Retriever{dense, keyword},
+			weights:    []float64{config.DenseWeight, config.KeywordWeight},
+			rrfK:       config.RRFK,
+			topK:       config.TopK,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown retriever mode: %s", config.Mode)
 	}
+}
 
-	// 构造并初始化 Retriever 对象，配置使用稠密向量余弦相似度进行搜索
-	rtr, err = es8.NewRetriever(ctx, &es8.RetrieverConfig{
-		Client: client,
-		Index:  indexName,
-		TopK:   5,
-		SearchMode: search_mode.SearchModeDenseVectorSimilarity(
-			search_mode.DenseVectorSimilarityTypeCosineSimilarity,
-			fieldContentVector,
-		),
-		// 自定义结果解析函数：将 Elasticsearch 响应转换为标准 Document 结构
-		ResultParser: func(ctx context.Context, hit types.Hit) (doc *schema.Document, err error) {
-			doc = &schema.Document{
-				ID:       *hit.Id_,
-				Content:  "",
-				MetaData: map[string]any{},
-			}
+// newVectorStore 根据环境变量创建向量库，embedder 为 nil 时使用 DashScope 嵌入模型
+func newVectorStore(ctx context.Context, embedder embedding.Embedder) (store vectorstore.Store, err error) {
+	if embedder == nil {
+		embedder, err = NewEmbedding(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-			var src map[string]any
-			if err = json.Unmarshal(hit.Source_, &src); err != nil {
-				return nil, err
-			}
+	storeConfig := vectorstore.ConfigFromEnv()
+	storeConfig.Embedding = embedder
+	return vectorstore.NewStore(ctx, storeConfig)
+}
 
-			// 遍历源数据字段，填充到 Document 中
-			for field, val := range src {
-				switch field {
-				case fieldContent:
-					doc.Content = val.(string)
-
-				case fieldContentVector:
-					var v []float64
-					for _, item := range val.([]interface{}) {
-						v = append(v, item.(float64))
-					}
-					doc.WithDenseVector(v)
-
-				case metaData:
-					metadata, err := StringToMap(val.(string))
-					if err != nil {
-						return nil, err
-					}
-					doc.MetaData = metadata
-
-				default:
-					return nil, fmt.Errorf("unexpected field=%s, val=%v", field, val)
-				}
-			}
+// hybridRetriever 并发执行多路检索，并用加权 RRF（Reciprocal Rank Fusion）融合结果
+type hybridRetriever struct {
+	retrievers []retriever.Retriever
+	weights    []float64
+	rrfK       int
+	topK       int
+}
 
-			// 设置匹配得分
-			if hit.Score_ != nil {
-				doc.WithScore(float64(*hit.Score_))
-			}
+func (h *hybridRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	options := retriever.GetCommonOptions(&retriever.Options{TopK: &h.topK}, opts...)
+
+	results := make([][]*schema.Document, len(h.retrievers))
+	errs := make([]error, len(h.retrievers))
+	var wg sync.WaitGroup
+	for i, r := range h.retrievers {
+		wg.Add(1)
+		go func(i int, r retriever.Retriever) {
+			defer wg.Done()
+			// 子检索器使用各自配置的候选数量，不透传 TopK
+			results[i], errs[i] = r.Retrieve(ctx, query)
+		}(i, r)
+	}
+	wg.Wait()
 
-			return doc, nil
-		},
-		Embedding: embedder,
-	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return rtr, err
+	docs := fuseRRF(results, h.weights, h.rrfK)
+	if len(docs) > *options.TopK {
+		docs = docs[:*options.TopK]
+	}
+	return docs, nil
+}
+
+func (h *hybridRetriever) GetType() string {
+	return "HybridRetriever"
 }
 
-// StringToMap 将 JSON 字符串转换为 map[string]any
-func StringToMap(s string) (map[string]any, error) {
-	var result map[string]any
-	err := json.Unmarshal([]byte(s), &result)
-	return result, err
+// fuseRRF 使用加权 RRF 融合多路检索结果：score(d) = Σ w_i / (k + rank_i(d))，rank 从 1 开始。
+// 文档按 ID 去重，返回的文档按融合得分降序排列，Score 为融合得分。
+func fuseRRF(results [][]*schema.Document, weights []float64, k int) []*schema.Document {
+	scores := make(map[string]float64)
+	docs := make(map[string]*schema.Document)
+	order := make([]string, 0)
+
+	for i, list := range results {
+		weight := 1.0
+		if i < len(weights) {
+			weight = weights[i]
+		}
+		for rank, doc := range list {
+			if _, ok := docs[doc.ID]; !ok {
+				docs[doc.ID] = doc
+				order = append(order, doc.ID)
+			}
+			scores[doc.ID] += weight / float64(k+rank+1)
+		}
+	}
+
+	fused := make([]*schema.Document, 0, len(order))
+	for _, id := range order {
+		fused = append(fused, docs[id].WithScore(scores[id]))
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score() > fused[j].Score()
+	})
+	return fused
 }
@@ -0,0 +1,78 @@
+package einoagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticRetriever 按固定顺序返回文档，用于测试
+type staticRetriever struct {
+	ids []string
+}
+
+func (s *staticRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	docs := make([]*schema.Document, 0, len(s.ids))
+	for _, id := range s.ids {
+		docs = append(docs, &schema.Document{ID: id, Content: id})
+	}
+	return docs, nil
+}
+
+func TestHybridRetriever(t *testing.T) {
+	tests := []struct {
+		name    string
+		dense   []string
+		keyword []string
+		weights []float64
+		topK    int
+		want    []string
+	}{
+		{
+			name:    "两路都命中的文档排在前面",
+			dense:   []string{"a", "b", "c"},
+			keyword: []string{"c", "d"},
+			weights: []float64{1, 1},
+			topK:    3,
+			want:    []string{"c", "a", "b"},
+		},
+		{
+			name:    "关键词权重更高时关键词结果优先",
+			dense:   []string{"a", "b"},
+			keyword: []string{"d", "e"},
+			weights: []float64{1, 2},
+			topK:    4,
+			want:    []string{"d", "e", "a", "b"},
+		},
+		{
+			name:    "TopK 截断",
+			dense:   []string{"a", "b", "c"},
+			keyword: nil,
+			weights: []float64{1, 1},
+			topK:    2,
+			want:    []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &hybridRetriever{
+				retrievers: []retriever.Retriever{&staticRetriever{ids: tt.dense}, &staticRetriever{ids: tt.keyword}},
+				weights:    tt.weights,
+				rrfK:       60,
+				topK:       tt.topK,
+			}
+			docs, err := h.Retrieve(context.Background(), "query")
+			assert.NoError(t, err)
+
+			got := make([]string, 0, len(docs))
+			for _, doc := range docs {
+				got = append(got, doc.ID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
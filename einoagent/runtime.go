@@ -0,0 +1,108 @@
+package einoagent
+
+import (
+	"context"
+	"fmt"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/joho/godotenv"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Runtime 长期存活的 Agent 运行时
+// 启动时编译一次图结构，之后所有请求共用同一个 Runnable 以及其中的模型、向量库和工具客户端；
+// 配置变更时重新构建，构建失败时保留原有的 Runnable 继续服务。
+type Runtime struct {
+	mu     sync.RWMutex
+	runner compose.Runnable[*UserMessage, *schema.Message]
+	opts   []Option
+}
+
+// NewRuntime 创建 Agent 运行时并立即构建图结构，构建失败时返回错误，便于在启动阶段暴露配置问题
+//
+// 参数:
+//
+//	ctx - 上下文对象
+//	opts - 每次构建时传给 BuildEinoAgent 的可选配置
+//
+// 返回值:
+//
+//	r - Agent 运行时
+//	err - 构建图结构时发生的错误
+func NewRuntime(ctx context.Context, opts ...Option) (r *Runtime, err error) {
+	r = &Runtime{opts: opts}
+	if err = r.Rebuild(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Runner 返回当前的 Runnable，调用方在一次请求中应只获取一次
+func (r *Runtime) Runner() compose.Runnable[*UserMessage, *schema.Message] {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.runner
+}
+
+// Rebuild 重新构建图结构，成功后替换当前的 Runnable，正在执行的请求不受影响
+func (r *Runtime) Rebuild(ctx context.Context) error {
+	runner, err := BuildEinoAgent(ctx, r.opts...)
+	if err != nil {
+		return fmt.Errorf("failed to build agent graph: %w", err)
+	}
+
+	r.mu.Lock()
+	r.runner = runner
+	r.mu.Unlock()
+	return nil
+}
+
+// WatchEnvFile 轮询环境变量文件，文件修改后重新加载其中的环境变量并重新构建图结构，直到 ctx 结束
+// 文件中删除的变量不会从进程环境变量中移除
+//
+// 参数:
+//
+//	ctx - 上下文对象，结束时停止监听
+//	path - 环境变量文件路径，通常为 .env
+//	interval - 轮询间隔
+func (r *Runtime) WatchEnvFile(ctx context.Context, path string, interval time.Duration) {
+	modTime := fileModTime(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		t := fileModTime(path)
+		if t.Equal(modTime) {
+			continue
+		}
+		modTime = t
+
+		if err := godotenv.Overload(path); err != nil {
+			log.Printf("[Runtime] reload %s failed: %v\n", path, err)
+			continue
+		}
+		if err := r.Rebuild(ctx); err != nil {
+			log.Printf("[Runtime] rebuild agent failed, keep the previous one: %v\n", err)
+			continue
+		}
+		log.Printf("[Runtime] %s changed, agent rebuilt\n", path)
+	}
+}
+
+// fileModTime 返回文件的修改时间，文件不存在时返回零值
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -1,16 +1,19 @@
 package einoagent
 
 import (
+	"Eino-example/knowledgeindexing"
 	"Eino-example/pkg/tool/einotool"
 	"Eino-example/pkg/tool/gitclone"
 	"Eino-example/pkg/tool/open"
 	"Eino-example/pkg/tool/task"
+	"Eino-example/pkg/vectorstore"
 	"context"
 	"github.com/cloudwego/eino-ext/components/tool/duckduckgo/v2"
 	"github.com/cloudwego/eino/components/tool"
 )
 
-func GetTools(ctx context.Context) ([]tool.BaseTool, error) {
+// GetTools 创建 ReAct Agent 默认的工具，gitclone 将仓库索引到检索使用的向量库 store 中
+func GetTools(ctx context.Context, store vectorstore.Store) ([]tool.BaseTool, error) {
 	einoAssistantTool, err := NewEinoAssistantTool(ctx)
 	if err != nil {
 		return nil, err
@@ -26,7 +29,12 @@ func GetTools(ctx context.Context) ([]tool.BaseTool, error) {
 		return nil, err
 	}
 
-	toolGitClone, err := NewGitCloneFile(ctx)
+	toolGitClone, err := NewGitCloneFile(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	toolRepoWorkspace, err := NewRepoWorkspace(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +49,7 @@ func GetTools(ctx context.Context) ([]tool.BaseTool, error) {
 		toolTask,
 		toolOpen,
 		toolGitClone,
+		toolRepoWorkspace,
 		toolDDGSearch,
 	}, nil
 }
@@ -68,14 +77,42 @@ func NewOpenFileTool(ctx context.Context) (tn tool.BaseTool, err error) {
 	return open.NewOpenFileTool(ctx, nil)
 }
 
-func NewGitCloneFile(ctx context.Context) (tn tool.BaseTool, err error) {
-	return gitclone.NewGitCloneFile(ctx, nil)
+// NewGitCloneFile 创建 gitclone 工具，clone 或 pull 之后将仓库中的 Go 代码和 markdown 增量索引到向量库 store
+func NewGitCloneFile(ctx context.Context, store vectorstore.Store) (tn tool.BaseTool, err error) {
+	config := &gitclone.GitCloneFileConfig{BaseDir: gitclone.DefaultBaseDir}
+	repoIndexer, err := knowledgeindexing.NewRepoIndexer(ctx, &knowledgeindexing.RepoIndexerConfig{BaseDir: config.BaseDir},
+		knowledgeindexing.WithVectorStore(store))
+	if err != nil {
+		return nil, err
+	}
+	config.AfterSync = func(ctx context.Context, repoPath string) (string, error) {
+		result, err := repoIndexer.Index(ctx, repoPath)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	}
+	return gitclone.NewGitCloneFile(ctx, config)
+}
+
+// NewRepoWorkspace 创建 repo_workspace 工具，查看 gitclone 克隆到同一目录下的仓库
+func NewRepoWorkspace(ctx context.Context) (tn tool.BaseTool, err error) {
+	return gitclone.NewRepoWorkspace(ctx, nil)
 }
 
 func NewEinoAssistantTool(ctx context.Context) (tn tool.BaseTool, err error) {
 	return einotool.NewEinoAssistantTool(ctx, nil)
 }
 
+// NewTaskTool 创建 task_manager 工具，并使用 DashScope embedding 开启 task 的语义搜索，
+// embedding 不可用时语义搜索退化为本地的特征哈希向量。工具访问 ctx 中用户的 task 和共享给该用户的 project
 func NewTaskTool(ctx context.Context) (tn tool.BaseTool, err error) {
-	return task.NewTaskTool(ctx, nil)
+	embedder, err := NewEmbedding(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return task.NewTaskTool(ctx, &task.TaskToolConfig{
+		Namespaces: task.GetDefaultNamespaces(),
+		Embedder:   embedder,
+	})
 }
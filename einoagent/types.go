@@ -7,3 +7,9 @@ type UserMessage struct {
 	Query   string            `json:"query"`
 	History []*schema.Message `json:"history"`
 }
+
+// agentState 图执行过程中的本地状态，在节点之间共享中间数据
+type agentState struct {
+	// Query 检索器实际使用的查询
+	Query string
+}
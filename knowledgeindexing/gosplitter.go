@@ -0,0 +1,104 @@
+package knowledgeindexing
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// goChunk is a top level declaration of a Go file together with its doc comment.
+type goChunk struct {
+	// Kind is one of package, func, method, type, const and var
+	Kind string
+	// Symbol is the declared name, Recv.Name for methods and the comma separated names of grouped declarations
+	Symbol    string
+	StartLine int
+	EndLine   int
+	Content   string
+}
+
+// splitGoSource splits Go source into its top level declarations, imports are dropped
+// and the package doc comment becomes a chunk of its own.
+//
+// Parameters:
+//   - src: content of the Go file
+//
+// Returns:
+//   - pkg: the package name
+//   - chunks: declarations in source order
+//   - err: error if the source cannot be parsed
+func splitGoSource(src []byte) (pkg string, chunks []*goChunk, err error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return "", nil, err
+	}
+	pkg = f.Name.Name
+
+	chunk := func(kind, symbol string, doc *ast.CommentGroup, node ast.Node) *goChunk {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		startPos, endPos := fset.Position(start), fset.Position(node.End())
+		return &goChunk{
+			Kind:      kind,
+			Symbol:    symbol,
+			StartLine: startPos.Line,
+			EndLine:   endPos.Line,
+			Content:   string(src[startPos.Offset:endPos.Offset]),
+		}
+	}
+
+	if f.Doc != nil {
+		chunks = append(chunks, chunk("package", pkg, f.Doc, f.Name))
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				chunks = append(chunks, chunk("method", receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, d.Doc, d))
+			} else {
+				chunks = append(chunks, chunk("func", d.Name.Name, d.Doc, d))
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			chunks = append(chunks, chunk(d.Tok.String(), strings.Join(specNames(d), ", "), d.Doc, d))
+		}
+	}
+	return pkg, chunks, nil
+}
+
+// receiverName returns the type name of a method receiver, without pointer and type parameters.
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	default:
+		return ""
+	}
+}
+
+func specNames(d *ast.GenDecl) []string {
+	var names []string
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	return names
+}
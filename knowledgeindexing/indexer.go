@@ -1,79 +1,49 @@
 package knowledgeindexing
 
 import (
+	"Eino-example/pkg/vectorstore"
 	"context"
-	"encoding/json"
-	"fmt"
-	"github.com/cloudwego/eino-ext/components/indexer/es8"
 	"github.com/cloudwego/eino/components/indexer"
-	"github.com/cloudwego/eino/schema"
-	"github.com/elastic/go-elasticsearch/v8"
-	"log"
-	"os"
 )
 
-const (
-	indexName               = "eino_example_index"
-	fieldContent            = "content"
-	fieldContentDenseVector = "content_dense_vector"
-	metaData                = "meta"
-)
-
-// newIndexer creates a new Elasticsearch indexer instance with embedding support.
-// It initializes the ES client, creates the index if needed, and sets up the document indexing pipeline.
+// newIndexer creates a new vector store indexer instance with embedding support.
+// The backend is selected by VECTOR_STORE_BACKEND: es8 (default) creates the index if needed,
+// local stores documents in an embedded file-backed index under data/.
 //
 // Parameters:
 //   - ctx: context for controlling the lifecycle of the operation
+//   - o: options of BuildKnowledgeIndexing, the vector store and embedder set there are used if any
 //
 // Returns:
 //   - idr: the created indexer instance for indexing documents
 //   - err: error if the indexer creation fails, nil otherwise
-func newIndexer(ctx context.Context) (idr indexer.Indexer, err error) {
-	// es supports multiple ways to connect
-	username := os.Getenv("ES_USERNAME")
-	password := os.Getenv("ES_PASSWORD")
-	host := os.Getenv("ES_URL")
-
-	client, err := elasticsearch.NewClient(elasticsearch.Config{
-		Addresses: []string{host},
-		Username:  username,
-		Password:  password,
-	})
+func newIndexer(ctx context.Context, o *options) (idr indexer.Indexer, err error) {
+	store, err := newStore(ctx, o)
 	if err != nil {
-		log.Fatalf("NewClient of es8 failed, err=%v", err)
-	}
-
-	// create index if needed.
-	// comment out the code if index has been created.
-	if err = createIndex(ctx, client); err != nil {
-		log.Fatalf("createIndex of es8 failed, err=%v", err)
+		return nil, err
 	}
-	embedder, err := newEmbedding(ctx)
 
-	// create es indexer component
-	idr, err = es8.NewIndexer(ctx, &es8.IndexerConfig{
-		Client:    client,
-		Index:     indexName,
+	return store.NewIndexer(ctx, &vectorstore.IndexerConfig{
 		BatchSize: 10,
-		DocumentToFields: func(ctx context.Context, doc *schema.Document) (field2Value map[string]es8.FieldValue, err error) {
-			metadataBytes, err := json.Marshal(doc.MetaData)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal metadata: %w", err)
-			}
-			return map[string]es8.FieldValue{
-				fieldContent: {
-					Value:    doc.Content,
-					EmbedKey: fieldContentDenseVector, // vectorize doc content and save vector to field "content_vector"
-				},
-				metaData: {
-					Value: string(metadataBytes),
-				},
-			}, nil
-		},
-		Embedding: embedder, // replace it with real embedding component
 	})
-	if err != nil {
-		log.Fatalf("NewIndexer of es8 failed, err=%v", err)
+}
+
+// newStore returns the vector store set by WithVectorStore, or creates one from
+// environment variables with the embedder set by WithEmbedder or the DashScope embedder.
+func newStore(ctx context.Context, o *options) (store vectorstore.Store, err error) {
+	if o.store != nil {
+		return o.store, nil
 	}
-	return idr, err
+
+	embedder := o.embedder
+	if embedder == nil {
+		embedder, err = newEmbedding(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config := vectorstore.ConfigFromEnv()
+	config.Embedding = embedder
+	return vectorstore.NewStore(ctx, config)
 }
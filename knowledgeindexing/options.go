@@ -0,0 +1,38 @@
+package knowledgeindexing
+
+import (
+	"Eino-example/pkg/vectorstore"
+	"github.com/cloudwego/eino/components/document"
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// Option is an optional config of BuildKnowledgeIndexing, it replaces the
+// components created from environment variables, e.g. with the fakes of pkg/fake in tests.
+type Option func(*options)
+
+type options struct {
+	embedder    embedding.Embedder
+	store       vectorstore.Store
+	transformer document.Transformer
+}
+
+// WithEmbedder sets the embedder of the vector store, it is ignored if WithVectorStore is set.
+func WithEmbedder(embedder embedding.Embedder) Option {
+	return func(o *options) {
+		o.embedder = embedder
+	}
+}
+
+// WithTransformer replaces the markdown header splitter that splits the loaded documents.
+func WithTransformer(transformer document.Transformer) Option {
+	return func(o *options) {
+		o.transformer = transformer
+	}
+}
+
+// WithVectorStore sets the vector store the documents are indexed into.
+func WithVectorStore(store vectorstore.Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
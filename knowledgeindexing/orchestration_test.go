@@ -0,0 +1,47 @@
+package knowledgeindexing
+
+import (
+	"Eino-example/pkg/fake"
+	"Eino-example/pkg/vectorstore"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudwego/eino/components/document"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildKnowledgeIndexing(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "eino.md")
+	err := os.WriteFile(path, []byte("# Eino\n\n## Graph\n\nGraph 支持分支与循环。\n\n## Chain\n\nChain 是线性的编排方式。\n"), 0644)
+	assert.NoError(t, err)
+
+	store, err := vectorstore.NewStore(ctx, &vectorstore.Config{
+		Backend:   vectorstore.BackendLocal,
+		Dir:       filepath.Join(dir, "vectorstore"),
+		Embedding: fake.NewEmbedder(0),
+	})
+	assert.NoError(t, err)
+
+	r, err := BuildKnowledgeIndexing(ctx, WithVectorStore(store))
+	assert.NoError(t, err)
+
+	ids, err := r.Invoke(ctx, document.Source{URI: path})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ids)
+
+	// 索引后的文档片段可以按内容检索，并保留来源与标题元数据
+	rtr, err := store.NewRetriever(ctx, &vectorstore.RetrieverConfig{TopK: 1})
+	assert.NoError(t, err)
+	docs, err := rtr.Retrieve(ctx, "Chain 线性编排")
+	assert.NoError(t, err)
+	assert.Len(t, docs, 1)
+	assert.Contains(t, docs[0].Content, "Chain 是线性的编排方式")
+	assert.Equal(t, "Eino", docs[0].MetaData["title"])
+	assert.Equal(t, "Chain", docs[0].MetaData["subtitle"])
+	assert.Equal(t, path, docs[0].MetaData["_source"])
+}
@@ -0,0 +1,406 @@
+package knowledgeindexing
+
+import (
+	"Eino-example/pkg/vectorstore"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/document"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// DefaultRepoStateDir keeps the indexed commit and chunk ids of every repository.
+	DefaultRepoStateDir = "data/repoindex"
+
+	// metadata of the repository chunks, besides the _source set by the file loader
+	MetaKeyRepo      = "repo"
+	MetaKeyPath      = "path"
+	MetaKeyCommit    = "commit"
+	MetaKeyKind      = "kind"
+	MetaKeySymbol    = "symbol"
+	MetaKeyStartLine = "start_line"
+	MetaKeyEndLine   = "end_line"
+
+	metaKeySource = "_source"
+
+	maxRepoFileSize = 1 << 20
+)
+
+// skippedRepoDirs are not indexed, they hold vendored or generated content.
+var skippedRepoDirs = []string{"vendor", "testdata", "node_modules", ".git"}
+
+type RepoIndexerConfig struct {
+	// BaseDir is where gitclone clones into, a repository is named by its path relative to it, e.g. cloudwego/eino-examples.
+	BaseDir string
+	// StateDir keeps the indexing state of the repositories, DefaultRepoStateDir by default.
+	StateDir string
+}
+
+// RepoIndexer indexes the Go sources and markdown files of cloned repositories with the
+// BuildKnowledgeIndexing graph: Go files are split by top level declarations, markdown files
+// by headers, and every chunk is tagged with the repo, path and commit it comes from.
+// The chunk ids of every file are remembered with the indexed commit, so indexing the
+// repository again after a pull only re-indexes the files changed since then.
+type RepoIndexer struct {
+	config *RepoIndexerConfig
+	store  vectorstore.Store
+
+	mu sync.Mutex
+}
+
+// RepoIndexResult is what Index changed in the knowledge base.
+type RepoIndexResult struct {
+	Repo   string `json:"repo"`
+	Commit string `json:"commit"`
+	// Full is true if the whole repository was indexed instead of the changed files
+	Full bool `json:"full"`
+	// Indexed are the files indexed, Removed the files whose chunks were deleted only
+	Indexed []string `json:"indexed"`
+	Removed []string `json:"removed"`
+	Chunks  int      `json:"chunks"`
+}
+
+func (r *RepoIndexResult) String() string {
+	return fmt.Sprintf("indexed %d files (%d chunks), removed %d files of %s@%s",
+		len(r.Indexed), r.Chunks, len(r.Removed), r.Repo, shortCommit(r.Commit))
+}
+
+// repoState is saved as <StateDir>/<repo>.json
+type repoState struct {
+	Commit string              `json:"commit"`
+	Files  map[string][]string `json:"files"`
+}
+
+// NewRepoIndexer creates a repository indexer.
+//
+// Parameters:
+//   - ctx: context for controlling the lifecycle of the operation
+//   - config: dirs of the cloned repositories and of the indexing state
+//   - opts: options of BuildKnowledgeIndexing, the vector store is created from environment variables if not set
+//
+// Returns:
+//   - the created indexer
+//   - err: error if the vector store cannot be created
+func NewRepoIndexer(ctx context.Context, config *RepoIndexerConfig, opts ...Option) (*RepoIndexer, error) {
+	if config == nil || config.BaseDir == "" {
+		return nil, fmt.Errorf("base dir cannot be empty")
+	}
+	if config.StateDir == "" {
+		config.StateDir = DefaultRepoStateDir
+	}
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	store, err := newStore(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RepoIndexer{config: config, store: store}, nil
+}
+
+// Index indexes the repository at repoPath, which must be inside BaseDir. The first call
+// indexes every tracked file, later calls re-index the files changed since the indexed commit
+// and delete the chunks of the removed ones; nothing is done if HEAD is still the indexed commit.
+func (r *RepoIndexer) Index(ctx context.Context, repoPath string) (*RepoIndexResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	root, repo, err := r.resolveRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	head, err := git(ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	head = strings.TrimSpace(head)
+
+	state, err := r.loadState(repo)
+	if err != nil {
+		return nil, err
+	}
+	result := &RepoIndexResult{Repo: repo, Commit: head}
+	if state.Commit == head {
+		return result, nil
+	}
+
+	paths, err := changedFiles(ctx, root, state.Commit, head)
+	if err != nil {
+		// the indexed commit is unknown, e.g. the first index or a force push
+		result.Full = true
+		files, err := git(ctx, root, "ls-files", "-z")
+		if err != nil {
+			return nil, err
+		}
+		paths = splitNull(files)
+		for path := range state.Files {
+			paths = append(paths, path)
+		}
+	}
+	paths = uniqueSorted(paths)
+
+	runner, err := BuildKnowledgeIndexing(ctx,
+		WithVectorStore(r.store),
+		WithTransformer(&repoSplitter{root: root, repo: repo, commit: head}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		if ids := state.Files[path]; len(ids) > 0 {
+			if err := r.store.Delete(ctx, ids); err != nil {
+				return nil, r.failed(repo, state, fmt.Errorf("delete chunks of %s failed: %w", path, err))
+			}
+			delete(state.Files, path)
+			if !indexable(root, path) {
+				result.Removed = append(result.Removed, path)
+			}
+		}
+		if !indexable(root, path) {
+			continue
+		}
+
+		ids, err := runner.Invoke(ctx, document.Source{URI: filepath.Join(root, filepath.FromSlash(path))})
+		if err != nil {
+			return nil, r.failed(repo, state, fmt.Errorf("index %s failed: %w", path, err))
+		}
+		state.Files[path] = ids
+		result.Indexed = append(result.Indexed, path)
+		result.Chunks += len(ids)
+	}
+
+	state.Commit = head
+	if err := r.saveState(repo, state); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// failed saves the files indexed so far, they are indexed again by the next call
+// since the indexed commit is unchanged.
+func (r *RepoIndexer) failed(repo string, state *repoState, err error) error {
+	if saveErr := r.saveState(repo, state); saveErr != nil {
+		return fmt.Errorf("%w, save state failed: %v", err, saveErr)
+	}
+	return err
+}
+
+func (r *RepoIndexer) resolveRepo(repoPath string) (root, repo string, err error) {
+	base, err := filepath.Abs(r.config.BaseDir)
+	if err != nil {
+		return "", "", err
+	}
+	root, err = filepath.Abs(repoPath)
+	if err != nil {
+		return "", "", err
+	}
+	rel, err := filepath.Rel(base, root)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("repo %s is not inside %s", repoPath, r.config.BaseDir)
+	}
+	return root, filepath.ToSlash(rel), nil
+}
+
+func (r *RepoIndexer) statePath(repo string) string {
+	return filepath.Join(r.config.StateDir, filepath.FromSlash(repo)+".json")
+}
+
+func (r *RepoIndexer) loadState(repo string) (*repoState, error) {
+	state := &repoState{Files: map[string][]string{}}
+	data, err := os.ReadFile(r.statePath(repo))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read index state of %s failed: %w", repo, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unmarshal index state of %s failed: %w", repo, err)
+	}
+	if state.Files == nil {
+		state.Files = map[string][]string{}
+	}
+	return state, nil
+}
+
+func (r *RepoIndexer) saveState(repo string, state *repoState) error {
+	path := r.statePath(repo)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write index state of %s failed: %w", repo, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// changedFiles lists the files changed between two commits, renames are reported as
+// a deleted and an added file so the chunks of the old path are removed.
+func changedFiles(ctx context.Context, root, from, to string) ([]string, error) {
+	if from == "" {
+		return nil, fmt.Errorf("no indexed commit")
+	}
+	out, err := git(ctx, root, "diff", "--name-only", "--no-renames", "-z", from, to, "--")
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(out), nil
+}
+
+// indexable reports whether path is a Go or markdown file that still exists in the work tree.
+func indexable(root, path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go", ".md", ".markdown":
+	default:
+		return false
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		for _, skipped := range skippedRepoDirs {
+			if dir == skipped {
+				return false
+			}
+		}
+	}
+	info, err := os.Stat(filepath.Join(root, filepath.FromSlash(path)))
+	return err == nil && info.Mode().IsRegular() && info.Size() <= maxRepoFileSize
+}
+
+func git(ctx context.Context, root string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", root}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w, output: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+func splitNull(s string) []string {
+	var parts []string
+	for _, part := range strings.Split(s, "\x00") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func uniqueSorted(paths []string) []string {
+	sort.Strings(paths)
+	unique := paths[:0]
+	for i, path := range paths {
+		if i == 0 || path != paths[i-1] {
+			unique = append(unique, path)
+		}
+	}
+	return unique
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// repoSplitter splits the files of a repository loaded by the file loader: Go files by
+// top level declarations and markdown files by headers. The chunk ids are derived from
+// the repo and path, so re-indexing a file overwrites its chunks.
+type repoSplitter struct {
+	root   string
+	repo   string
+	commit string
+
+	markdown document.Transformer
+}
+
+func (s *repoSplitter) Transform(ctx context.Context, src []*schema.Document, opts ...document.TransformerOption) ([]*schema.Document, error) {
+	var chunks []*schema.Document
+	for _, doc := range src {
+		source, _ := doc.MetaData[metaKeySource].(string)
+		rel, err := filepath.Rel(s.root, source)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.ToSlash(rel)
+
+		var docs []*schema.Document
+		if strings.HasSuffix(path, ".go") {
+			docs = s.splitGo(doc)
+		} else {
+			if s.markdown == nil {
+				if s.markdown, err = NewTransformer(ctx); err != nil {
+					return nil, err
+				}
+			}
+			if docs, err = s.markdown.Transform(ctx, []*schema.Document{doc}, opts...); err != nil {
+				return nil, err
+			}
+		}
+
+		for i, chunk := range docs {
+			if chunk.MetaData == nil {
+				chunk.MetaData = map[string]any{}
+			}
+			chunk.ID = fmt.Sprintf("%s/%s#%d", s.repo, path, i)
+			chunk.MetaData[metaKeySource] = source
+			chunk.MetaData[MetaKeyRepo] = s.repo
+			chunk.MetaData[MetaKeyPath] = path
+			chunk.MetaData[MetaKeyCommit] = s.commit
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
+// splitGo returns a chunk per declaration prefixed with the package clause, a file that
+// cannot be parsed is kept as a single chunk.
+func (s *repoSplitter) splitGo(doc *schema.Document) []*schema.Document {
+	pkg, decls, err := splitGoSource([]byte(doc.Content))
+	if err != nil || len(decls) == 0 {
+		return []*schema.Document{{Content: doc.Content, MetaData: copyMeta(doc.MetaData)}}
+	}
+
+	docs := make([]*schema.Document, 0, len(decls))
+	for _, decl := range decls {
+		meta := copyMeta(doc.MetaData)
+		// shown as the heading of the citation
+		meta["title"] = decl.Kind + " " + decl.Symbol
+		meta[MetaKeyKind] = decl.Kind
+		meta[MetaKeySymbol] = decl.Symbol
+		meta[MetaKeyStartLine] = decl.StartLine
+		meta[MetaKeyEndLine] = decl.EndLine
+		docs = append(docs, &schema.Document{
+			Content:  "package " + pkg + "\n\n" + decl.Content,
+			MetaData: meta,
+		})
+	}
+	return docs
+}
+
+func copyMeta(meta map[string]any) map[string]any {
+	c := make(map[string]any, len(meta))
+	for k, v := range meta {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,180 @@
+package knowledgeindexing
+
+import (
+	"Eino-example/pkg/fake"
+	"Eino-example/pkg/vectorstore"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const demoGo = `// Package demo greets people.
+package demo
+
+import "fmt"
+
+// Greeter greets people by name.
+type Greeter struct {
+	Prefix string
+}
+
+// Greet returns the greeting of name.
+func (g *Greeter) Greet(name string) string {
+	return fmt.Sprintf("%s %s", g.Prefix, name)
+}
+
+const (
+	DefaultPrefix = "hello"
+	OtherPrefix   = "hi"
+)
+
+func New() *Greeter {
+	return &Greeter{Prefix: DefaultPrefix}
+}
+`
+
+func TestSplitGoSource(t *testing.T) {
+	pkg, chunks, err := splitGoSource([]byte(demoGo))
+	assert.NoError(t, err)
+	assert.Equal(t, "demo", pkg)
+
+	var symbols []string
+	for _, c := range chunks {
+		symbols = append(symbols, c.Kind+" "+c.Symbol)
+	}
+	assert.Equal(t, []string{"package demo", "type Greeter", "method Greeter.Greet", "const DefaultPrefix, OtherPrefix", "func New"}, symbols)
+
+	// 声明带上文档注释，并记录行号
+	assert.Equal(t, "// Greet returns the greeting of name.\nfunc (g *Greeter) Greet(name string) string {\n\treturn fmt.Sprintf(\"%s %s\", g.Prefix, name)\n}", chunks[2].Content)
+	assert.Equal(t, 11, chunks[2].StartLine)
+	assert.Equal(t, 14, chunks[2].EndLine)
+
+	_, _, err = splitGoSource([]byte("package demo\n\nfunc broken( {"))
+	assert.Error(t, err)
+}
+
+func TestRepoIndexer(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	base := filepath.Join(dir, "repos")
+	repo := filepath.Join(base, "cloudwego", "demo")
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=tester", "-c", "user.email=tester@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		path := filepath.Join(repo, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	assert.NoError(t, os.MkdirAll(repo, 0755))
+	git("init", "-q")
+	write("README.md", "# Demo\n\nA demo repo.\n\n## Usage\n\nCall New then Greet.\n")
+	write("demo.go", demoGo)
+	write("old/old.go", "package old\n\n// Old is renamed later.\nfunc Old() {}\n")
+	write("vendor/lib/lib.go", "package lib\n\nfunc Lib() {}\n")
+	write("go.mod", "module demo\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	store, err := vectorstore.NewStore(ctx, &vectorstore.Config{
+		Backend:   vectorstore.BackendLocal,
+		Dir:       filepath.Join(dir, "vectorstore"),
+		Embedding: fake.NewEmbedder(0),
+	})
+	assert.NoError(t, err)
+	indexer, err := NewRepoIndexer(ctx, &RepoIndexerConfig{BaseDir: base, StateDir: filepath.Join(dir, "state")}, WithVectorStore(store))
+	assert.NoError(t, err)
+
+	// 返回向量库中的全部文档 id
+	documents := func() map[string]map[string]any {
+		rtr, err := store.NewRetriever(ctx, &vectorstore.RetrieverConfig{TopK: 100})
+		assert.NoError(t, err)
+		docs, err := rtr.Retrieve(ctx, "demo")
+		assert.NoError(t, err)
+		byID := map[string]map[string]any{}
+		for _, doc := range docs {
+			byID[doc.ID] = doc.MetaData
+		}
+		return byID
+	}
+	ids := func(docs map[string]map[string]any) []string {
+		var ids []string
+		for id := range docs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	t.Run("首次索引全部文件", func(t *testing.T) {
+		result, err := indexer.Index(ctx, repo)
+		assert.NoError(t, err)
+		assert.True(t, result.Full)
+		assert.Equal(t, "cloudwego/demo", result.Repo)
+		assert.Equal(t, []string{"README.md", "demo.go", "old/old.go"}, result.Indexed)
+
+		docs := documents()
+		assert.Equal(t, []string{
+			"cloudwego/demo/README.md#0", "cloudwego/demo/README.md#1",
+			"cloudwego/demo/demo.go#0", "cloudwego/demo/demo.go#1", "cloudwego/demo/demo.go#2", "cloudwego/demo/demo.go#3", "cloudwego/demo/demo.go#4",
+			"cloudwego/demo/old/old.go#0",
+		}, ids(docs))
+		assert.Equal(t, result.Chunks, len(docs))
+
+		meta := docs["cloudwego/demo/demo.go#2"]
+		assert.Equal(t, "cloudwego/demo", meta[MetaKeyRepo])
+		assert.Equal(t, "demo.go", meta[MetaKeyPath])
+		assert.Equal(t, result.Commit, meta[MetaKeyCommit])
+		assert.Equal(t, "Greeter.Greet", meta[MetaKeySymbol])
+		assert.Equal(t, "method Greeter.Greet", meta["title"])
+		assert.Equal(t, filepath.Join(repo, "demo.go"), meta[metaKeySource])
+		assert.Equal(t, "Usage", docs["cloudwego/demo/README.md#1"]["subtitle"])
+	})
+
+	t.Run("HEAD 未变化时不重复索引", func(t *testing.T) {
+		result, err := indexer.Index(ctx, repo)
+		assert.NoError(t, err)
+		assert.Empty(t, result.Indexed)
+		assert.Empty(t, result.Removed)
+	})
+
+	t.Run("只重新索引变化的文件", func(t *testing.T) {
+		write("README.md", "# Demo\n\nA demo repo.\n")
+		git("mv", "old/old.go", "renamed.go")
+		git("commit", "-q", "-am", "change")
+
+		// 重新创建 indexer，状态从文件中恢复
+		indexer, err := NewRepoIndexer(ctx, &RepoIndexerConfig{BaseDir: base, StateDir: filepath.Join(dir, "state")}, WithVectorStore(store))
+		assert.NoError(t, err)
+		result, err := indexer.Index(ctx, repo)
+		assert.NoError(t, err)
+		assert.False(t, result.Full)
+		assert.Equal(t, []string{"README.md", "renamed.go"}, result.Indexed)
+		assert.Equal(t, []string{"old/old.go"}, result.Removed)
+
+		docs := documents()
+		assert.Equal(t, []string{
+			"cloudwego/demo/README.md#0",
+			"cloudwego/demo/demo.go#0", "cloudwego/demo/demo.go#1", "cloudwego/demo/demo.go#2", "cloudwego/demo/demo.go#3", "cloudwego/demo/demo.go#4",
+			"cloudwego/demo/renamed.go#0",
+		}, ids(docs))
+		assert.Equal(t, result.Commit, docs["cloudwego/demo/README.md#0"][MetaKeyCommit])
+		assert.NotEqual(t, result.Commit, docs["cloudwego/demo/demo.go#0"][MetaKeyCommit])
+	})
+
+	t.Run("仓库必须在 BaseDir 下", func(t *testing.T) {
+		_, err := indexer.Index(ctx, dir)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// DefaultUser is the user of every request when authentication is disabled.
+const DefaultUser = "default"
+
+// CookieToken keeps the token of the web pages, EventSource cannot send an Authorization header.
+const CookieToken = "eino_token"
+
+type userKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated user.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the authenticated user of ctx, DefaultUser if there is none.
+func UserFrom(ctx context.Context) string {
+	if user, ok := ctx.Value(userKey{}).(string); ok && user != "" {
+		return user
+	}
+	return DefaultUser
+}
+
+// Tokens maps bearer tokens to user ids, authentication is disabled when it is empty.
+type Tokens map[string]string
+
+// ParseTokens parses a comma separated list of user:token pairs.
+func ParseTokens(s string) (Tokens, error) {
+	tokens := Tokens{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		user, token, ok := strings.Cut(pair, ":")
+		user, token = strings.TrimSpace(user), strings.TrimSpace(token)
+		if !ok || user == "" || token == "" {
+			return nil, fmt.Errorf("invalid token pair %q, expect user:token", pair)
+		}
+		if _, exists := tokens[token]; exists {
+			return nil, fmt.Errorf("duplicated token of user %s", user)
+		}
+		tokens[token] = user
+	}
+	return tokens, nil
+}
+
+// TokensFromEnv reads the tokens from AUTH_TOKENS, e.g. AUTH_TOKENS=alice:token-a,bob:token-b.
+func TokensFromEnv() (Tokens, error) {
+	tokens, err := ParseTokens(os.Getenv("AUTH_TOKENS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid env AUTH_TOKENS: %w", err)
+	}
+	return tokens, nil
+}
+
+// Enabled reports whether requests must carry a token.
+func (t Tokens) Enabled() bool {
+	return len(t) > 0
+}
+
+// Authenticate returns the user of the token in the Authorization bearer header
+// or the CookieToken cookie. Tokens in the query are not accepted, URLs end up
+// in logs and browser history.
+func (t Tokens) Authenticate(c *app.RequestContext) (string, bool) {
+	if !t.Enabled() {
+		return DefaultUser, true
+	}
+	token := strings.TrimPrefix(string(c.GetHeader("Authorization")), "Bearer ")
+	if token == "" {
+		token = string(c.Cookie(CookieToken))
+	}
+	user, ok := t[token]
+	return user, ok
+}
+
+// Middleware rejects requests without a valid token and puts the user into the request context.
+func (t Tokens) Middleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		user, ok := t.Authenticate(c)
+		if !ok {
+			c.AbortWithStatusJSON(consts.StatusUnauthorized, map[string]string{
+				"status": "error",
+				"error":  "unauthorized",
+			})
+			return
+		}
+		c.Next(WithUser(ctx, user))
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTokens(t *testing.T) {
+	tokens, err := ParseTokens(" alice:token-a, bob:token-b ,")
+	assert.NoError(t, err)
+	assert.Equal(t, Tokens{"token-a": "alice", "token-b": "bob"}, tokens)
+	assert.True(t, tokens.Enabled())
+
+	tokens, err = ParseTokens("")
+	assert.NoError(t, err)
+	assert.False(t, tokens.Enabled())
+
+	_, err = ParseTokens("alice")
+	assert.Error(t, err)
+	_, err = ParseTokens("alice:same,bob:same")
+	assert.Error(t, err)
+}
+
+func TestUserFrom(t *testing.T) {
+	assert.Equal(t, DefaultUser, UserFrom(context.Background()))
+	assert.Equal(t, "alice", UserFrom(WithUser(context.Background(), "alice")))
+}
+
+func TestAuthenticate(t *testing.T) {
+	tokens := Tokens{"token-a": "alice"}
+	request := func(uri string, setup func(c *app.RequestContext)) *app.RequestContext {
+		c := app.NewContext(0)
+		c.Request.SetRequestURI(uri)
+		if setup != nil {
+			setup(c)
+		}
+		return c
+	}
+
+	user, ok := tokens.Authenticate(request("/api", func(c *app.RequestContext) {
+		c.Request.Header.Set("Authorization", "Bearer token-a")
+	}))
+	assert.True(t, ok)
+	assert.Equal(t, "alice", user)
+
+	user, ok = tokens.Authenticate(request("/api", func(c *app.RequestContext) {
+		c.Request.Header.SetCookie(CookieToken, "token-a")
+	}))
+	assert.True(t, ok)
+	assert.Equal(t, "alice", user)
+
+	// 查询参数中的令牌不被接受
+	_, ok = tokens.Authenticate(request("/api?token=token-a", nil))
+	assert.False(t, ok)
+	_, ok = tokens.Authenticate(request("/api", func(c *app.RequestContext) {
+		c.Request.Header.Set("Authorization", "Bearer token-b")
+	}))
+	assert.False(t, ok)
+
+	user, ok = Tokens{}.Authenticate(request("/api", nil))
+	assert.True(t, ok)
+	assert.Equal(t, DefaultUser, user)
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chatmodel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudwego/eino-ext/components/model/ark"
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+type Provider string
+
+const (
+	ProviderOpenAI Provider = "openai"
+	ProviderArk    Provider = "ark"
+	// ProviderLocal is an OpenAI compatible endpoint such as ollama or vllm.
+	ProviderLocal Provider = "local"
+
+	// DefaultProfile uses the OPENAI_BASE_URL / OPENAI_API_KEY / MODEL_NAME envs.
+	DefaultProfile = "default"
+	// DefaultLocalBaseURL is the OpenAI compatible endpoint of ollama.
+	DefaultLocalBaseURL = "http://localhost:11434/v1"
+)
+
+// Profile is a named chat model configuration.
+type Profile struct {
+	Name     string
+	Provider Provider
+	Model    string
+	APIKey   string
+	BaseURL  string
+}
+
+// providerEnvs are the envs used when a profile does not set its own,
+// in the order of model, api key and base url.
+var providerEnvs = map[Provider][3]string{
+	ProviderOpenAI: {"MODEL_NAME", "OPENAI_API_KEY", "OPENAI_BASE_URL"},
+	ProviderArk:    {"ARK_MODEL", "ARK_API_KEY", "ARK_BASE_URL"},
+	ProviderLocal:  {"LOCAL_MODEL_NAME", "LOCAL_MODEL_API_KEY", "LOCAL_MODEL_BASE_URL"},
+}
+
+// ProfileFromEnv reads the profile of name from envs with prefix MODEL_PROFILE_<NAME>_:
+//   - PROVIDER: openai, ark or local, default to the profile name for the
+//     profiles named after a provider, and openai for the default profile
+//   - MODEL / API_KEY / BASE_URL: default to the provider envs, i.e.
+//     MODEL_NAME, OPENAI_API_KEY, OPENAI_BASE_URL for openai,
+//     ARK_MODEL, ARK_API_KEY, ARK_BASE_URL for ark,
+//     LOCAL_MODEL_NAME, LOCAL_MODEL_API_KEY, LOCAL_MODEL_BASE_URL for local
+func ProfileFromEnv(name string) (*Profile, error) {
+	prefix := "MODEL_PROFILE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+	provider := Provider(os.Getenv(prefix + "PROVIDER"))
+	if provider == "" {
+		switch Provider(name) {
+		case ProviderOpenAI, ProviderArk, ProviderLocal:
+			provider = Provider(name)
+		default:
+			if name != DefaultProfile {
+				return nil, fmt.Errorf("env %sPROVIDER of model profile %s is not set", prefix, name)
+			}
+			provider = ProviderOpenAI
+		}
+	}
+	envs, ok := providerEnvs[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown model provider: %s", provider)
+	}
+
+	profile := &Profile{
+		Name:     name,
+		Provider: provider,
+		Model:    envOr(prefix+"MODEL", envs[0]),
+		APIKey:   envOr(prefix+"API_KEY", envs[1]),
+		BaseURL:  envOr(prefix+"BASE_URL", envs[2]),
+	}
+	return profile, nil
+}
+
+// NewChatModel creates the chat model of a profile.
+func NewChatModel(ctx context.Context, profile *Profile) (model.ToolCallingChatModel, error) {
+	if profile.Model == "" {
+		return nil, fmt.Errorf("model of profile %s is not set", profile.Name)
+	}
+
+	switch profile.Provider {
+	case ProviderOpenAI:
+		return openai.NewChatModel(ctx, &openai.ChatModelConfig{
+			Model:   profile.Model,
+			APIKey:  profile.APIKey,
+			BaseURL: profile.BaseURL,
+		})
+	case ProviderArk:
+		return ark.NewChatModel(ctx, &ark.ChatModelConfig{
+			Model:   profile.Model,
+			APIKey:  profile.APIKey,
+			BaseURL: profile.BaseURL,
+		})
+	case ProviderLocal:
+		baseURL, apiKey := profile.BaseURL, profile.APIKey
+		if baseURL == "" {
+			baseURL = DefaultLocalBaseURL
+		}
+		if apiKey == "" {
+			// local endpoints usually ignore the key, but the client requires one
+			apiKey = "EMPTY"
+		}
+		return openai.NewChatModel(ctx, &openai.ChatModelConfig{
+			Model:   profile.Model,
+			APIKey:  apiKey,
+			BaseURL: baseURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown model provider: %s", profile.Provider)
+	}
+}
+
+type options struct {
+	model string
+}
+
+type Option func(*options)
+
+// WithModel overrides the model of the primary profile.
+func WithModel(model string) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
+
+// NewFromEnv creates the chat model of the profiles listed in MODEL_PROFILES
+// (comma separated, default "default"). The first one is the primary, the
+// others are tried in order when it fails with a rate limit or 5xx error.
+func NewFromEnv(ctx context.Context, opts ...Option) (model.ToolCallingChatModel, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	names := []string{DefaultProfile}
+	if v := os.Getenv("MODEL_PROFILES"); v != "" {
+		names = names[:0]
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	models := make([]*NamedModel, 0, len(names))
+	for i, name := range names {
+		profile, err := ProfileFromEnv(name)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && o.model != "" {
+			profile.Model = o.model
+		}
+		cm, err := NewChatModel(ctx, profile)
+		if err != nil {
+			return nil, fmt.Errorf("create chat model of profile %s failed: %w", name, err)
+		}
+		models = append(models, &NamedModel{Name: name, Model: cm})
+	}
+
+	if len(models) == 1 {
+		return models[0].Model, nil
+	}
+	return NewFallback(models...)
+}
+
+func envOr(key, fallbackKey string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return os.Getenv(fallbackKey)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chatmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// NamedModel is a chat model in the fallback chain, the name is used in logs.
+type NamedModel struct {
+	Name  string
+	Model model.ToolCallingChatModel
+}
+
+// FallbackChatModel calls the models in order, the next one is tried only when
+// the previous one fails with a retryable error, see IsRetryable.
+// For Stream, the error must happen before the first chunk is received.
+type FallbackChatModel struct {
+	models []*NamedModel
+}
+
+func NewFallback(models ...*NamedModel) (*FallbackChatModel, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("at least one model is required")
+	}
+	return &FallbackChatModel{models: models}, nil
+}
+
+func (f *FallbackChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	var err error
+	for i, m := range f.models {
+		var out *schema.Message
+		out, err = m.Model.Generate(ctx, input, opts...)
+		if err == nil {
+			return out, nil
+		}
+		if !f.fallback(i, err) {
+			break
+		}
+	}
+	return nil, err
+}
+
+func (f *FallbackChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	var err error
+	for i, m := range f.models {
+		var sr *schema.StreamReader[*schema.Message]
+		sr, err = m.Model.Stream(ctx, input, opts...)
+		if err == nil {
+			// rate limit errors may come with the first chunk
+			var first *schema.Message
+			first, err = sr.Recv()
+			if err == nil {
+				return prepend(first, sr), nil
+			}
+			sr.Close()
+			if errors.Is(err, io.EOF) {
+				return schema.StreamReaderFromArray([]*schema.Message{}), nil
+			}
+		}
+		if !f.fallback(i, err) {
+			break
+		}
+	}
+	return nil, err
+}
+
+func (f *FallbackChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	models := make([]*NamedModel, 0, len(f.models))
+	for _, m := range f.models {
+		cm, err := m.Model.WithTools(tools)
+		if err != nil {
+			return nil, fmt.Errorf("bind tools to model %s failed: %w", m.Name, err)
+		}
+		models = append(models, &NamedModel{Name: m.Name, Model: cm})
+	}
+	return &FallbackChatModel{models: models}, nil
+}
+
+func (f *FallbackChatModel) GetType() string {
+	return "Fallback"
+}
+
+// IsCallbacksEnabled returns true since the callbacks are reported by the
+// model actually called.
+func (f *FallbackChatModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+// fallback reports whether the model after index i should be tried.
+func (f *FallbackChatModel) fallback(i int, err error) bool {
+	if i == len(f.models)-1 || !IsRetryable(err) {
+		return false
+	}
+	log.Printf("[chatmodel] model %s failed, fallback to %s: %v\n", f.models[i].Name, f.models[i+1].Name, err)
+	return true
+}
+
+// statusCodePattern matches the status code in the errors of the openai and
+// ark clients, e.g. "error, status code: 429, ..." and "Error code: 503 - ...".
+var statusCodePattern = regexp.MustCompile(`(?i)code: (\d{3})\b`)
+
+// IsRetryable reports whether err is a rate limit or 5xx error of the provider.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	msg := err.Error()
+	if m := statusCodePattern.FindStringSubmatch(msg); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code == 429 || code >= 500
+	}
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+}
+
+// prepend returns a stream of first followed by the chunks of sr.
+func prepend(first *schema.Message, sr *schema.StreamReader[*schema.Message]) *schema.StreamReader[*schema.Message] {
+	r, w := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer w.Close()
+		defer sr.Close()
+
+		if closed := w.Send(first, nil); closed {
+			return
+		}
+		for {
+			chunk, err := sr.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if closed := w.Send(chunk, err); closed || err != nil {
+				return
+			}
+		}
+	}()
+	return r
+}
@@ -0,0 +1,101 @@
+package chatmodel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubModel 返回固定回复或错误的模型，用于测试
+type stubModel struct {
+	reply string
+	err   error
+	calls int
+}
+
+func (m *stubModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return schema.AssistantMessage(m.reply, nil), nil
+}
+
+func (m *stubModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.calls++
+	if m.err != nil {
+		// 错误随第一个数据块返回
+		r, w := schema.Pipe[*schema.Message](1)
+		w.Send(nil, m.err)
+		w.Close()
+		return r, nil
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{
+		schema.AssistantMessage(m.reply[:1], nil),
+		schema.AssistantMessage(m.reply[1:], nil),
+	}), nil
+}
+
+func (m *stubModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func TestFallbackChatModel(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		primaryErr error
+		want       string
+		wantErr    bool
+		wantCalls  int
+	}{
+		{name: "主模型成功", want: "primary", wantCalls: 0},
+		{name: "限流时切换到备用模型", primaryErr: errors.New("error, status code: 429, status: Too Many Requests"), want: "secondary", wantCalls: 1},
+		{name: "5xx 时切换到备用模型", primaryErr: errors.New("Error code: 503 - {}"), want: "secondary", wantCalls: 1},
+		{name: "4xx 不切换", primaryErr: errors.New("error, status code: 401, status: Unauthorized"), wantErr: true, wantCalls: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &stubModel{reply: "primary", err: tt.primaryErr}
+			secondary := &stubModel{reply: "secondary"}
+			cm, err := NewFallback(&NamedModel{Name: "a", Model: primary}, &NamedModel{Name: "b", Model: secondary})
+			assert.NoError(t, err)
+
+			out, err := cm.Generate(ctx, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, out.Content)
+			}
+			assert.Equal(t, tt.wantCalls, secondary.calls)
+		})
+	}
+}
+
+func TestFallbackChatModelStream(t *testing.T) {
+	ctx := context.Background()
+	primary := &stubModel{err: errors.New("rate limit exceeded")}
+	secondary := &stubModel{reply: "secondary"}
+	cm, err := NewFallback(&NamedModel{Name: "a", Model: primary}, &NamedModel{Name: "b", Model: secondary})
+	assert.NoError(t, err)
+
+	sr, err := cm.Stream(ctx, nil)
+	assert.NoError(t, err)
+	var chunks []*schema.Message
+	for {
+		chunk, err := sr.Recv()
+		if err != nil {
+			break
+		}
+		chunks = append(chunks, chunk)
+	}
+	msg, err := schema.ConcatMessages(chunks)
+	assert.NoError(t, err)
+	assert.Equal(t, "secondary", msg.Content)
+}
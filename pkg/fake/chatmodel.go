@@ -0,0 +1,180 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ChatModelConfig scripts the replies of ChatModel.
+type ChatModelConfig struct {
+	// Replies are returned in order, one per call, the last one is repeated
+	// when they are used up.
+	Replies []*schema.Message
+	// Respond computes the reply from the input, it takes precedence over Replies.
+	Respond func(ctx context.Context, input []*schema.Message) (*schema.Message, error)
+	// ChunkSize is the number of runes per chunk on Stream, default 4.
+	ChunkSize int
+}
+
+// ChatModel is a deterministic model.ToolCallingChatModel for offline tests,
+// it replies with the scripted messages and records the inputs.
+type ChatModel struct {
+	config *ChatModelConfig
+	tools  []*schema.ToolInfo
+	// state is shared by the models returned by WithTools
+	state *chatModelState
+}
+
+type chatModelState struct {
+	mu     sync.Mutex
+	inputs [][]*schema.Message
+}
+
+func NewChatModel(config *ChatModelConfig) *ChatModel {
+	if config == nil {
+		config = &ChatModelConfig{}
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = 4
+	}
+	return &ChatModel{config: config, state: &chatModelState{}}
+}
+
+// Text returns an assistant message with content.
+func Text(content string) *schema.Message {
+	return schema.AssistantMessage(content, nil)
+}
+
+// ToolCall returns an assistant message calling the tool name with arguments in json.
+func ToolCall(id, name, arguments string) *schema.Message {
+	return schema.AssistantMessage("", []schema.ToolCall{{
+		ID:   id,
+		Type: "function",
+		Function: schema.FunctionCall{
+			Name:      name,
+			Arguments: arguments,
+		},
+	}})
+}
+
+func (m *ChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.reply(ctx, input)
+}
+
+// Stream splits the content of the reply into chunks of ChunkSize runes, the
+// tool calls are sent with the first chunk and the usage with the last one.
+func (m *ChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.reply(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	content := []rune(msg.Content)
+	chunks := make([]*schema.Message, 0, len(content)/m.config.ChunkSize+1)
+	for start := 0; start < len(content) || start == 0; start += m.config.ChunkSize {
+		end := min(start+m.config.ChunkSize, len(content))
+		chunks = append(chunks, &schema.Message{
+			Role:    schema.Assistant,
+			Content: string(content[start:end]),
+		})
+	}
+	chunks[0].ToolCalls = msg.ToolCalls
+	chunks[len(chunks)-1].ResponseMeta = msg.ResponseMeta
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+func (m *ChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return &ChatModel{config: m.config, tools: tools, state: m.state}, nil
+}
+
+// Tools returns the tools bound by WithTools.
+func (m *ChatModel) Tools() []*schema.ToolInfo {
+	return m.tools
+}
+
+// Calls returns the number of calls of the model and the models returned by WithTools.
+func (m *ChatModel) Calls() int {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	return len(m.state.inputs)
+}
+
+// Inputs returns the input messages of every call.
+func (m *ChatModel) Inputs() [][]*schema.Message {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	return append([][]*schema.Message{}, m.state.inputs...)
+}
+
+func (m *ChatModel) GetType() string {
+	return "Fake"
+}
+
+func (m *ChatModel) reply(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+	m.state.mu.Lock()
+	call := len(m.state.inputs)
+	m.state.inputs = append(m.state.inputs, input)
+	m.state.mu.Unlock()
+
+	var msg *schema.Message
+	switch {
+	case m.config.Respond != nil:
+		var err error
+		msg, err = m.config.Respond(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+	case len(m.config.Replies) > 0:
+		msg = m.config.Replies[min(call, len(m.config.Replies)-1)]
+	default:
+		return nil, fmt.Errorf("no reply scripted for call %d", call)
+	}
+
+	// copy so that callers modifying the reply do not change the script
+	out := *msg
+	out.ResponseMeta = &schema.ResponseMeta{
+		FinishReason: "stop",
+		Usage:        usage(input, &out),
+	}
+	if len(out.ToolCalls) > 0 {
+		out.ResponseMeta.FinishReason = "tool_calls"
+	}
+	return &out, nil
+}
+
+// usage counts runes as tokens.
+func usage(input []*schema.Message, output *schema.Message) *schema.TokenUsage {
+	prompt := 0
+	for _, msg := range input {
+		prompt += len([]rune(msg.Content))
+	}
+	completion := len([]rune(output.Content))
+	for _, tc := range output.ToolCalls {
+		completion += len([]rune(tc.Function.Arguments))
+	}
+	return &schema.TokenUsage{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      prompt + completion,
+	}
+}
@@ -0,0 +1,38 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fake
+
+import (
+	"Eino-example/pkg/vectorstore"
+)
+
+// DefaultDimensions of the vectors of Embedder.
+const DefaultDimensions = vectorstore.DefaultHashingDimensions
+
+// Embedder is a deterministic embedding.Embedder for offline tests, it is the
+// feature hashing vectorstore.HashingEmbedder, so texts sharing terms are similar.
+type Embedder struct {
+	*vectorstore.HashingEmbedder
+}
+
+func NewEmbedder(dimensions int) *Embedder {
+	return &Embedder{HashingEmbedder: vectorstore.NewHashingEmbedder(dimensions)}
+}
+
+func (e *Embedder) GetType() string {
+	return "Fake"
+}
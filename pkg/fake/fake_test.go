@@ -0,0 +1,104 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatModel(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("按顺序回复并重复最后一条", func(t *testing.T) {
+		m := NewChatModel(&ChatModelConfig{Replies: []*schema.Message{Text("a"), Text("b")}})
+		var got []string
+		for i := 0; i < 3; i++ {
+			msg, err := m.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+			assert.NoError(t, err)
+			got = append(got, msg.Content)
+		}
+		assert.Equal(t, []string{"a", "b", "b"}, got)
+		assert.Equal(t, 3, m.Calls())
+	})
+
+	t.Run("WithTools 共享调用记录", func(t *testing.T) {
+		m := NewChatModel(&ChatModelConfig{Replies: []*schema.Message{Text("ok")}})
+		tm, err := m.WithTools([]*schema.ToolInfo{{Name: "echo"}})
+		assert.NoError(t, err)
+		_, err = tm.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, m.Calls())
+		assert.Equal(t, "hi", m.Inputs()[0][0].Content)
+		assert.Len(t, tm.(*ChatModel).Tools(), 1)
+	})
+
+	t.Run("Respond 的错误透传", func(t *testing.T) {
+		m := NewChatModel(&ChatModelConfig{Respond: func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+			return nil, errors.New("boom")
+		}})
+		_, err := m.Generate(ctx, nil)
+		assert.EqualError(t, err, "boom")
+	})
+
+	t.Run("未配置回复时报错", func(t *testing.T) {
+		_, err := NewChatModel(nil).Generate(ctx, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Stream 分块并携带工具调用与用量", func(t *testing.T) {
+		reply := ToolCall("call_1", "echo", `{}`)
+		reply.Content = "你好，世界！"
+		m := NewChatModel(&ChatModelConfig{Replies: []*schema.Message{reply}, ChunkSize: 2})
+		sr, err := m.Stream(ctx, []*schema.Message{schema.UserMessage("hi")})
+		assert.NoError(t, err)
+
+		var chunks []*schema.Message
+		for {
+			chunk, err := sr.Recv()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			chunks = append(chunks, chunk)
+		}
+		assert.Len(t, chunks, 3)
+		assert.Equal(t, "你好", chunks[0].Content)
+		assert.Len(t, chunks[0].ToolCalls, 1)
+
+		msg, err := schema.ConcatMessages(chunks)
+		assert.NoError(t, err)
+		assert.Equal(t, "你好，世界！", msg.Content)
+		assert.Equal(t, "tool_calls", msg.ResponseMeta.FinishReason)
+		assert.Equal(t, 2, msg.ResponseMeta.Usage.PromptTokens)
+	})
+}
+
+func TestEmbedder(t *testing.T) {
+	ctx := context.Background()
+	e := NewEmbedder(0)
+
+	vectors, err := e.EmbedStrings(ctx, []string{"graph branch", "graph branch", "graph loop", "chain lambda"})
+	assert.NoError(t, err)
+	assert.Len(t, vectors[0], DefaultDimensions)
+	// 相同文本得到相同向量
+	assert.Equal(t, vectors[0], vectors[1])
+
+	dot := func(a, b []float64) float64 {
+		var sum float64
+		for i := range a {
+			sum += a[i] * b[i]
+		}
+		return sum
+	}
+	assert.InDelta(t, 1, dot(vectors[0], vectors[0]), 1e-9)
+	// 共享词项的文本更相似
+	assert.Greater(t, dot(vectors[0], vectors[2]), dot(vectors[0], vectors[3]))
+
+	empty, err := e.EmbedStrings(ctx, []string{""})
+	assert.NoError(t, err)
+	assert.Len(t, empty[0], DefaultDimensions)
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotUserMessage  = errors.New("not a user message")
+)
+
+// Node is a message of the conversation tree.
+type Node struct {
+	ID string `json:"id"`
+	// ParentID is the message before this one, "" for the first message.
+	ParentID string          `json:"parent_id,omitempty"`
+	Message  *schema.Message `json:"message"`
+}
+
+// legacyID is the ID of the i-th message stored before the messages had IDs,
+// each one is the child of the previous one.
+func legacyID(i int) string {
+	return "m" + strconv.Itoa(i)
+}
+
+// BranchMessage is a message of the active branch and its place in the tree.
+type BranchMessage struct {
+	ID       string `json:"id"`
+	ParentID string `json:"parent_id,omitempty"`
+	// Siblings are the IDs of the branches at this message in creation order,
+	// set when there is more than one, Checkout one to switch to it. Branch is
+	// the index of the active one.
+	Siblings []string `json:"siblings,omitempty"`
+	Branch   int      `json:"branch"`
+	*schema.Message
+}
+
+// load sets the tree of the conversation, head defaults to the last node.
+func (c *Conversation) load(nodes []*Node, head string) {
+	c.nodes = nodes
+	c.byID = make(map[string]*Node, len(nodes))
+	for _, n := range nodes {
+		c.byID[n.ID] = n
+	}
+	if c.node(head) == nil {
+		head = ""
+		if len(nodes) > 0 {
+			head = nodes[len(nodes)-1].ID
+		}
+	}
+	c.Head = head
+	c.path = c.pathTo(head)
+	c.Messages = messagesOf(c.path)
+}
+
+// Nodes returns all the messages of the conversation in the order appended.
+func (c *Conversation) Nodes() []*Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*Node(nil), c.nodes...)
+}
+
+// Path returns the nodes of the active branch.
+func (c *Conversation) Path() []*Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*Node(nil), c.path...)
+}
+
+// Edit moves Head to the parent of the user message id, so that the edited
+// message is appended as its sibling, on a new branch.
+func (c *Conversation) Edit(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.node(id)
+	if n == nil {
+		return ErrMessageNotFound
+	}
+	if n.Message.Role != schema.User {
+		return ErrNotUserMessage
+	}
+	c.setHead(n.ParentID)
+	return nil
+}
+
+// Regenerate moves Head back to the last user message of the active branch and
+// returns it, so that a new answer is appended beside the previous one.
+func (c *Conversation) Regenerate() (*schema.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := len(c.path) - 1; i >= 0; i-- {
+		if n := c.path[i]; n.Message.Role == schema.User {
+			c.setHead(n.ID)
+			return n.Message, nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// Checkout activates the branch through the message id, down to its most
+// recent leaf.
+func (c *Conversation) Checkout(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.node(id)
+	if n == nil {
+		return ErrMessageNotFound
+	}
+	for {
+		children := c.children(n.ID)
+		if len(children) == 0 {
+			break
+		}
+		n = children[len(children)-1]
+	}
+	c.setHead(n.ID)
+	return nil
+}
+
+// View returns the active branch with the siblings of each message. With
+// collapse the tool steps are left out as in CollapseToolSteps, an answer
+// then takes the siblings of the first step after the user message.
+func (c *Conversation) View(collapse bool) []*BranchMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	view := make([]*BranchMessage, 0, len(c.path))
+	for i, n := range c.path {
+		msg := n.Message
+		branch := n
+		if collapse {
+			if msg = collapseToolSteps(msg); msg == nil {
+				continue
+			}
+			for j := i - 1; j >= 0 && collapseToolSteps(c.path[j].Message) == nil; j-- {
+				branch = c.path[j]
+			}
+		}
+
+		m := &BranchMessage{ID: n.ID, ParentID: n.ParentID, Message: msg}
+		if siblings := c.children(branch.ParentID); len(siblings) > 1 {
+			for k, s := range siblings {
+				m.Siblings = append(m.Siblings, s.ID)
+				if s == branch {
+					m.Branch = k
+				}
+			}
+		}
+		view = append(view, m)
+	}
+	return view
+}
+
+// setHead activates the branch ending at id, the summary is dropped when it
+// folds messages which are not on the branch.
+func (c *Conversation) setHead(id string) {
+	path := c.pathTo(id)
+	common := 0
+	for common < len(path) && common < len(c.path) && path[common] == c.path[common] {
+		common++
+	}
+	c.Head, c.path, c.Messages = id, path, messagesOf(path)
+
+	if c.Summarized > common {
+		c.Summary, c.Summarized = "", 0
+		if err := c.store.saveSummary(c); err != nil {
+			log.Printf("failed to save summary of conversation %s: %v", c.ID, err)
+		}
+	}
+	if err := c.store.saveHead(c); err != nil {
+		log.Printf("failed to save head of conversation %s: %v", c.ID, err)
+	}
+}
+
+func (c *Conversation) node(id string) *Node {
+	return c.byID[id]
+}
+
+func (c *Conversation) children(id string) []*Node {
+	var children []*Node
+	for _, n := range c.nodes {
+		if n.ParentID == id {
+			children = append(children, n)
+		}
+	}
+	return children
+}
+
+// pathTo returns the nodes from the first message to id.
+func (c *Conversation) pathTo(id string) []*Node {
+	var path []*Node
+	// a broken file may have a loop, a path never has more than all the nodes
+	for n := c.node(id); n != nil && len(path) < len(c.nodes); n = c.node(n.ParentID) {
+		path = append(path, n)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func messagesOf(nodes []*Node) []*schema.Message {
+	msgs := make([]*schema.Message, 0, len(nodes))
+	for _, n := range nodes {
+		msgs = append(msgs, n.Message)
+	}
+	return msgs
+}
@@ -0,0 +1,124 @@
+package mem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationBranches(t *testing.T) {
+	backends := []struct {
+		name string
+		// open opens the memory of dir, a new one reads everything from the files
+		open func(t *testing.T, dir string) Memory
+	}{
+		{"jsonl", func(t *testing.T, dir string) Memory { return NewSimpleMemory(Config{Dir: dir}) }},
+		{"sqlite", func(t *testing.T, dir string) Memory { return newSQLiteMemory(t, Config{Dir: dir}) }},
+	}
+
+	contents := func(msgs []*schema.Message) []string {
+		var s []string
+		for _, msg := range msgs {
+			s = append(s, msg.Content)
+		}
+		return s
+	}
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := getConversation(t, b.open(t, dir), "c", true)
+			for _, msg := range []*schema.Message{
+				schema.UserMessage("u1"),
+				schema.AssistantMessage("a1", nil),
+				schema.UserMessage("u2"),
+				schema.AssistantMessage("a2", nil),
+			} {
+				c.Append(msg)
+			}
+			u2 := c.Path()[2].ID
+
+			assert.ErrorIs(t, c.Edit("missing"), ErrMessageNotFound)
+			assert.ErrorIs(t, c.Edit(c.Path()[1].ID), ErrNotUserMessage)
+
+			// 编辑消息后在旁边开出新分支
+			assert.NoError(t, c.Edit(u2))
+			assert.Equal(t, []string{"u1", "a1"}, contents(c.GetFullMessages()))
+			c.Append(schema.UserMessage("u2'"))
+			c.Append(schema.AssistantMessage("a2'", nil))
+			edited := c.Path()[2].ID
+
+			reloaded := getConversation(t, b.open(t, dir), "c", false)
+			assert.Equal(t, []string{"u1", "a1", "u2'", "a2'"}, contents(reloaded.GetFullMessages()))
+			view := reloaded.View(true)
+			assert.Equal(t, []string{u2, edited}, view[2].Siblings)
+			assert.Equal(t, 1, view[2].Branch)
+			assert.Empty(t, view[3].Siblings)
+
+			// 切换回原分支
+			assert.NoError(t, reloaded.Checkout(u2))
+			assert.Equal(t, []string{"u1", "a1", "u2", "a2"}, contents(reloaded.GetFullMessages()))
+			reloaded = getConversation(t, b.open(t, dir), "c", false)
+			assert.Equal(t, []string{"u1", "a1", "u2", "a2"}, contents(reloaded.GetFullMessages()))
+
+			// 重新生成的回答与原回答互为兄弟，折叠后挂在工具调用之前
+			msg, err := reloaded.Regenerate()
+			assert.NoError(t, err)
+			assert.Equal(t, "u2", msg.Content)
+			assert.Equal(t, []string{"u1", "a1", "u2"}, contents(reloaded.GetFullMessages()))
+			for _, msg := range toolTurn()[1:] {
+				reloaded.Append(msg)
+			}
+			view = getConversation(t, b.open(t, dir), "c", false).View(true)
+			assert.Len(t, view, 4)
+			assert.Equal(t, "a1", view[3].Content)
+			assert.Len(t, view[3].Siblings, 2)
+			assert.Equal(t, 1, view[3].Branch)
+			assert.Len(t, getConversation(t, b.open(t, dir), "c", false).View(false), 7)
+		})
+	}
+}
+
+func TestConversationBranchSummary(t *testing.T) {
+	m := NewSimpleMemory(Config{Dir: t.TempDir()})
+	c := getConversation(t, m, "c", true)
+	for _, msg := range toolTurn() {
+		c.Append(msg)
+	}
+	c.Append(schema.UserMessage("q2"))
+	c.Summary, c.Summarized = "摘要", 5
+
+	// 摘要覆盖的消息仍在分支上时保留
+	_, err := c.Regenerate()
+	assert.NoError(t, err)
+	assert.Equal(t, "摘要", c.Summary)
+
+	// 编辑被摘要的消息后丢弃摘要
+	assert.NoError(t, c.Edit(c.Path()[0].ID))
+	assert.Empty(t, c.Summary)
+	assert.Equal(t, 0, c.Summarized)
+}
+
+func TestReadLegacyJSONL(t *testing.T) {
+	dir := t.TempDir()
+	lines := `{"role":"user","content":"u1"}` + "\n" + `{"role":"assistant","content":"a1"}` + "\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "old.jsonl"), []byte(lines), 0644))
+
+	c := getConversation(t, NewSimpleMemory(Config{Dir: dir}), "old", false)
+	path := c.Path()
+	assert.Equal(t, "m0", path[0].ID)
+	assert.Equal(t, "m0", path[1].ParentID)
+	assert.Equal(t, "m1", c.Head)
+
+	// 旧消息可以作为新消息的父消息
+	c.Append(schema.UserMessage("u2"))
+	reloaded := getConversation(t, NewSimpleMemory(Config{Dir: dir}), "old", false)
+	assert.Len(t, reloaded.GetFullMessages(), 3)
+	assert.Equal(t, "m1", reloaded.Path()[2].ParentID)
+
+	imported := getConversation(t, newSQLiteMemory(t, Config{Dir: dir}), "old", false)
+	assert.Equal(t, reloaded.Path(), imported.Path())
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+)
+
+// conversationStore persists the changes of a Conversation.
+type conversationStore interface {
+	// appendMessage saves n, which is the new head of c.
+	appendMessage(c *Conversation, n *Node) error
+	saveHead(c *Conversation) error
+	saveSummary(c *Conversation) error
+}
+
+// Conversation is a tree of messages, editing a message or regenerating an
+// answer starts a new branch beside the previous one. Messages is the active
+// branch, the one sent to the model.
+type Conversation struct {
+	mu sync.Mutex
+
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	// Messages is the active branch, from the first message to Head.
+	Messages []*schema.Message `json:"messages"`
+	// Head is the ID of the last message of the active branch.
+	Head string `json:"head,omitempty"`
+	// Summary folds the first Summarized messages when a Summarizer is set.
+	Summary    string `json:"summary,omitempty"`
+	Summarized int    `json:"summarized,omitempty"`
+
+	// nodes are all the messages in the order appended
+	nodes []*Node
+	byID  map[string]*Node
+	// path is the active branch, the nodes of Messages
+	path []*Node
+
+	store conversationStore
+	cfg   Config
+}
+
+// Append adds msg after Head and makes it the new Head.
+func (c *Conversation) Append(msg *schema.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.appendNode(&Node{ID: uuid.NewString(), ParentID: c.Head, Message: msg})
+}
+
+// appendNode adds n to the tree as the new Head and saves it.
+func (c *Conversation) appendNode(n *Node) {
+	if c.byID == nil {
+		c.byID = make(map[string]*Node)
+	}
+	c.nodes = append(c.nodes, n)
+	c.byID[n.ID] = n
+	if n.ParentID == c.Head {
+		c.path = append(c.path, n)
+		c.Messages = append(c.Messages, n.Message)
+	} else {
+		c.path = c.pathTo(n.ID)
+		c.Messages = messagesOf(c.path)
+	}
+	c.Head = n.ID
+
+	if err := c.store.appendMessage(c, n); err != nil {
+		log.Printf("failed to save message of conversation %s: %v", c.ID, err)
+	}
+}
+
+func (c *Conversation) GetFullMessages() []*schema.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.Messages
+}
+
+// GetMessages returns the recent messages within the token budget, preceded by
+// the summary of the earlier ones. Without MaxTokens it returns the last
+// MaxWindowSize messages. A tool call is never separated from its results.
+// The conversation is not locked while the Summarizer runs, the summary is
+// dropped if the messages it folds changed meanwhile.
+func (c *Conversation) GetMessages(ctx context.Context) []*schema.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg.MaxTokens <= 0 {
+		return c.Messages[turnStart(c.Messages, len(c.Messages)-c.cfg.MaxWindowSize):]
+	}
+
+	start := c.budgetStart()
+	// the new summary takes budget too, fold again until both fit
+	for c.cfg.Summarizer != nil && start > c.Summarized {
+		prevSummary, prevSummarized := c.Summary, c.Summarized
+		folded := append([]*schema.Message{}, c.Messages[prevSummarized:start]...)
+		last := c.path[start-1]
+
+		c.mu.Unlock()
+		summary, err := c.cfg.Summarizer.Summarize(ctx, prevSummary, folded)
+		c.mu.Lock()
+
+		// the branch is a path from the root, it still starts with the folded
+		// messages if it still goes through the last one
+		if c.Summary != prevSummary || c.Summarized != prevSummarized || len(c.path) < start || c.path[start-1] != last {
+			log.Printf("conversation %s changed while summarizing, drop the summary", c.ID)
+			start = c.budgetStart()
+			break
+		}
+		if err != nil {
+			// keep the previous summary, the messages in between are dropped this time
+			log.Printf("failed to summarize conversation %s: %v", c.ID, err)
+			break
+		}
+		c.Summary, c.Summarized = summary, start
+		if err := c.store.saveSummary(c); err != nil {
+			log.Printf("failed to save summary of conversation %s: %v", c.ID, err)
+		}
+		start = c.budgetStart()
+	}
+
+	if c.Summary == "" {
+		return c.Messages[start:]
+	}
+	return append([]*schema.Message{summaryMessage(c.Summary)}, c.Messages[start:]...)
+}
+
+// budgetStart returns the index of the oldest message kept within the budget
+// left by the summary, the latest message is always kept along with its tool results.
+func (c *Conversation) budgetStart() int {
+	budget := c.cfg.MaxTokens
+	if c.Summary != "" {
+		budget -= c.cfg.CountTokens(summaryMessage(c.Summary))
+	}
+
+	start := len(c.Messages)
+	used := 0
+	for i := len(c.Messages) - 1; i >= c.Summarized; i-- {
+		used += c.cfg.CountTokens(c.Messages[i])
+		if c.Messages[i].Role == schema.Tool {
+			continue
+		}
+		if used > budget && start < len(c.Messages) {
+			break
+		}
+		start = i
+	}
+	return start
+}
+
+// turnStart moves i back so that the history does not start with tool results.
+func turnStart(msgs []*schema.Message, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	for i > 0 && msgs[i].Role == schema.Tool {
+		i--
+	}
+	return i
+}
+
+// CollapseToolSteps returns msgs without the tool steps of the agent: the tool
+// results are dropped, and so are the tool calls of the assistant messages,
+// which are dropped too when nothing else is left.
+func CollapseToolSteps(msgs []*schema.Message) []*schema.Message {
+	collapsed := make([]*schema.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg = collapseToolSteps(msg); msg != nil {
+			collapsed = append(collapsed, msg)
+		}
+	}
+	return collapsed
+}
+
+// collapseToolSteps returns msg without its tool calls, or nil if it is dropped.
+func collapseToolSteps(msg *schema.Message) *schema.Message {
+	if msg.Role == schema.Tool {
+		return nil
+	}
+	if len(msg.ToolCalls) > 0 {
+		if msg.Content == "" {
+			return nil
+		}
+		m := *msg
+		m.ToolCalls = nil
+		return &m
+	}
+	return msg
+}
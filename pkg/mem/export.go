@@ -0,0 +1,262 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+// Citation is a source cited by an answer as [Index].
+type Citation struct {
+	Index    int      `json:"index"`
+	ID       string   `json:"id"`
+	URI      string   `json:"uri,omitempty"`
+	Headings []string `json:"headings,omitempty"`
+}
+
+// extraCitations is the key of the citations in the Extra of an answer.
+const extraCitations = "citations"
+
+// SetCitations keeps the sources cited by the answer msg with it.
+func SetCitations(msg *schema.Message, citations []*Citation) {
+	if msg.Extra == nil {
+		msg.Extra = make(map[string]any)
+	}
+	msg.Extra[extraCitations] = citations
+}
+
+// Citations returns the sources cited by the answer msg, set by SetCitations.
+func Citations(msg *schema.Message) []*Citation {
+	v, ok := msg.Extra[extraCitations]
+	if !ok {
+		return nil
+	}
+	if citations, ok := v.([]*Citation); ok {
+		return citations
+	}
+	// decoded from json as []any
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var citations []*Citation
+	if err := json.Unmarshal(data, &citations); err != nil {
+		return nil
+	}
+	return citations
+}
+
+// BundleVersion is the version of the Bundle written by Conversation.Bundle.
+const BundleVersion = 1
+
+// Bundle is a portable copy of a conversation with all its branches.
+type Bundle struct {
+	Version    int       `json:"version"`
+	ID         string    `json:"id"`
+	Title      string    `json:"title,omitempty"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Head is the last message of the active branch.
+	Head string `json:"head,omitempty"`
+	// Messages are all the messages in the order appended, a parent always
+	// comes before its children.
+	Messages []*Node `json:"messages"`
+}
+
+// Bundle exports the conversation with all its branches.
+func (c *Conversation) Bundle() *Bundle {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &Bundle{
+		Version:    BundleVersion,
+		ID:         c.ID,
+		Title:      c.title(),
+		ExportedAt: time.Now(),
+		Head:       c.Head,
+		Messages:   append([]*Node(nil), c.nodes...),
+	}
+}
+
+// ImportBundle recreates the conversation of b in m under a new ID and
+// returns it, it returns ErrInvalidBundle if b is malformed.
+func ImportBundle(ctx context.Context, m Memory, b *Bundle) (*Conversation, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+
+	c, err := m.GetConversation(ctx, uuid.NewString(), true)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	for _, n := range b.Messages {
+		c.appendNode(&Node{ID: n.ID, ParentID: n.ParentID, Message: n.Message})
+	}
+	if b.Head != "" {
+		c.setHead(b.Head)
+	}
+	c.mu.Unlock()
+
+	if b.Title != "" {
+		if err := m.SetTitle(ctx, c.ID, b.Title); err != nil {
+			return nil, err
+		}
+		c.Title = defaultTitle(b.Title)
+	}
+	return c, nil
+}
+
+func (b *Bundle) validate() error {
+	if b.Version != BundleVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, b.Version)
+	}
+	seen := make(map[string]bool, len(b.Messages))
+	for i, n := range b.Messages {
+		switch {
+		case n == nil || n.Message == nil:
+			return fmt.Errorf("%w: message %d is empty", ErrInvalidBundle, i)
+		case n.ID == "" || seen[n.ID]:
+			return fmt.Errorf("%w: message %d has an empty or duplicate id", ErrInvalidBundle, i)
+		case n.ParentID != "" && !seen[n.ParentID]:
+			return fmt.Errorf("%w: the parent of message %s does not come before it", ErrInvalidBundle, n.ID)
+		}
+		switch n.Message.Role {
+		case schema.User, schema.Assistant, schema.System, schema.Tool:
+		default:
+			return fmt.Errorf("%w: message %s has an unknown role %q", ErrInvalidBundle, n.ID, n.Message.Role)
+		}
+		seen[n.ID] = true
+	}
+	if b.Head != "" && !seen[b.Head] {
+		return fmt.Errorf("%w: head %s does not exist", ErrInvalidBundle, b.Head)
+	}
+	return nil
+}
+
+// maxExportToolResultRunes truncates the tool results in Markdown.
+const maxExportToolResultRunes = 1000
+
+// Markdown renders the active branch of the conversation, the tool steps of
+// the agent are folded into a <details> block before the answer, and the
+// sources cited by an answer are listed after it.
+func (c *Conversation) Markdown() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var sb strings.Builder
+	title := c.title()
+	if title == "" {
+		title = c.ID
+	}
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+
+	// the tool steps of an answer are folded once the answer comes, after
+	// the header written by the first tool call
+	var steps strings.Builder
+	answering := false
+	flushSteps := func() {
+		if steps.Len() > 0 {
+			sb.WriteString("<details>\n<summary>Tool calls</summary>\n\n")
+			sb.WriteString(steps.String())
+			sb.WriteString("</details>\n\n")
+			steps.Reset()
+		}
+		answering = false
+	}
+
+	for _, msg := range c.Messages {
+		switch {
+		case msg.Role == schema.Tool:
+			result := msg.Content
+			if runes := []rune(result); len(runes) > maxExportToolResultRunes {
+				result = string(runes[:maxExportToolResultRunes]) + "..."
+			}
+			fmt.Fprintf(&steps, "```\n%s\n```\n\n", strings.TrimSpace(result))
+			continue
+		case len(msg.ToolCalls) > 0:
+			if !answering {
+				sb.WriteString("## Assistant\n\n")
+				answering = true
+			}
+			if content := strings.TrimSpace(msg.Content); content != "" {
+				fmt.Fprintf(&steps, "%s\n\n", content)
+			}
+			for _, tc := range msg.ToolCalls {
+				fmt.Fprintf(&steps, "- `%s(%s)`\n\n", tc.Function.Name, tc.Function.Arguments)
+			}
+			continue
+		}
+
+		if answering && msg.Role == schema.Assistant {
+			flushSteps()
+		} else {
+			flushSteps()
+			fmt.Fprintf(&sb, "## %s\n\n", roleTitle(msg.Role))
+		}
+		fmt.Fprintf(&sb, "%s\n\n", strings.TrimSpace(msg.Content))
+
+		if citations := Citations(msg); len(citations) > 0 {
+			sb.WriteString("Sources:\n\n")
+			for _, citation := range citations {
+				name := strings.Join(citation.Headings, " > ")
+				if name == "" {
+					name = citation.ID
+				}
+				fmt.Fprintf(&sb, "- [%d] %s", citation.Index, name)
+				if citation.URI != "" {
+					fmt.Fprintf(&sb, " (%s)", citation.URI)
+				}
+				sb.WriteString("\n")
+			}
+			sb.WriteString("\n")
+		}
+	}
+	flushSteps()
+
+	return strings.TrimSpace(sb.String()) + "\n"
+}
+
+// title returns Title, or the default title until the conversation is loaded again.
+func (c *Conversation) title() string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return titleOf(c.nodes)
+}
+
+func roleTitle(role schema.RoleType) string {
+	switch role {
+	case schema.User:
+		return "User"
+	case schema.Assistant:
+		return "Assistant"
+	case schema.System:
+		return "System"
+	default:
+		return string(role)
+	}
+}
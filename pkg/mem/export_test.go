@@ -0,0 +1,70 @@
+package mem
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationMarkdown(t *testing.T) {
+	m := NewSimpleMemory(Config{Dir: t.TempDir()})
+	c := getConversation(t, m, "c", true)
+	turn := toolTurn()
+	SetCitations(turn[4], []*Citation{{Index: 1, ID: "branch", URI: "docs/graph.md", Headings: []string{"Graph", "Branch"}}})
+	for _, msg := range turn {
+		c.Append(msg)
+	}
+
+	// 重新读取后引用仍在
+	reloaded := getConversation(t, NewSimpleMemory(Config{Dir: m.dir}), "c", false)
+	md := reloaded.Markdown()
+	assert.True(t, strings.HasPrefix(md, "# q1\n\n## User\n\nq1\n\n## Assistant\n\n<details>"), md)
+	assert.Equal(t, 1, strings.Count(md, "## Assistant"))
+	assert.Contains(t, md, "- `search({})`")
+	assert.Contains(t, md, "```\nr2\n```")
+	assert.Contains(t, md, "</details>\n\na1\n\nSources:\n\n- [1] Graph > Branch (docs/graph.md)\n")
+}
+
+func TestBundle(t *testing.T) {
+	ctx := context.Background()
+	c := getConversation(t, NewSimpleMemory(Config{Dir: t.TempDir()}), "c", true)
+	c.Append(schema.UserMessage("u1"))
+	c.Append(schema.AssistantMessage("a1", nil))
+	_, err := c.Regenerate()
+	assert.NoError(t, err)
+	c.Append(schema.AssistantMessage("a1'", nil))
+	assert.NoError(t, c.Checkout(c.Path()[0].ID))
+	assert.NoError(t, c.Checkout(c.Nodes()[1].ID))
+
+	data, err := json.Marshal(c.Bundle())
+	assert.NoError(t, err)
+	var b Bundle
+	assert.NoError(t, json.Unmarshal(data, &b))
+
+	// 导入到另一种存储，分支与当前分支保持不变
+	m := newSQLiteMemory(t, Config{Dir: t.TempDir()})
+	imported, err := ImportBundle(ctx, m, &b)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "c", imported.ID)
+	reloaded := getConversation(t, m, imported.ID, false)
+	assert.Equal(t, "u1", reloaded.Title)
+	assert.Len(t, reloaded.Nodes(), 3)
+	assert.Equal(t, "a1", reloaded.GetFullMessages()[1].Content)
+	assert.Len(t, reloaded.View(true)[1].Siblings, 2)
+
+	for name, b := range map[string]*Bundle{
+		"version":  {Version: 2},
+		"parent":   {Version: BundleVersion, Messages: []*Node{{ID: "a", ParentID: "b", Message: schema.UserMessage("u")}}},
+		"role":     {Version: BundleVersion, Messages: []*Node{{ID: "a", Message: &schema.Message{Role: "robot"}}}},
+		"head":     {Version: BundleVersion, Head: "x"},
+		"no id":    {Version: BundleVersion, Messages: []*Node{{Message: schema.UserMessage("u")}}},
+		"no value": {Version: BundleVersion, Messages: []*Node{{ID: "a"}}},
+	} {
+		_, err := ImportBundle(ctx, m, b)
+		assert.ErrorIs(t, err, ErrInvalidBundle, name)
+	}
+}
@@ -0,0 +1,341 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"Eino-example/pkg/vectorstore"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+)
+
+var ErrFactNotFound = errors.New("fact not found")
+
+// Fact is a durable fact about the user or their projects, remembered across
+// conversations.
+type Fact struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	// ConversationID is the conversation the fact was learned from.
+	ConversationID string    `json:"conversation_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+const (
+	// DefaultFactsTopK is the number of facts retrieved for a query.
+	DefaultFactsTopK = 5
+	// knownFactsTopK is the number of related facts shown to the extractor,
+	// so that it does not extract them again.
+	knownFactsTopK = 20
+)
+
+type factRecord struct {
+	*Fact
+	Vector []float64 `json:"vector"`
+}
+
+// FactStore is the long-term memory shared by all conversations, it keeps the
+// facts with their embeddings in a json file and retrieves them by cosine
+// similarity. It is a retriever.Retriever of the facts as documents.
+type FactStore struct {
+	mu       sync.RWMutex
+	path     string
+	embedder embedding.Embedder
+	records  []*factRecord
+}
+
+// NewFactStore opens the facts stored at path, the file is created on the
+// first fact remembered.
+func NewFactStore(path string, embedder embedding.Embedder) (*FactStore, error) {
+	if embedder == nil {
+		return nil, fmt.Errorf("embedding cannot be empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dir: %w", err)
+	}
+	s := &FactStore{path: path, embedder: embedder}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read facts: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal facts: %w", err)
+	}
+	return s, nil
+}
+
+// Facts returns all the facts in the order remembered.
+func (s *FactStore) Facts() []*Fact {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	facts := make([]*Fact, 0, len(s.records))
+	for _, rec := range s.records {
+		facts = append(facts, rec.Fact)
+	}
+	return facts
+}
+
+// Remember embeds and stores the facts learned from the conversation, the
+// ones already remembered with the same text are skipped.
+func (s *FactStore) Remember(ctx context.Context, conversationID string, contents []string) ([]*Fact, error) {
+	s.mu.RLock()
+	seen := make(map[string]bool, len(s.records))
+	for _, rec := range s.records {
+		seen[factKey(rec.Content)] = true
+	}
+	s.mu.RUnlock()
+
+	var texts []string
+	for _, content := range contents {
+		content = strings.Join(strings.Fields(content), " ")
+		if content == "" || seen[factKey(content)] {
+			continue
+		}
+		seen[factKey(content)] = true
+		texts = append(texts, content)
+	}
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	vectors, err := s.embedder.EmbedStrings(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed facts: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("invalid embedding result, expected %d vectors, got %d", len(texts), len(vectors))
+	}
+
+	facts := make([]*Fact, 0, len(texts))
+	records := make([]*factRecord, 0, len(texts))
+	now := time.Now()
+	for i, text := range texts {
+		fact := &Fact{ID: uuid.NewString(), Content: text, ConversationID: conversationID, CreatedAt: now}
+		facts = append(facts, fact)
+		records = append(records, &factRecord{Fact: fact, Vector: vectors[i]})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+	if err := writeJSONFile(s.path, s.records); err != nil {
+		s.records = s.records[:len(s.records)-len(records)]
+		return nil, err
+	}
+	return facts, nil
+}
+
+// Forget deletes the fact id, it returns ErrFactNotFound if id does not exist.
+func (s *FactStore) Forget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, rec := range s.records {
+		if rec.ID != id {
+			continue
+		}
+		records := append(append([]*factRecord{}, s.records[:i]...), s.records[i+1:]...)
+		if err := writeJSONFile(s.path, records); err != nil {
+			return err
+		}
+		s.records = records
+		return nil
+	}
+	return ErrFactNotFound
+}
+
+// ForgetConversation deletes the facts learned from the conversation
+// conversationID and returns the number deleted.
+func (s *FactStore) ForgetConversation(conversationID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]*factRecord, 0, len(s.records))
+	for _, rec := range s.records {
+		if rec.ConversationID != conversationID {
+			records = append(records, rec)
+		}
+	}
+	n := len(s.records) - len(records)
+	if n == 0 {
+		return 0, nil
+	}
+	if err := writeJSONFile(s.path, records); err != nil {
+		return 0, err
+	}
+	s.records = records
+	return n, nil
+}
+
+// Retrieve returns the facts most similar to query as documents, with the
+// similarity as score. It takes the TopK (default DefaultFactsTopK) and
+// ScoreThreshold options.
+func (s *FactStore) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	topK := DefaultFactsTopK
+	options := retriever.GetCommonOptions(&retriever.Options{TopK: &topK}, opts...)
+
+	s.mu.RLock()
+	empty := len(s.records) == 0
+	s.mu.RUnlock()
+	if empty {
+		return nil, nil
+	}
+
+	vectors, err := s.embedder.EmbedStrings(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query failed: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("invalid embedding result, expected 1 vector, got %d", len(vectors))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs := make([]*schema.Document, 0, len(s.records))
+	for _, rec := range s.records {
+		score := vectorstore.CosineSimilarity(vectors[0], rec.Vector)
+		if options.ScoreThreshold != nil && score < *options.ScoreThreshold {
+			continue
+		}
+		doc := &schema.Document{ID: rec.ID, Content: rec.Content}
+		docs = append(docs, doc.WithScore(score))
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score() > docs[j].Score()
+	})
+	if options.TopK != nil && *options.TopK > 0 && len(docs) > *options.TopK {
+		docs = docs[:*options.TopK]
+	}
+	return docs, nil
+}
+
+func (s *FactStore) GetType() string {
+	return "FactStore"
+}
+
+// Learn extracts the new facts of msgs with extractor and remembers them, the
+// facts related to msgs are given to extractor as the known ones.
+func (s *FactStore) Learn(ctx context.Context, extractor FactExtractor, conversationID string, msgs []*schema.Message) ([]*Fact, error) {
+	var query strings.Builder
+	for _, msg := range msgs {
+		if msg.Role == schema.User {
+			query.WriteString(msg.Content)
+			query.WriteString("\n")
+		}
+	}
+	var known []string
+	if query.Len() > 0 {
+		docs, err := s.Retrieve(ctx, query.String(), retriever.WithTopK(knownFactsTopK))
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			known = append(known, doc.Content)
+		}
+	}
+
+	contents, err := extractor.Extract(ctx, known, msgs)
+	if err != nil {
+		return nil, err
+	}
+	return s.Remember(ctx, conversationID, contents)
+}
+
+func factKey(content string) string {
+	return strings.ToLower(content)
+}
+
+// FactExtractor extracts durable facts from the messages of a conversation.
+type FactExtractor interface {
+	// Extract returns the new facts stated in msgs, known are the facts
+	// remembered already.
+	Extract(ctx context.Context, known []string, msgs []*schema.Message) ([]string, error)
+}
+
+const extractFactsPrompt = `You maintain the long-term memory of an assistant about its user.
+Extract the durable facts stated in the new messages which stay useful in later conversations:
+the user's name, role, preferences, environment, projects and decisions.
+Skip questions, one-off requests, facts about the world, and the known facts.
+Write each fact as a short standalone sentence in the language of the user.
+Output a JSON array of strings only, [] if there is nothing new.`
+
+type chatModelFactExtractor struct {
+	model model.BaseChatModel
+}
+
+// NewChatModelFactExtractor returns a FactExtractor that asks cm for the facts
+// of a turn as a JSON array.
+func NewChatModelFactExtractor(cm model.BaseChatModel) FactExtractor {
+	return &chatModelFactExtractor{model: cm}
+}
+
+func (e *chatModelFactExtractor) Extract(ctx context.Context, known []string, msgs []*schema.Message) ([]string, error) {
+	var sb strings.Builder
+	if len(known) > 0 {
+		sb.WriteString("Known facts:\n")
+		for _, fact := range known {
+			fmt.Fprintf(&sb, "- %s\n", fact)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("New messages:\n")
+	for _, msg := range CollapseToolSteps(msgs) {
+		content := msg.Content
+		if runes := []rune(content); len(runes) > maxSummarizeRunes {
+			content = string(runes[:maxSummarizeRunes]) + "..."
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", msg.Role, content)
+	}
+
+	out, err := e.model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(extractFactsPrompt),
+		schema.UserMessage(sb.String()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract facts: %w", err)
+	}
+
+	// the array may be wrapped in a code block or text
+	content := out.Content
+	start, end := strings.Index(content, "["), strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid facts: %s", content)
+	}
+	var facts []string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &facts); err != nil {
+		return nil, fmt.Errorf("invalid facts: %w", err)
+	}
+	return facts, nil
+}
@@ -0,0 +1,99 @@
+package mem
+
+import (
+	"Eino-example/pkg/fake"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "facts.json")
+	s, err := NewFactStore(path, fake.NewEmbedder(0))
+	assert.NoError(t, err)
+
+	docs, err := s.Retrieve(ctx, "anything")
+	assert.NoError(t, err)
+	assert.Empty(t, docs)
+
+	facts, err := s.Remember(ctx, "c1", []string{"The user prefers Go over Python", "  ", "The user deploys with Kubernetes"})
+	assert.NoError(t, err)
+	assert.Len(t, facts, 2)
+	assert.Equal(t, "c1", facts[0].ConversationID)
+
+	// 相同内容不重复记忆
+	facts, err = s.Remember(ctx, "c2", []string{"the user prefers go over python"})
+	assert.NoError(t, err)
+	assert.Empty(t, facts)
+
+	docs, err = s.Retrieve(ctx, "deploys Kubernetes", retriever.WithTopK(1))
+	assert.NoError(t, err)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, "The user deploys with Kubernetes", docs[0].Content)
+
+	// 重新打开后事实仍在
+	reopened, err := NewFactStore(path, fake.NewEmbedder(0))
+	assert.NoError(t, err)
+	assert.Len(t, reopened.Facts(), 2)
+	for i, fact := range s.Facts() {
+		assert.Equal(t, fact.ID, reopened.Facts()[i].ID)
+		assert.Equal(t, fact.Content, reopened.Facts()[i].Content)
+	}
+
+	assert.NoError(t, reopened.Forget(docs[0].ID))
+	assert.ErrorIs(t, reopened.Forget(docs[0].ID), ErrFactNotFound)
+	assert.Len(t, reopened.Facts(), 1)
+
+	// 删除会话时忘记从它学到的事实
+	_, err = reopened.Remember(ctx, "c2", []string{"The user writes tests first"})
+	assert.NoError(t, err)
+	n, err := reopened.ForgetConversation("c1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = reopened.ForgetConversation("c1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	reopened, err = NewFactStore(path, fake.NewEmbedder(0))
+	assert.NoError(t, err)
+	if assert.Len(t, reopened.Facts(), 1) {
+		assert.Equal(t, "c2", reopened.Facts()[0].ConversationID)
+	}
+
+	// 低于 ScoreThreshold 的事实不返回
+	docs, err = reopened.Retrieve(ctx, "Kubernetes deployment", retriever.WithScoreThreshold(0.5))
+	assert.NoError(t, err)
+	assert.Empty(t, docs)
+}
+
+func TestFactStoreLearn(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFactStore(filepath.Join(t.TempDir(), "facts.json"), fake.NewEmbedder(0))
+	assert.NoError(t, err)
+	_, err = s.Remember(ctx, "c1", []string{"The user works on the Eino examples"})
+	assert.NoError(t, err)
+
+	cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{
+		fake.Text("```json\n[\"The user uses the local vector store\"]\n```"),
+		fake.Text("no facts"),
+	}})
+	msgs := []*schema.Message{
+		schema.UserMessage("I run the Eino examples with the local vector store"),
+		schema.AssistantMessage("OK", nil),
+	}
+	facts, err := s.Learn(ctx, NewChatModelFactExtractor(cm), "c2", msgs)
+	assert.NoError(t, err)
+	assert.Len(t, facts, 1)
+	assert.Equal(t, "The user uses the local vector store", facts[0].Content)
+	assert.Equal(t, "c2", facts[0].ConversationID)
+	// 相关的已知事实提供给模型，避免重复提取
+	assert.Contains(t, cm.Inputs()[0][1].Content, "- The user works on the Eino examples")
+
+	_, err = s.Learn(ctx, NewChatModelFactExtractor(cm), "c2", msgs)
+	assert.Error(t, err)
+	assert.Len(t, s.Facts(), 2)
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Backend is where the conversations are stored.
+type Backend string
+
+const (
+	// BackendJSONL stores one JSONL file per conversation, see SimpleMemory.
+	BackendJSONL Backend = "jsonl"
+	// BackendSQLite stores all conversations in a SQLite database, see SQLiteMemory.
+	BackendSQLite Backend = "sqlite"
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	// ErrInvalidConversationID is returned for the ids that can not name a
+	// file of SimpleMemory: empty, "." or "..", or with a path separator.
+	ErrInvalidConversationID = errors.New("invalid conversation id")
+)
+
+// ValidateConversationID returns ErrInvalidConversationID if id can not be the
+// id of a conversation, so it never reaches outside the memory dir.
+func ValidateConversationID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidConversationID, id)
+	}
+	return nil
+}
+
+// Memory stores the messages of conversations.
+type Memory interface {
+	// GetConversation returns the conversation of id, or nil if it does not
+	// exist and createIfNotExist is false.
+	GetConversation(ctx context.Context, id string, createIfNotExist bool) (*Conversation, error)
+	// ListConversations returns a page of the conversations, the pinned ones
+	// first then the most recently updated, and the total number of conversations.
+	ListConversations(ctx context.Context, opts *ListOptions) ([]*ConversationInfo, int, error)
+	// SearchMessages returns a page of the messages containing query.
+	SearchMessages(ctx context.Context, query string, opts *ListOptions) ([]*SearchResult, error)
+	// DeleteConversation returns ErrConversationNotFound if id does not exist.
+	DeleteConversation(ctx context.Context, id string) error
+	// SetTitle replaces the title derived from the first user message.
+	SetTitle(ctx context.Context, id string, title string) error
+	// ReplaceDefaultTitle sets title only if the conversation still has the
+	// title derived from the first user message, i.e. it was not renamed, and
+	// reports whether it was set.
+	ReplaceDefaultTitle(ctx context.Context, id string, title string) (bool, error)
+	// SetPinned pins the conversation to the top of the list.
+	SetPinned(ctx context.Context, id string, pinned bool) error
+}
+
+// ListOptions is the page of a list, Limit <= 0 means no limit.
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+type ConversationInfo struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	MessageCount int       `json:"message_count"`
+	Pinned       bool      `json:"pinned"`
+}
+
+type SearchResult struct {
+	ConversationID string `json:"conversation_id"`
+	Title          string `json:"title"`
+	// Index is the position of the message in the order appended, it may be
+	// on another branch than the active one.
+	Index     int    `json:"index"`
+	MessageID string `json:"message_id"`
+	Role      string `json:"role"`
+	Snippet   string `json:"snippet"`
+}
+
+// NewMemory creates the memory of cfg.Backend, default BackendJSONL.
+func NewMemory(ctx context.Context, cfg Config) (Memory, error) {
+	switch cfg.Backend {
+	case BackendJSONL, "":
+		m := NewSimpleMemory(cfg)
+		if m == nil {
+			return nil, fmt.Errorf("failed to create memory dir %s", cfg.Dir)
+		}
+		return m, nil
+	case BackendSQLite:
+		return NewSQLiteMemory(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown memory backend: %s", cfg.Backend)
+	}
+}
+
+const (
+	maxTitleRunes   = 50
+	snippetRadius   = 40
+	snippetEllipsis = "..."
+)
+
+// defaultTitle is the title of a conversation derived from its first user
+// message, it also cleans up the titles set.
+func defaultTitle(content string) string {
+	title := strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(title) > maxTitleRunes {
+		title = string([]rune(title)[:maxTitleRunes]) + snippetEllipsis
+	}
+	return title
+}
+
+// snippet returns the text around the first case insensitive match of query
+// in content, or "" if it does not match.
+func snippet(content, query string) string {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	q := []rune(strings.ToLower(query))
+	// ToLower may change the length of some runes, match on the original then
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	at := -1
+	for i := 0; i+len(q) <= len(lower); i++ {
+		if string(lower[i:i+len(q)]) == string(q) {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return ""
+	}
+
+	start, end := max(at-snippetRadius, 0), min(at+len(q)+snippetRadius, len(runes))
+	s := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		s = snippetEllipsis + s
+	}
+	if end < len(runes) {
+		s += snippetEllipsis
+	}
+	return s
+}
+
+// page returns the page of n items as [start, end).
+func page(n int, opts *ListOptions) (int, int) {
+	if opts == nil {
+		return 0, n
+	}
+	start := min(max(opts.Offset, 0), n)
+	if opts.Limit <= 0 {
+		return start, n
+	}
+	return start, min(start+opts.Limit, n)
+}
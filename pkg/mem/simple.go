@@ -18,39 +18,87 @@ package mem
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/cloudwego/eino/schema"
 )
 
 func GetDefaultMemory() *SimpleMemory {
-	return NewSimpleMemory(SimpleMemoryConfig{
+	return NewSimpleMemory(DefaultConfig())
+}
+
+// DefaultConfig reads the memory config from environment variables:
+//   - MEMORY_BACKEND: jsonl (default) or sqlite
+//   - MEMORY_MAX_TOKENS: token budget of the history, default 4000, 0 falls
+//     back to a window of 6 messages
+func DefaultConfig() Config {
+	cfg := Config{
+		Backend:       BackendJSONL,
 		Dir:           "data/memory",
 		MaxWindowSize: 6,
-	})
+		MaxTokens:     4000,
+	}
+	if v := os.Getenv("MEMORY_BACKEND"); v != "" {
+		cfg.Backend = Backend(v)
+	}
+	if v := os.Getenv("MEMORY_MAX_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Printf("invalid env MEMORY_MAX_TOKENS=%s: %v", v, err)
+		} else {
+			cfg.MaxTokens = n
+		}
+	}
+	return cfg
 }
 
-type SimpleMemoryConfig struct {
-	Dir           string
+type Config struct {
+	Backend Backend
+	// Dir is where BackendJSONL stores the files, BackendSQLite imports the
+	// JSONL files found in it on open.
+	Dir string
+	// Path is the database file of BackendSQLite, default <Dir>/memory.db.
+	Path string
+
 	MaxWindowSize int
+	// MaxTokens is the token budget of the history returned by GetMessages,
+	// it replaces MaxWindowSize when > 0.
+	MaxTokens int
+	// CountTokens counts the tokens of a message, default EstimateTokens.
+	CountTokens func(msg *schema.Message) int
+	// Summarizer folds the messages out of the budget into a rolling summary,
+	// they are dropped if it is nil. It only works with MaxTokens.
+	Summarizer Summarizer
 }
 
-func NewSimpleMemory(cfg SimpleMemoryConfig) *SimpleMemory {
+func (cfg *Config) setDefaults() {
 	if cfg.Dir == "" {
 		cfg.Dir = "/tmp/eino/memory"
 	}
+	if cfg.CountTokens == nil {
+		cfg.CountTokens = EstimateTokens
+	}
+}
+
+func NewSimpleMemory(cfg Config) *SimpleMemory {
+	cfg.setDefaults()
 	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
 		return nil
 	}
 
 	return &SimpleMemory{
 		dir:           cfg.Dir,
-		maxWindowSize: cfg.MaxWindowSize,
+		cfg:           cfg,
 		conversations: make(map[string]*Conversation),
 	}
 }
@@ -59,150 +107,442 @@ func NewSimpleMemory(cfg SimpleMemoryConfig) *SimpleMemory {
 type SimpleMemory struct {
 	mu            sync.Mutex
 	dir           string
-	maxWindowSize int
+	cfg           Config
 	conversations map[string]*Conversation
 }
 
-func (m *SimpleMemory) GetConversation(id string, createIfNotExist bool) *Conversation {
+func (m *SimpleMemory) GetConversation(ctx context.Context, id string, createIfNotExist bool) (*Conversation, error) {
+	if err := ValidateConversationID(id); err != nil {
+		return nil, err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	_, ok := m.conversations[id]
-
-	filePath := filepath.Join(m.dir, id+".jsonl")
-	if !ok {
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			if createIfNotExist {
-				if err := os.WriteFile(filePath, []byte(""), 0644); err != nil {
-					return nil
-				}
-				m.conversations[id] = &Conversation{
-					ID:            id,
-					Messages:      make([]*schema.Message, 0),
-					filePath:      filePath,
-					maxWindowSize: m.maxWindowSize,
-				}
+	if con, ok := m.conversations[id]; ok {
+		return con, nil
+	}
+
+	store := m.store(id)
+	if _, err := os.Stat(store.filePath); os.IsNotExist(err) {
+		if !createIfNotExist {
+			return nil, nil
+		}
+		if err := os.WriteFile(store.filePath, []byte(""), 0644); err != nil {
+			return nil, fmt.Errorf("failed to create file: %w", err)
+		}
+		if err := writeJSONFile(store.metaPath, &metaFile{CreatedAt: time.Now()}); err != nil {
+			return nil, err
+		}
+	}
+
+	con := &Conversation{
+		ID:    id,
+		store: store,
+		cfg:   m.cfg,
+	}
+	nodes, err := readJSONL(store.filePath)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readMeta(store.metaPath)
+	if err != nil {
+		return nil, err
+	}
+	con.load(nodes, meta.Head)
+	con.Title = meta.title(nodes)
+	store.headSaved = meta.Head != ""
+	if err := store.loadSummary(con); err != nil {
+		log.Printf("failed to load summary of conversation %s: %v", id, err)
+	}
+	m.conversations[id] = con
+
+	return con, nil
+}
+
+func (m *SimpleMemory) ListConversations(ctx context.Context, opts *ListOptions) ([]*ConversationInfo, int, error) {
+	ids, err := m.ids()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	infos := make([]*ConversationInfo, 0, len(ids))
+	for _, id := range ids {
+		info, err := m.info(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		infos = append(infos, info)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Pinned != infos[j].Pinned {
+			return infos[i].Pinned
+		}
+		return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+	})
+
+	start, end := page(len(infos), opts)
+	return infos[start:end], len(infos), nil
+}
+
+// SearchMessages scans all files for the case insensitive query.
+func (m *SimpleMemory) SearchMessages(ctx context.Context, query string, opts *ListOptions) ([]*SearchResult, error) {
+	ids, err := m.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*SearchResult
+	for _, id := range ids {
+		store := m.store(id)
+		nodes, err := readJSONL(store.filePath)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := readMeta(store.metaPath)
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range nodes {
+			if s := snippet(n.Message.Content, query); s != "" {
+				results = append(results, &SearchResult{
+					ConversationID: id,
+					Title:          meta.title(nodes),
+					Index:          i,
+					MessageID:      n.ID,
+					Role:           string(n.Message.Role),
+					Snippet:        s,
+				})
 			}
 		}
+	}
+
+	start, end := page(len(results), opts)
+	return results[start:end], nil
+}
+
+func (m *SimpleMemory) DeleteConversation(ctx context.Context, id string) error {
+	if err := ValidateConversationID(id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		con := &Conversation{
-			ID:            id,
-			Messages:      make([]*schema.Message, 0),
-			filePath:      filePath,
-			maxWindowSize: m.maxWindowSize,
+	// drop the cache first, so that a missing file does not leave it behind
+	_, cached := m.conversations[id]
+	delete(m.conversations, id)
+
+	store := m.store(id)
+	if err := os.Remove(store.filePath); err != nil {
+		if os.IsNotExist(err) && !cached {
+			return ErrConversationNotFound
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete file: %w", err)
 		}
-		con.load()
-		m.conversations[id] = con
 	}
+	for _, path := range []string{store.summaryPath, store.metaPath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+	}
+	return nil
+}
 
-	return m.conversations[id]
+func (m *SimpleMemory) SetTitle(ctx context.Context, id string, title string) error {
+	return m.updateMeta(id, func(meta *metaFile) {
+		meta.Title = defaultTitle(title)
+		if con, ok := m.conversations[id]; ok {
+			con.mu.Lock()
+			con.Title = meta.Title
+			con.mu.Unlock()
+		}
+	})
 }
 
-func (m *SimpleMemory) ListConversations() []string {
+func (m *SimpleMemory) ReplaceDefaultTitle(ctx context.Context, id string, title string) (bool, error) {
+	replaced := false
+	err := m.updateMeta(id, func(meta *metaFile) {
+		if meta.Title != "" {
+			return
+		}
+		replaced = true
+		meta.Title = defaultTitle(title)
+		if con, ok := m.conversations[id]; ok {
+			con.mu.Lock()
+			con.Title = meta.Title
+			con.mu.Unlock()
+		}
+	})
+	return replaced, err
+}
+
+func (m *SimpleMemory) SetPinned(ctx context.Context, id string, pinned bool) error {
+	return m.updateMeta(id, func(meta *metaFile) {
+		meta.Pinned = pinned
+	})
+}
+
+func (m *SimpleMemory) updateMeta(id string, update func(meta *metaFile)) error {
+	if err := ValidateConversationID(id); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	store := m.store(id)
+	if _, err := os.Stat(store.filePath); err != nil {
+		if os.IsNotExist(err) {
+			return ErrConversationNotFound
+		}
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	meta, err := readMeta(store.metaPath)
+	if err != nil {
+		return err
+	}
+	update(meta)
+	return writeJSONFile(store.metaPath, meta)
+}
+
+// store returns the files of the conversation id, which must be valid.
+func (m *SimpleMemory) store(id string) *jsonlStore {
+	return &jsonlStore{
+		filePath:    filepath.Join(m.dir, id+".jsonl"),
+		summaryPath: filepath.Join(m.dir, id+summaryFileSuffix),
+		metaPath:    filepath.Join(m.dir, id+metaFileSuffix),
+	}
+}
+
+func (m *SimpleMemory) ids() ([]string, error) {
 	files, err := os.ReadDir(m.dir)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to read dir: %w", err)
 	}
 
 	ids := make([]string, 0, len(files))
 	for _, file := range files {
-		if file.IsDir() {
+		// skip the summary and meta files stored alongside
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".jsonl") {
 			continue
 		}
 		ids = append(ids, strings.TrimSuffix(file.Name(), ".jsonl"))
 	}
 
-	return ids
+	return ids, nil
 }
 
-func (m *SimpleMemory) DeleteConversation(id string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	filePath := filepath.Join(m.dir, id+".jsonl")
-	if err := os.Remove(filePath); err != nil {
-		return fmt.Errorf("failed to delete file: %w", err)
+func (m *SimpleMemory) info(id string) (*ConversationInfo, error) {
+	store := m.store(id)
+	stat, err := os.Stat(store.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	nodes, err := readJSONL(store.filePath)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readMeta(store.metaPath)
+	if err != nil {
+		return nil, err
 	}
 
-	delete(m.conversations, id)
-	return nil
+	info := &ConversationInfo{
+		ID:           id,
+		Title:        meta.title(nodes),
+		CreatedAt:    meta.CreatedAt,
+		UpdatedAt:    stat.ModTime(),
+		MessageCount: len(nodes),
+		Pinned:       meta.Pinned,
+	}
+	// files written before the meta file have no creation time
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = info.UpdatedAt
+	}
+	return info, nil
 }
 
-type Conversation struct {
-	mu sync.Mutex
-
-	ID       string            `json:"id"`
-	Messages []*schema.Message `json:"messages"`
-
-	filePath string
-
-	maxWindowSize int
+// titleOf returns the default title of the conversation of nodes.
+func titleOf(nodes []*Node) string {
+	for _, n := range nodes {
+		if n.Message.Role == schema.User {
+			return defaultTitle(n.Message.Content)
+		}
+	}
+	return ""
 }
 
-func (c *Conversation) Append(msg *schema.Message) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// jsonlStore appends the messages of a conversation to a JSONL file and keeps
+// the summary and the metadata in json files alongside.
+type jsonlStore struct {
+	filePath    string
+	summaryPath string
+	metaPath    string
+	// headSaved is set when the meta file has a head, the last message is
+	// the head otherwise.
+	headSaved bool
+}
 
-	c.Messages = append(c.Messages, msg)
+const metaFileSuffix = ".meta.json"
 
-	c.save(msg)
+type metaFile struct {
+	// Title is set by SetTitle, otherwise it is derived from the messages.
+	Title     string    `json:"title,omitempty"`
+	Pinned    bool      `json:"pinned,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Head is set when it is not the last message.
+	Head string `json:"head,omitempty"`
 }
 
-func (c *Conversation) GetFullMessages() []*schema.Message {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	return c.Messages
+func (meta *metaFile) title(nodes []*Node) string {
+	if meta.Title != "" {
+		return meta.Title
+	}
+	return titleOf(nodes)
 }
 
-// get messages with max window size
-func (c *Conversation) GetMessages() []*schema.Message {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func readMeta(metaPath string) (*metaFile, error) {
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &metaFile{}, nil
+		}
+		return nil, fmt.Errorf("failed to read meta: %w", err)
+	}
 
-	if len(c.Messages) > c.maxWindowSize {
-		return c.Messages[len(c.Messages)-c.maxWindowSize:]
+	var meta metaFile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal meta: %w", err)
 	}
+	return &meta, nil
+}
 
-	return c.Messages
+// writeJSONFile writes v to a temp file then renames it, so a crash never
+// leaves a partial file.
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return os.Rename(tmp, path)
 }
 
-func (c *Conversation) load() error {
-	reader, err := os.Open(c.filePath)
+// maxLineSize is the max size of a message in JSONL, tool results can be large.
+const maxLineSize = 16 * 1024 * 1024
+
+// readJSONL reads the nodes of a conversation, the lines written before the
+// messages had IDs are plain messages, each one the child of the previous one.
+func readJSONL(filePath string) ([]*Node, error) {
+	reader, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
+	nodes := make([]*Node, 0)
 	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
 	for scanner.Scan() {
-		line := scanner.Text()
-		var msg schema.Message
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			return fmt.Errorf("failed to unmarshal message: %w", err)
+		line := scanner.Bytes()
+		var n Node
+		if err := json.Unmarshal(line, &n); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal message: %w", err)
 		}
-		c.Messages = append(c.Messages, &msg)
+		if n.Message == nil {
+			var msg schema.Message
+			if err := json.Unmarshal(line, &msg); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+			}
+			n = Node{ID: legacyID(len(nodes)), Message: &msg}
+			if len(nodes) > 0 {
+				n.ParentID = nodes[len(nodes)-1].ID
+			}
+		}
+		nodes = append(nodes, &n)
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("scanner error: %w", err)
+		return nil, fmt.Errorf("scanner error: %w", err)
 	}
 
-	return nil
+	return nodes, nil
 }
 
-func (c *Conversation) save(msg *schema.Message) {
-	str, _ := json.Marshal(msg)
+func (s *jsonlStore) appendMessage(c *Conversation, n *Node) error {
+	str, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
 
 	// Append to file
-	f, err := os.OpenFile(c.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer f.Close()
-	f.Write(str)
-	f.WriteString("\n")
+	if _, err := f.Write(append(str, '\n')); err != nil {
+		return err
+	}
+	// the new message is the last one
+	if s.headSaved {
+		return s.saveHead(c)
+	}
+	return nil
+}
+
+func (s *jsonlStore) saveHead(c *Conversation) error {
+	meta, err := readMeta(s.metaPath)
+	if err != nil {
+		return err
+	}
+	meta.Head = ""
+	if n := len(c.nodes); n > 0 && c.nodes[n-1].ID != c.Head {
+		meta.Head = c.Head
+	}
+	if err := writeJSONFile(s.metaPath, meta); err != nil {
+		return err
+	}
+	s.headSaved = meta.Head != ""
+	return nil
+}
+
+type summaryFile struct {
+	Summary    string `json:"summary"`
+	Summarized int    `json:"summarized"`
+}
+
+func readSummary(summaryPath string) (*summaryFile, error) {
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &summaryFile{}, nil
+		}
+		return nil, fmt.Errorf("failed to read summary: %w", err)
+	}
+
+	var f summaryFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal summary: %w", err)
+	}
+	return &f, nil
+}
+
+func (s *jsonlStore) loadSummary(c *Conversation) error {
+	f, err := readSummary(s.summaryPath)
+	if err != nil {
+		return err
+	}
+	// the summary is stale if the messages were rewritten
+	if f.Summarized > len(c.Messages) {
+		return nil
+	}
+	c.Summary, c.Summarized = f.Summary, f.Summarized
+	return nil
+}
+
+func (s *jsonlStore) saveSummary(c *Conversation) error {
+	return writeJSONFile(s.summaryPath, &summaryFile{Summary: c.Summary, Summarized: c.Summarized})
 }
@@ -0,0 +1,56 @@
+package mem
+
+import (
+	"Eino-example/pkg/fake"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollapseToolSteps(t *testing.T) {
+	withText := fake.ToolCall("call_2", "search", `{}`)
+	withText.Content = "让我再查一下"
+
+	msgs := []*schema.Message{
+		schema.UserMessage("q"),
+		fake.ToolCall("call_1", "search", `{}`),
+		schema.ToolMessage("r1", "call_1"),
+		withText,
+		schema.ToolMessage("r2", "call_2"),
+		schema.AssistantMessage("a", nil),
+	}
+
+	collapsed := CollapseToolSteps(msgs)
+	assert.Equal(t, []*schema.Message{
+		schema.UserMessage("q"),
+		schema.AssistantMessage("让我再查一下", nil),
+		schema.AssistantMessage("a", nil),
+	}, collapsed)
+	// 不修改原消息
+	assert.Len(t, withText.ToolCalls, 1)
+}
+
+func TestSimpleMemoryInvalidID(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	// 另一个用户的目录之外的会话
+	outside := filepath.Join(root, "c.jsonl")
+	assert.NoError(t, os.WriteFile(outside, []byte(""), 0644))
+	m := NewSimpleMemory(Config{Dir: filepath.Join(root, "users", "bob")})
+
+	for _, id := range []string{"", ".", "..", "../c", "a/b", `..\c`} {
+		_, err := m.GetConversation(ctx, id, true)
+		assert.ErrorIs(t, err, ErrInvalidConversationID, id)
+		assert.ErrorIs(t, m.DeleteConversation(ctx, id), ErrInvalidConversationID, id)
+		assert.ErrorIs(t, m.SetTitle(ctx, id, "t"), ErrInvalidConversationID, id)
+		assert.ErrorIs(t, m.SetPinned(ctx, id, true), ErrInvalidConversationID, id)
+		_, err = m.ReplaceDefaultTitle(ctx, id, "t")
+		assert.ErrorIs(t, err, ErrInvalidConversationID, id)
+	}
+	assert.FileExists(t, outside)
+	assert.NoFileExists(t, filepath.Join(root, "c.meta.json"))
+}
@@ -0,0 +1,501 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+	_ "modernc.org/sqlite"
+)
+
+// migrations are applied in order once, the applied count is kept in PRAGMA user_version.
+var migrations = []string{
+	`CREATE TABLE conversations (
+		id            TEXT PRIMARY KEY,
+		title         TEXT NOT NULL DEFAULT '',
+		summary       TEXT NOT NULL DEFAULT '',
+		summarized    INTEGER NOT NULL DEFAULT 0,
+		message_count INTEGER NOT NULL DEFAULT 0,
+		created_at    INTEGER NOT NULL,
+		updated_at    INTEGER NOT NULL
+	);
+	CREATE INDEX conversations_updated_at ON conversations (updated_at);
+	CREATE TABLE messages (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		conversation_id TEXT NOT NULL,
+		seq             INTEGER NOT NULL,
+		role            TEXT NOT NULL,
+		content         TEXT NOT NULL,
+		message         TEXT NOT NULL,
+		created_at      INTEGER NOT NULL,
+		UNIQUE (conversation_id, seq)
+	);
+	CREATE VIRTUAL TABLE messages_fts USING fts5(content, content='messages', content_rowid='id', tokenize='trigram');
+	CREATE TRIGGER messages_ai AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts (rowid, content) VALUES (new.id, new.content);
+	END;
+	CREATE TRIGGER messages_ad AFTER DELETE ON messages BEGIN
+		INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	END;`,
+	`ALTER TABLE conversations ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;`,
+	// the messages stored before become a single branch, with the IDs given by readJSONL
+	`ALTER TABLE messages ADD COLUMN node_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE messages ADD COLUMN parent_id TEXT NOT NULL DEFAULT '';
+	UPDATE messages SET node_id = 'm' || seq, parent_id = CASE WHEN seq = 0 THEN '' ELSE 'm' || (seq - 1) END;
+	ALTER TABLE conversations ADD COLUMN head TEXT NOT NULL DEFAULT '';`,
+	// the JSONL conversations imported once, so deleting one does not import it again;
+	// the conversations stored before may have been imported already
+	`CREATE TABLE jsonl_imports (
+		id          TEXT PRIMARY KEY,
+		imported_at INTEGER NOT NULL
+	);
+	INSERT INTO jsonl_imports (id, imported_at) SELECT id, created_at FROM conversations;`,
+}
+
+// minFTSQueryRunes is the shortest query the trigram index can match, shorter
+// ones fall back to LIKE.
+const minFTSQueryRunes = 3
+
+// SQLiteMemory stores the conversations in a SQLite database, with their
+// metadata and a full text index of the messages. Conversations are read from
+// the database on every GetConversation, nothing is cached.
+type SQLiteMemory struct {
+	db  *sql.DB
+	cfg Config
+}
+
+// NewSQLiteMemory opens the database at cfg.Path and imports the JSONL
+// conversations of cfg.Dir which have not been imported before.
+func NewSQLiteMemory(ctx context.Context, cfg Config) (*SQLiteMemory, error) {
+	cfg.setDefaults()
+	if cfg.Path == "" {
+		cfg.Path = filepath.Join(cfg.Dir, "memory.db")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dir: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+cfg.Path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// sqlite allows one writer at a time
+	db.SetMaxOpenConns(1)
+
+	m := &SQLiteMemory{db: db, cfg: cfg}
+	if err := m.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if n, err := m.ImportJSONL(ctx, cfg.Dir); err != nil {
+		db.Close()
+		return nil, err
+	} else if n > 0 {
+		log.Printf("imported %d conversations from %s", n, cfg.Dir)
+	}
+	return m, nil
+}
+
+func (m *SQLiteMemory) Close() error {
+	return m.db.Close()
+}
+
+func (m *SQLiteMemory) migrate(ctx context.Context) error {
+	var version int
+	if err := m.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	for i := version; i < len(migrations); i++ {
+		err := withTx(ctx, m.db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate schema to version %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (m *SQLiteMemory) GetConversation(ctx context.Context, id string, createIfNotExist bool) (*Conversation, error) {
+	con := &Conversation{
+		ID:    id,
+		store: &sqliteStore{db: m.db},
+		cfg:   m.cfg,
+	}
+
+	var head string
+	err := m.db.QueryRowContext(ctx, "SELECT title, summary, summarized, head FROM conversations WHERE id = ?", id).
+		Scan(&con.Title, &con.Summary, &con.Summarized, &head)
+	if errors.Is(err, sql.ErrNoRows) {
+		if !createIfNotExist {
+			return nil, nil
+		}
+		now := time.Now().UnixMilli()
+		_, err = m.db.ExecContext(ctx, "INSERT INTO conversations (id, created_at, updated_at) VALUES (?, ?, ?) ON CONFLICT (id) DO NOTHING", id, now, now)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT node_id, parent_id, message FROM messages WHERE conversation_id = ? ORDER BY seq", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	nodes := make([]*Node, 0)
+	for rows.Next() {
+		var n Node
+		var data string
+		if err := rows.Scan(&n.ID, &n.ParentID, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := json.Unmarshal([]byte(data), &n.Message); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+		nodes = append(nodes, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	con.load(nodes, head)
+	return con, nil
+}
+
+func (m *SQLiteMemory) ListConversations(ctx context.Context, opts *ListOptions) ([]*ConversationInfo, int, error) {
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM conversations").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count conversations: %w", err)
+	}
+
+	limit, offset := limitOffset(opts)
+	rows, err := m.db.QueryContext(ctx, `SELECT id, title, created_at, updated_at, message_count, pinned FROM conversations
+		ORDER BY pinned DESC, updated_at DESC, id LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	infos := make([]*ConversationInfo, 0)
+	for rows.Next() {
+		var info ConversationInfo
+		var created, updated int64
+		if err := rows.Scan(&info.ID, &info.Title, &created, &updated, &info.MessageCount, &info.Pinned); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		info.CreatedAt, info.UpdatedAt = time.UnixMilli(created), time.UnixMilli(updated)
+		infos = append(infos, &info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	return infos, total, nil
+}
+
+// SearchMessages matches query with the trigram index, best matches first,
+// queries shorter than 3 runes are matched with LIKE, newest first.
+func (m *SQLiteMemory) SearchMessages(ctx context.Context, query string, opts *ListOptions) ([]*SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return []*SearchResult{}, nil
+	}
+
+	limit, offset := limitOffset(opts)
+	var rows *sql.Rows
+	var err error
+	if utf8.RuneCountInString(query) >= minFTSQueryRunes {
+		// quote the query as a phrase, so that its characters are not parsed as operators
+		phrase := `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+		rows, err = m.db.QueryContext(ctx, `SELECT m.conversation_id, c.title, m.seq, m.node_id, m.role, m.content
+			FROM messages_fts f JOIN messages m ON m.id = f.rowid JOIN conversations c ON c.id = m.conversation_id
+			WHERE messages_fts MATCH ? ORDER BY f.rank LIMIT ? OFFSET ?`, phrase, limit, offset)
+	} else {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+		rows, err = m.db.QueryContext(ctx, `SELECT m.conversation_id, c.title, m.seq, m.node_id, m.role, m.content
+			FROM messages m JOIN conversations c ON c.id = m.conversation_id
+			WHERE m.content LIKE ? ESCAPE '\' ORDER BY m.id DESC LIMIT ? OFFSET ?`, pattern, limit, offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]*SearchResult, 0)
+	for rows.Next() {
+		var r SearchResult
+		var content string
+		if err := rows.Scan(&r.ConversationID, &r.Title, &r.Index, &r.MessageID, &r.Role, &content); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		r.Snippet = snippet(content, query)
+		results = append(results, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	return results, nil
+}
+
+func (m *SQLiteMemory) DeleteConversation(ctx context.Context, id string) error {
+	return withTx(ctx, m.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM messages WHERE conversation_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete messages: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM conversations WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete conversation: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrConversationNotFound
+		}
+		return nil
+	})
+}
+
+func (m *SQLiteMemory) SetTitle(ctx context.Context, id string, title string) error {
+	return m.updateConversation(ctx, "UPDATE conversations SET title = ? WHERE id = ?", defaultTitle(title), id)
+}
+
+func (m *SQLiteMemory) ReplaceDefaultTitle(ctx context.Context, id string, title string) (bool, error) {
+	replaced := false
+	err := withTx(ctx, m.db, func(tx *sql.Tx) error {
+		var current string
+		err := tx.QueryRowContext(ctx, "SELECT title FROM conversations WHERE id = ?", id).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrConversationNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get conversation: %w", err)
+		}
+		if current != "" {
+			var first string
+			err := tx.QueryRowContext(ctx, "SELECT content FROM messages WHERE conversation_id = ? AND role = ? ORDER BY seq LIMIT 1",
+				id, string(schema.User)).Scan(&first)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to get first message: %w", err)
+			}
+			if current != defaultTitle(first) {
+				return nil
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE conversations SET title = ? WHERE id = ?", defaultTitle(title), id); err != nil {
+			return fmt.Errorf("failed to update conversation: %w", err)
+		}
+		replaced = true
+		return nil
+	})
+	return replaced, err
+}
+
+func (m *SQLiteMemory) SetPinned(ctx context.Context, id string, pinned bool) error {
+	return m.updateConversation(ctx, "UPDATE conversations SET pinned = ? WHERE id = ?", pinned, id)
+}
+
+func (m *SQLiteMemory) updateConversation(ctx context.Context, query string, args ...any) error {
+	res, err := m.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// ImportJSONL imports the conversations of the JSONL files in dir, as written
+// by SimpleMemory, which have not been imported before, and returns the number
+// of conversations imported. The files are left in place, the imported ones are
+// recorded in the jsonl_imports table so a deleted conversation stays deleted.
+func (m *SQLiteMemory) ImportJSONL(ctx context.Context, dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read dir: %w", err)
+	}
+
+	imported := 0
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".jsonl") {
+			continue
+		}
+		id := strings.TrimSuffix(file.Name(), ".jsonl")
+		ok, err := m.importJSONL(ctx, dir, id)
+		if err != nil {
+			return imported, fmt.Errorf("failed to import conversation %s: %w", id, err)
+		}
+		if ok {
+			imported++
+		}
+	}
+	return imported, nil
+}
+
+func (m *SQLiteMemory) importJSONL(ctx context.Context, dir, id string) (bool, error) {
+	filePath := filepath.Join(dir, id+".jsonl")
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return false, err
+	}
+	nodes, err := readJSONL(filePath)
+	if err != nil {
+		return false, err
+	}
+	summary, err := readSummary(filepath.Join(dir, id+summaryFileSuffix))
+	if err != nil {
+		return false, err
+	}
+	meta, err := readMeta(filepath.Join(dir, id+metaFileSuffix))
+	if err != nil {
+		return false, err
+	}
+	if summary.Summarized > len(nodes) {
+		summary = &summaryFile{}
+	}
+
+	imported := false
+	err = withTx(ctx, m.db, func(tx *sql.Tx) error {
+		modified := stat.ModTime().UnixMilli()
+		res, err := tx.ExecContext(ctx, "INSERT INTO jsonl_imports (id, imported_at) VALUES (?, ?) ON CONFLICT (id) DO NOTHING",
+			id, time.Now().UnixMilli())
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			// imported before
+			return nil
+		}
+
+		created := modified
+		if !meta.CreatedAt.IsZero() {
+			created = meta.CreatedAt.UnixMilli()
+		}
+		res, err = tx.ExecContext(ctx, `INSERT INTO conversations (id, title, summary, summarized, pinned, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+			id, meta.title(nodes), summary.Summary, summary.Summarized, meta.Pinned, created, modified)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			// a conversation with the same id is stored already
+			return nil
+		}
+		for _, n := range nodes {
+			if err := insertMessage(ctx, tx, id, n, modified); err != nil {
+				return err
+			}
+		}
+		if meta.Head != "" {
+			if _, err := tx.ExecContext(ctx, "UPDATE conversations SET head = ? WHERE id = ?", meta.Head, id); err != nil {
+				return err
+			}
+		}
+		imported = true
+		return nil
+	})
+	return imported, err
+}
+
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertMessage appends n to the conversation id as its head and updates its
+// metadata, the title is set from the first user message.
+func insertMessage(ctx context.Context, tx *sql.Tx, id string, n *Node, now int64) error {
+	msg := n.Message
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO messages (conversation_id, seq, node_id, parent_id, role, content, message, created_at)
+		VALUES (?, (SELECT COALESCE(MAX(seq), -1) + 1 FROM messages WHERE conversation_id = ?), ?, ?, ?, ?, ?, ?)`,
+		id, id, n.ID, n.ParentID, string(msg.Role), msg.Content, string(data), now)
+	if err != nil {
+		return fmt.Errorf("failed to insert message: %w", err)
+	}
+
+	title := ""
+	if msg.Role == schema.User {
+		title = defaultTitle(msg.Content)
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE conversations SET message_count = message_count + 1, updated_at = ?, head = ?,
+		title = CASE WHEN title = '' THEN ? ELSE title END WHERE id = ?`, now, n.ID, title, id)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+func limitOffset(opts *ListOptions) (int, int) {
+	if opts == nil {
+		return -1, 0
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	return limit, max(opts.Offset, 0)
+}
+
+// sqliteStore writes the changes of a conversation to the database.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func (s *sqliteStore) appendMessage(c *Conversation, n *Node) error {
+	ctx := context.Background()
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		return insertMessage(ctx, tx, c.ID, n, time.Now().UnixMilli())
+	})
+}
+
+func (s *sqliteStore) saveHead(c *Conversation) error {
+	_, err := s.db.Exec("UPDATE conversations SET head = ? WHERE id = ?", c.Head, c.ID)
+	return err
+}
+
+func (s *sqliteStore) saveSummary(c *Conversation) error {
+	_, err := s.db.Exec("UPDATE conversations SET summary = ?, summarized = ? WHERE id = ?", c.Summary, c.Summarized, c.ID)
+	return err
+}
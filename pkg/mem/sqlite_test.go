@@ -0,0 +1,204 @@
+package mem
+
+import (
+	"Eino-example/pkg/fake"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSQLiteMemory(t *testing.T, cfg Config) *SQLiteMemory {
+	m, err := NewSQLiteMemory(context.Background(), cfg)
+	assert.NoError(t, err)
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	backends := []struct {
+		name string
+		new  func(t *testing.T) Memory
+	}{
+		{"jsonl", func(t *testing.T) Memory { return NewSimpleMemory(Config{Dir: t.TempDir()}) }},
+		{"sqlite", func(t *testing.T) Memory { return newSQLiteMemory(t, Config{Dir: t.TempDir()}) }},
+	}
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			m := b.new(t)
+
+			c, err := m.GetConversation(ctx, "missing", false)
+			assert.NoError(t, err)
+			assert.Nil(t, c)
+			assert.ErrorIs(t, m.DeleteConversation(ctx, "missing"), ErrConversationNotFound)
+
+			first := getConversation(t, m, "first", true)
+			first.Append(schema.UserMessage("Eino 的 Graph 如何添加分支？"))
+			first.Append(schema.AssistantMessage("使用 AddBranch 添加分支。", nil))
+			second := getConversation(t, m, "second", true)
+			second.Append(schema.UserMessage("什么是 Chain"))
+
+			// 重新读取的会话包含全部消息
+			got := getConversation(t, m, "first", false)
+			assert.Len(t, got.GetFullMessages(), 2)
+
+			infos, total, err := m.ListConversations(ctx, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, total)
+			assert.Len(t, infos, 2)
+			byID := map[string]*ConversationInfo{}
+			for _, info := range infos {
+				byID[info.ID] = info
+			}
+			assert.Equal(t, "Eino 的 Graph 如何添加分支？", byID["first"].Title)
+			assert.Equal(t, 2, byID["first"].MessageCount)
+			assert.Equal(t, 1, byID["second"].MessageCount)
+
+			paged, total, err := m.ListConversations(ctx, &ListOptions{Limit: 1, Offset: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, 2, total)
+			assert.Len(t, paged, 1)
+			assert.Equal(t, infos[1].ID, paged[0].ID)
+
+			results, err := m.SearchMessages(ctx, "AddBranch", nil)
+			assert.NoError(t, err)
+			assert.Len(t, results, 1)
+			assert.Equal(t, "first", results[0].ConversationID)
+			assert.Equal(t, 1, results[0].Index)
+			assert.Equal(t, "assistant", results[0].Role)
+			assert.Equal(t, "使用 AddBranch 添加分支。", results[0].Snippet)
+
+			// 中文与短查询
+			results, err = m.SearchMessages(ctx, "添加分支", nil)
+			assert.NoError(t, err)
+			assert.Len(t, results, 2)
+			results, err = m.SearchMessages(ctx, "chain", nil)
+			assert.NoError(t, err)
+			assert.Len(t, results, 1)
+			results, err = m.SearchMessages(ctx, "分支", &ListOptions{Limit: 1})
+			assert.NoError(t, err)
+			assert.Len(t, results, 1)
+
+			// 置顶的会话排在最前，重命名后标题不再来自首条消息
+			assert.NoError(t, m.SetPinned(ctx, "first", true))
+			assert.NoError(t, m.SetTitle(ctx, "first", "  Graph\n分支  "))
+			infos, _, err = m.ListConversations(ctx, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, "first", infos[0].ID)
+			assert.True(t, infos[0].Pinned)
+			assert.Equal(t, "Graph 分支", infos[0].Title)
+			assert.False(t, infos[1].Pinned)
+			assert.ErrorIs(t, m.SetTitle(ctx, "missing", "t"), ErrConversationNotFound)
+
+			// 生成的标题只替换来自首条消息的标题，不覆盖重命名
+			replaced, err := m.ReplaceDefaultTitle(ctx, "second", "Chain 简介")
+			assert.NoError(t, err)
+			assert.True(t, replaced)
+			replaced, err = m.ReplaceDefaultTitle(ctx, "first", "生成的标题")
+			assert.NoError(t, err)
+			assert.False(t, replaced)
+			infos, _, err = m.ListConversations(ctx, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, "Graph 分支", infos[0].Title)
+			assert.Equal(t, "Chain 简介", infos[1].Title)
+			_, err = m.ReplaceDefaultTitle(ctx, "missing", "t")
+			assert.ErrorIs(t, err, ErrConversationNotFound)
+			assert.ErrorIs(t, m.SetPinned(ctx, "missing", true), ErrConversationNotFound)
+
+			assert.NoError(t, m.DeleteConversation(ctx, "first"))
+			c, err = m.GetConversation(ctx, "first", false)
+			assert.NoError(t, err)
+			assert.Nil(t, c)
+			results, err = m.SearchMessages(ctx, "AddBranch", nil)
+			assert.NoError(t, err)
+			assert.Empty(t, results)
+		})
+	}
+}
+
+func TestSQLiteMemory(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("摘要随会话持久化", func(t *testing.T) {
+		cfg := Config{
+			Dir:         t.TempDir(),
+			MaxTokens:   2,
+			CountTokens: countOne,
+			Summarizer:  NewChatModelSummarizer(fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{fake.Text("摘要")}})),
+		}
+		m := newSQLiteMemory(t, cfg)
+		c := getConversation(t, m, "c", true)
+		for _, msg := range toolTurn() {
+			c.Append(msg)
+		}
+		msgs := c.GetMessages(ctx)
+
+		reloaded := getConversation(t, m, "c", false)
+		assert.Equal(t, "摘要", reloaded.Summary)
+		assert.Equal(t, c.Summarized, reloaded.Summarized)
+		assert.Equal(t, msgs, reloaded.GetMessages(ctx))
+	})
+
+	t.Run("从 JSONL 文件迁移", func(t *testing.T) {
+		dir := t.TempDir()
+		simple := NewSimpleMemory(Config{Dir: dir})
+		c := getConversation(t, simple, "old", true)
+		for _, msg := range toolTurn() {
+			c.Append(msg)
+		}
+		c.Summary, c.Summarized = "旧摘要", 1
+		assert.NoError(t, c.store.saveSummary(c))
+		assert.NoError(t, simple.SetTitle(ctx, "old", "旧会话"))
+		assert.NoError(t, simple.SetPinned(ctx, "old", true))
+
+		m := newSQLiteMemory(t, Config{Dir: dir, Path: filepath.Join(t.TempDir(), "memory.db")})
+		imported := getConversation(t, m, "old", false)
+		assert.Equal(t, c.GetFullMessages(), imported.GetFullMessages())
+		assert.Equal(t, "旧摘要", imported.Summary)
+		assert.Equal(t, 1, imported.Summarized)
+
+		infos, _, err := m.ListConversations(ctx, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "旧会话", infos[0].Title)
+		assert.True(t, infos[0].Pinned)
+		assert.Equal(t, 5, infos[0].MessageCount)
+
+		// 已导入的会话不会重复导入
+		n, err := m.ImportJSONL(ctx, dir)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		// 删除的会话在重新打开后不会再从 JSONL 文件导入
+		assert.NoError(t, m.DeleteConversation(ctx, "old"))
+		m.Close()
+		m = newSQLiteMemory(t, Config{Dir: dir, Path: m.cfg.Path})
+		infos, _, err = m.ListConversations(ctx, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, infos)
+	})
+	t.Run("旧版本数据库的消息成为单一分支", func(t *testing.T) {
+		cfg := Config{Dir: t.TempDir()}
+		all := migrations
+		migrations = all[:2]
+		old := newSQLiteMemory(t, cfg)
+		migrations = all
+		for _, msg := range []*schema.Message{schema.UserMessage("u1"), schema.AssistantMessage("a1", nil)} {
+			_, err := old.db.Exec(`INSERT INTO messages (conversation_id, seq, role, content, message, created_at)
+				VALUES ('old', (SELECT COUNT(*) FROM messages), ?, ?, '{"role":"`+string(msg.Role)+`","content":"`+msg.Content+`"}', 0)`,
+				string(msg.Role), msg.Content)
+			assert.NoError(t, err)
+		}
+		_, err := old.db.Exec("INSERT INTO conversations (id, created_at, updated_at) VALUES ('old', 0, 0)")
+		assert.NoError(t, err)
+		old.Close()
+
+		c := getConversation(t, newSQLiteMemory(t, cfg), "old", false)
+		assert.Len(t, c.GetFullMessages(), 2)
+		assert.Equal(t, "m1", c.Head)
+		assert.Equal(t, "m0", c.Path()[1].ParentID)
+	})
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const summaryFileSuffix = ".summary.json"
+
+// Summarizer folds messages into the rolling summary of a conversation.
+type Summarizer interface {
+	// Summarize returns the new summary covering summary and msgs.
+	Summarize(ctx context.Context, summary string, msgs []*schema.Message) (string, error)
+}
+
+const summarizePrompt = `You maintain the memory of a conversation between a user and an assistant.
+Merge the previous summary and the new messages into an updated summary.
+Keep the facts, decisions, user preferences and open questions that later turns may refer to,
+drop greetings and details that are no longer relevant. Write at most 200 words in the language of the conversation.
+Output the summary only.`
+
+// maxSummarizeRunes truncates long messages, e.g. tool results, in the summarize prompt.
+const maxSummarizeRunes = 2000
+
+type chatModelSummarizer struct {
+	model model.BaseChatModel
+}
+
+// NewChatModelSummarizer returns a Summarizer that asks cm to update the summary.
+func NewChatModelSummarizer(cm model.BaseChatModel) Summarizer {
+	return &chatModelSummarizer{model: cm}
+}
+
+func (s *chatModelSummarizer) Summarize(ctx context.Context, summary string, msgs []*schema.Message) (string, error) {
+	var sb strings.Builder
+	if summary != "" {
+		sb.WriteString("Previous summary:\n")
+		sb.WriteString(summary)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("New messages:\n")
+	for _, msg := range msgs {
+		content := msg.Content
+		for _, tc := range msg.ToolCalls {
+			content += fmt.Sprintf("\n[call %s(%s)]", tc.Function.Name, tc.Function.Arguments)
+		}
+		if runes := []rune(content); len(runes) > maxSummarizeRunes {
+			content = string(runes[:maxSummarizeRunes]) + "..."
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", msg.Role, content)
+	}
+
+	out, err := s.model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(summarizePrompt),
+		schema.UserMessage(sb.String()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate summary: %w", err)
+	}
+	return strings.TrimSpace(out.Content), nil
+}
+
+// EstimateTokens estimates the tokens of msg without a tokenizer: a non ASCII
+// rune (e.g. CJK) counts as one token, four ASCII bytes count as one, plus a
+// fixed overhead per message.
+func EstimateTokens(msg *schema.Message) int {
+	text := msg.Content
+	for _, tc := range msg.ToolCalls {
+		text += tc.Function.Name + tc.Function.Arguments
+	}
+
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return 4 + other + (ascii+3)/4
+}
+
+func summaryMessage(summary string) *schema.Message {
+	return schema.SystemMessage("Summary of the earlier conversation:\n" + summary)
+}
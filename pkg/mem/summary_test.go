@@ -0,0 +1,176 @@
+package mem
+
+import (
+	"Eino-example/pkg/fake"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// countOne 每条消息计为 1 个 token，便于构造预算
+func countOne(msg *schema.Message) int {
+	return 1
+}
+
+func getConversation(t *testing.T, m Memory, id string, createIfNotExist bool) *Conversation {
+	c, err := m.GetConversation(context.Background(), id, createIfNotExist)
+	assert.NoError(t, err)
+	return c
+}
+
+func toolTurn() []*schema.Message {
+	return []*schema.Message{
+		schema.UserMessage("q1"),
+		fake.ToolCall("call_1", "search", `{}`),
+		schema.ToolMessage("r1", "call_1"),
+		schema.ToolMessage("r2", "call_1"),
+		schema.AssistantMessage("a1", nil),
+	}
+}
+
+func TestConversationGetMessages(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("窗口截断不拆开工具调用与结果", func(t *testing.T) {
+		m := NewSimpleMemory(Config{Dir: t.TempDir(), MaxWindowSize: 3})
+		c := getConversation(t, m, "c", true)
+		for _, msg := range toolTurn() {
+			c.Append(msg)
+		}
+		msgs := c.GetMessages(ctx)
+		assert.Len(t, msgs, 4)
+		assert.Len(t, msgs[0].ToolCalls, 1)
+	})
+
+	t.Run("按 token 预算保留并生成摘要", func(t *testing.T) {
+		dir := t.TempDir()
+		sm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{fake.Text("用户问过 q0")}})
+		cfg := Config{
+			Dir:         dir,
+			MaxTokens:   4,
+			CountTokens: countOne,
+			Summarizer:  NewChatModelSummarizer(sm),
+		}
+		c := getConversation(t, NewSimpleMemory(cfg), "c", true)
+		c.Append(schema.UserMessage("q0"))
+		c.Append(schema.AssistantMessage("a0", nil))
+		for _, msg := range toolTurn() {
+			c.Append(msg)
+		}
+
+		// 摘要占 1 个 token，剩余预算只够最后一条回答，工具调用与结果整体移出
+		msgs := c.GetMessages(ctx)
+		assert.Len(t, msgs, 2)
+		assert.Equal(t, schema.System, msgs[0].Role)
+		assert.Contains(t, msgs[0].Content, "用户问过 q0")
+		assert.Equal(t, "a1", msgs[1].Content)
+		assert.Equal(t, 6, c.Summarized)
+
+		// 摘要本身占用预算，因此分两次折叠：先折叠第一轮，再带着旧摘要折叠工具调用
+		inputs := sm.Inputs()
+		assert.Len(t, inputs, 2)
+		assert.Contains(t, inputs[0][1].Content, "user: q0")
+		assert.Contains(t, inputs[1][1].Content, "Previous summary:\n用户问过 q0")
+		assert.Contains(t, inputs[1][1].Content, "[call search({})]")
+		assert.Contains(t, inputs[1][1].Content, "tool: r2")
+
+		// 摘要持久化在 JSONL 旁，重新加载后不再调用模型
+		_, err := os.Stat(filepath.Join(dir, "c"+summaryFileSuffix))
+		assert.NoError(t, err)
+		reloaded := getConversation(t, NewSimpleMemory(cfg), "c", false)
+		assert.Equal(t, msgs, reloaded.GetMessages(ctx))
+		assert.Equal(t, 2, sm.Calls())
+		infos, total, err := NewSimpleMemory(cfg).ListConversations(ctx, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, "c", infos[0].ID)
+
+		assert.NoError(t, NewSimpleMemory(cfg).DeleteConversation(ctx, "c"))
+		_, err = os.Stat(filepath.Join(dir, "c"+summaryFileSuffix))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("摘要失败时只保留预算内的消息", func(t *testing.T) {
+		sm := fake.NewChatModel(&fake.ChatModelConfig{Respond: func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+			return nil, errors.New("boom")
+		}})
+		c := getConversation(t, NewSimpleMemory(Config{
+			Dir:         t.TempDir(),
+			MaxTokens:   2,
+			CountTokens: countOne,
+			Summarizer:  NewChatModelSummarizer(sm),
+		}), "c", true)
+		for _, msg := range toolTurn() {
+			c.Append(msg)
+		}
+
+		msgs := c.GetMessages(ctx)
+		assert.Len(t, msgs, 1)
+		assert.Equal(t, "a1", msgs[0].Content)
+		assert.Empty(t, c.Summary)
+		assert.Equal(t, 0, c.Summarized)
+	})
+
+	t.Run("生成摘要时不锁住会话", func(t *testing.T) {
+		var c *Conversation
+		cfg := Config{Dir: t.TempDir(), MaxTokens: 2, CountTokens: countOne}
+		cfg.Summarizer = summarizerFunc(func(ctx context.Context, summary string, msgs []*schema.Message) (string, error) {
+			// 新消息追加在折叠的消息之后，摘要仍然有效
+			if summary == "" {
+				c.Append(schema.UserMessage("q2"))
+			}
+			return summary + "摘要", nil
+		})
+		c = getConversation(t, NewSimpleMemory(cfg), "c", true)
+		for _, msg := range []string{"q0", "a0", "q1"} {
+			c.Append(schema.UserMessage(msg))
+		}
+
+		msgs := c.GetMessages(ctx)
+		assert.Len(t, c.GetFullMessages(), 4)
+		assert.Equal(t, 3, c.Summarized)
+		assert.Equal(t, "摘要摘要", c.Summary)
+		assert.Equal(t, "q2", msgs[len(msgs)-1].Content)
+	})
+
+	t.Run("生成摘要时折叠的消息变化则丢弃摘要", func(t *testing.T) {
+		var c *Conversation
+		cfg := Config{Dir: t.TempDir(), MaxTokens: 2, CountTokens: countOne}
+		cfg.Summarizer = summarizerFunc(func(ctx context.Context, summary string, msgs []*schema.Message) (string, error) {
+			// 编辑第一条消息，折叠的消息不再在当前分支上
+			assert.NoError(t, c.Edit(c.Path()[0].ID))
+			return "摘要", nil
+		})
+		c = getConversation(t, NewSimpleMemory(cfg), "c", true)
+		for _, msg := range []string{"q0", "a0", "q1"} {
+			c.Append(schema.UserMessage(msg))
+		}
+
+		msgs := c.GetMessages(ctx)
+		assert.Empty(t, c.Summary)
+		assert.Equal(t, 0, c.Summarized)
+		assert.Empty(t, msgs)
+	})
+
+	t.Run("最后一条消息超出预算时仍然保留", func(t *testing.T) {
+		c := getConversation(t, NewSimpleMemory(Config{Dir: t.TempDir(), MaxTokens: 1}), "c", true)
+		c.Append(schema.UserMessage("a long question that is over the budget"))
+		assert.Len(t, c.GetMessages(ctx), 1)
+	})
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 4+2, EstimateTokens(schema.UserMessage("abcdefgh")))
+	assert.Equal(t, 4+2, EstimateTokens(schema.UserMessage("你好")))
+}
+
+type summarizerFunc func(ctx context.Context, summary string, msgs []*schema.Message) (string, error)
+
+func (f summarizerFunc) Summarize(ctx context.Context, summary string, msgs []*schema.Message) (string, error) {
+	return f(ctx, summary, msgs)
+}
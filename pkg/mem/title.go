@@ -0,0 +1,68 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Titler generates the title of a conversation.
+type Titler interface {
+	Title(ctx context.Context, msgs []*schema.Message) (string, error)
+}
+
+const titlePrompt = `Write a short title for the conversation between a user and an assistant below,
+at most 8 words or 16 Chinese characters, in the language of the user. Output the title only, without quotes.`
+
+type chatModelTitler struct {
+	model model.BaseChatModel
+}
+
+// NewChatModelTitler returns a Titler that asks cm for the title of the first exchange.
+func NewChatModelTitler(cm model.BaseChatModel) Titler {
+	return &chatModelTitler{model: cm}
+}
+
+func (t *chatModelTitler) Title(ctx context.Context, msgs []*schema.Message) (string, error) {
+	var sb strings.Builder
+	for _, msg := range CollapseToolSteps(msgs) {
+		content := msg.Content
+		if runes := []rune(content); len(runes) > maxSummarizeRunes {
+			content = string(runes[:maxSummarizeRunes]) + "..."
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", msg.Role, content)
+	}
+
+	out, err := t.model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(titlePrompt),
+		schema.UserMessage(sb.String()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate title: %w", err)
+	}
+
+	title := defaultTitle(strings.Trim(strings.TrimSpace(out.Content), `"'“”「」《》`))
+	if title == "" {
+		return "", fmt.Errorf("empty title generated")
+	}
+	return title, nil
+}
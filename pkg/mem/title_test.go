@@ -0,0 +1,32 @@
+package mem
+
+import (
+	"Eino-example/pkg/fake"
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatModelTitler(t *testing.T) {
+	ctx := context.Background()
+	exchange := []*schema.Message{
+		schema.UserMessage("Graph 如何添加分支"),
+		fake.ToolCall("call_1", "search", `{}`),
+		schema.ToolMessage("AddBranch", "call_1"),
+		schema.AssistantMessage("使用 AddBranch。", nil),
+	}
+
+	cm := fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{fake.Text(" “Graph 分支”\n")}})
+	title, err := NewChatModelTitler(cm).Title(ctx, exchange)
+	assert.NoError(t, err)
+	assert.Equal(t, "Graph 分支", title)
+	// 工具步骤不出现在提示词中
+	input := cm.Inputs()[0][1].Content
+	assert.Equal(t, "user: Graph 如何添加分支\nassistant: 使用 AddBranch。\n", input)
+
+	cm = fake.NewChatModel(&fake.ChatModelConfig{Replies: []*schema.Message{fake.Text("  ")}})
+	_, err = NewChatModelTitler(cm).Title(ctx, exchange)
+	assert.Error(t, err)
+}
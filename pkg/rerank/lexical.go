@@ -0,0 +1,57 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rerank
+
+import (
+	"Eino-example/pkg/vectorstore"
+	"context"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// LexicalReranker scores a document by the fraction of distinct query terms
+// it contains, it needs no model and is cheap enough to run on every request.
+type LexicalReranker struct{}
+
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{}
+}
+
+func (l *LexicalReranker) Rerank(ctx context.Context, query string, docs []*schema.Document) ([]*schema.Document, error) {
+	queryTerms := make(map[string]struct{})
+	for _, term := range vectorstore.Tokenize(query) {
+		queryTerms[term] = struct{}{}
+	}
+
+	reranked := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		var score float64
+		if len(queryTerms) > 0 {
+			matched := make(map[string]struct{})
+			for _, term := range vectorstore.Tokenize(doc.Content) {
+				if _, ok := queryTerms[term]; ok {
+					matched[term] = struct{}{}
+				}
+			}
+			score = float64(len(matched)) / float64(len(queryTerms))
+		}
+		reranked = append(reranked, doc.WithScore(score))
+	}
+
+	sortByScore(reranked)
+	return reranked, nil
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const llmRerankSystemPrompt = `You are a relevance judge for a retrieval system.
+Given a user query and a list of numbered documents, rate how useful each document is for answering the query,
+on a scale from 0 (irrelevant) to 10 (directly answers the query).
+Output ONLY a JSON array like [{"index":0,"score":7},{"index":1,"score":0}], one item per document, nothing else.`
+
+// LLMReranker uses a chat model as the judge to score all documents in one request.
+type LLMReranker struct {
+	model model.BaseChatModel
+	// truncates each document before sending it to the model, in runes
+	maxContentLength int
+}
+
+type LLMRerankerConfig struct {
+	Model            model.BaseChatModel
+	MaxContentLength int
+}
+
+func NewLLMReranker(ctx context.Context, config *LLMRerankerConfig) (*LLMReranker, error) {
+	if config == nil || config.Model == nil {
+		return nil, fmt.Errorf("model cannot be empty")
+	}
+	if config.MaxContentLength <= 0 {
+		config.MaxContentLength = 1000
+	}
+	return &LLMReranker{model: config.Model, maxContentLength: config.MaxContentLength}, nil
+}
+
+type llmScore struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+func (l *LLMReranker) Rerank(ctx context.Context, query string, docs []*schema.Document) ([]*schema.Document, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Query: %s\n\nDocuments:\n", query)
+	for i, doc := range docs {
+		content := []rune(doc.Content)
+		if len(content) > l.maxContentLength {
+			content = content[:l.maxContentLength]
+		}
+		fmt.Fprintf(&sb, "[%d] %s\n\n", i, string(content))
+	}
+
+	resp, err := l.model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(llmRerankSystemPrompt),
+		schema.UserMessage(sb.String()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("llm rerank failed: %w", err)
+	}
+
+	scores, err := parseLLMScores(resp.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	// documents the judge did not mention are scored 0
+	normalized := make([]float64, len(docs))
+	for _, s := range scores {
+		if s.Index < 0 || s.Index >= len(docs) {
+			continue
+		}
+		normalized[s.Index] = s.Score / 10
+	}
+
+	reranked := make([]*schema.Document, 0, len(docs))
+	for i, doc := range docs {
+		reranked = append(reranked, doc.WithScore(normalized[i]))
+	}
+
+	sortByScore(reranked)
+	return reranked, nil
+}
+
+// parseLLMScores extracts the json array from the model output, tolerating markdown code fences around it.
+func parseLLMScores(content string) ([]llmScore, error) {
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid llm rerank output: %s", content)
+	}
+
+	var scores []llmScore
+	if err := json.Unmarshal([]byte(content[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal llm rerank output: %w", err)
+	}
+	return scores, nil
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rerank
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Reranker rescores retrieved documents against the query.
+// The returned documents carry the new score (schema.Document.Score) and are
+// sorted by it in descending order.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, docs []*schema.Document) ([]*schema.Document, error)
+}
+
+// Select keeps the documents whose score is not less than threshold, and at most topN of them.
+// docs must be sorted by score in descending order.
+func Select(docs []*schema.Document, topN int, threshold float64) []*schema.Document {
+	selected := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Score() < threshold {
+			continue
+		}
+		selected = append(selected, doc)
+		if topN > 0 && len(selected) >= topN {
+			break
+		}
+	}
+	return selected
+}
+
+func sortByScore(docs []*schema.Document) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score() > docs[j].Score()
+	})
+}
@@ -0,0 +1,39 @@
+package rerank
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexicalReranker(t *testing.T) {
+	docs := []*schema.Document{
+		{ID: "1", Content: "chain orchestration"},
+		{ID: "2", Content: "graph with AddLambdaNode"},
+		{ID: "3", Content: "AddLambdaNode adds a lambda node to the graph"},
+	}
+
+	reranked, err := NewLexicalReranker().Rerank(context.Background(), "how to use AddLambdaNode in graph", docs)
+	assert.NoError(t, err)
+
+	selected := Select(reranked, 2, 0.3)
+	assert.Len(t, selected, 2)
+	// 文档 3 命中 addlambdanode、graph、to 三个查询词
+	assert.Equal(t, "3", selected[0].ID)
+	assert.InDelta(t, 3.0/6.0, selected[0].Score(), 1e-9)
+	assert.Equal(t, "2", selected[1].ID)
+	assert.InDelta(t, 2.0/6.0, selected[1].Score(), 1e-9)
+
+	assert.Len(t, Select(reranked, 0, 0.4), 1)
+}
+
+func TestParseLLMScores(t *testing.T) {
+	scores, err := parseLLMScores("```json\n[{\"index\":0,\"score\":3},{\"index\":1,\"score\":9}]\n```")
+	assert.NoError(t, err)
+	assert.Equal(t, []llmScore{{Index: 0, Score: 3}, {Index: 1, Score: 9}}, scores)
+
+	_, err = parseLLMScores("no scores")
+	assert.Error(t, err)
+}
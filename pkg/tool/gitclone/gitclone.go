@@ -28,17 +28,23 @@ import (
 	"github.com/cloudwego/eino/components/tool/utils"
 )
 
+// DefaultBaseDir 是默认的仓库克隆目录
+const DefaultBaseDir = "./data/repos"
+
 type GitCloneFileImpl struct {
 	config *GitCloneFileConfig
 }
 
 type GitCloneFileConfig struct {
 	BaseDir string
+	// AfterSync 在 clone 或 pull 成功后调用，参数为仓库的绝对路径，返回的信息追加到工具结果中，
+	// 例如将仓库索引到知识库
+	AfterSync func(ctx context.Context, repoPath string) (string, error)
 }
 
 func defaultGitCloneFileConfig(ctx context.Context) (*GitCloneFileConfig, error) {
 	config := &GitCloneFileConfig{
-		BaseDir: "./data/repos",
+		BaseDir: DefaultBaseDir,
 	}
 	return config, nil
 }
@@ -119,6 +125,17 @@ func (g *GitCloneFileImpl) Invoke(ctx context.Context, req *GitCloneRequest) (re
 		return res, nil
 	}
 	res.Message = fmt.Sprintf("success, repo path: %s", absPath)
+
+	if g.config.AfterSync != nil {
+		msg, err := g.config.AfterSync(ctx, absPath)
+		if err != nil {
+			res.Error = fmt.Sprintf("repo synced but post-sync hook failed: %v", err)
+			return res, nil
+		}
+		if msg != "" {
+			res.Message += ", " + msg
+		}
+	}
 	return res, nil
 }
 
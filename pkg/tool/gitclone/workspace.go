@@ -0,0 +1,509 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gitclone
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+)
+
+const (
+	defaultTreeDepth  = 2
+	defaultTreeLimit  = 500
+	defaultReadLines  = 200
+	maxReadLines      = 1000
+	defaultGrepLimit  = 100
+	defaultLogLimit   = 20
+	maxGrepFileSize   = 1 << 20
+	maxOutputSize     = 64 << 10
+	maxLineSize       = 64 << 20
+	binarySniffLength = 8000
+)
+
+type RepoWorkspaceAction string
+
+const (
+	RepoWorkspaceActionTree  RepoWorkspaceAction = "tree"
+	RepoWorkspaceActionRead  RepoWorkspaceAction = "read"
+	RepoWorkspaceActionGrep  RepoWorkspaceAction = "grep"
+	RepoWorkspaceActionLog   RepoWorkspaceAction = "log"
+	RepoWorkspaceActionBlame RepoWorkspaceAction = "blame"
+	RepoWorkspaceActionDiff  RepoWorkspaceAction = "diff"
+)
+
+type RepoWorkspaceRequest struct {
+	Action    RepoWorkspaceAction `json:"action" jsonschema_description:"The action to perform, enum:tree,read,grep,log,blame,diff"`
+	Path      string              `json:"path" jsonschema_description:"Path relative to the repos dir, the first two segments are the group and repo of the cloned repository, e.g. cloudwego/eino-examples/README.md; empty lists the cloned repositories with tree"`
+	Depth     int                 `json:"depth,omitempty" jsonschema_description:"Depth of the tree, 2 by default"`
+	StartLine int                 `json:"start_line,omitempty" jsonschema_description:"First line to read or blame, 1-based, 1 by default"`
+	EndLine   int                 `json:"end_line,omitempty" jsonschema_description:"Last line to read or blame, inclusive, 200 lines after start_line by default"`
+	Pattern   string              `json:"pattern,omitempty" jsonschema_description:"Regular expression (Go RE2 syntax) to grep for"`
+	Glob      string              `json:"glob,omitempty" jsonschema_description:"Only grep files whose name matches this glob, e.g. *.go"`
+	Rev       string              `json:"rev,omitempty" jsonschema_description:"Revision or range to diff, e.g. HEAD~1 or v0.1.0..HEAD; changes of the working tree when empty"`
+	Limit     int                 `json:"limit,omitempty" jsonschema_description:"Max number of tree entries, grep matches or log commits"`
+}
+
+type RepoWorkspaceResponse struct {
+	Output    string `json:"output"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error"`
+}
+
+// RepoWorkspaceImpl 查看 gitclone 克隆的仓库，所有路径都限制在 BaseDir 下
+type RepoWorkspaceImpl struct {
+	config *GitCloneFileConfig
+}
+
+func NewRepoWorkspace(ctx context.Context, config *GitCloneFileConfig) (tn tool.BaseTool, err error) {
+	if config == nil {
+		config, err = defaultGitCloneFileConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.BaseDir == "" {
+		return nil, fmt.Errorf("base dir cannot be empty")
+	}
+	t := &RepoWorkspaceImpl{config: config}
+	tn, err = t.ToEinoTool()
+	if err != nil {
+		return nil, err
+	}
+	return tn, nil
+}
+
+func (w *RepoWorkspaceImpl) ToEinoTool() (tool.BaseTool, error) {
+	return utils.InferTool("repo_workspace", "browse the repositories cloned by gitclone: list the directory tree, read lines of a file, grep with a regex, show git log, blame or diff of a path", w.Invoke)
+}
+
+func (w *RepoWorkspaceImpl) Invoke(ctx context.Context, req *RepoWorkspaceRequest) (res *RepoWorkspaceResponse, err error) {
+	res = &RepoWorkspaceResponse{}
+
+	base, err := filepath.Abs(w.config.BaseDir)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed to get absolute [%s] path: %v", w.config.BaseDir, err)
+		return res, nil
+	}
+	target, err := resolvePath(base, req.Path)
+	if err != nil {
+		res.Error = err.Error()
+		return res, nil
+	}
+
+	var out *output
+	switch req.Action {
+	case RepoWorkspaceActionTree:
+		out, err = tree(base, target, req.Depth, req.Limit)
+	case RepoWorkspaceActionRead:
+		out, err = readLines(target, req.StartLine, req.EndLine)
+	case RepoWorkspaceActionGrep:
+		out, err = grep(base, target, req.Pattern, req.Glob, req.Limit)
+	case RepoWorkspaceActionLog, RepoWorkspaceActionBlame, RepoWorkspaceActionDiff:
+		out, err = gitCommand(ctx, base, target, req)
+	default:
+		err = fmt.Errorf("unknown action: %s", req.Action)
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res, nil
+	}
+
+	res.Output, res.Truncated = out.String(), out.truncated
+	return res, nil
+}
+
+// resolvePath 将 path 解析为 base 下的绝对路径，拒绝通过 .. 或符号链接逃逸到 base 之外的路径，
+// 以及 .git 目录中的路径，其中的 config 可能带有凭据，hooks 也不应暴露
+func resolvePath(base, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("path must be relative to the repos dir: %s", path)
+	}
+	target := filepath.Join(base, filepath.FromSlash(path))
+	if !within(base, target) {
+		return "", fmt.Errorf("path is outside of the repos dir: %s", path)
+	}
+	if inGitDir(base, target) {
+		return "", fmt.Errorf("path is inside a .git directory: %s", path)
+	}
+
+	realBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", fmt.Errorf("repos dir does not exist, clone a repository first: %v", err)
+	}
+	realTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", fmt.Errorf("path does not exist: %s", path)
+	}
+	if !within(realBase, realTarget) {
+		return "", fmt.Errorf("path is outside of the repos dir: %s", path)
+	}
+	if inGitDir(realBase, realTarget) {
+		return "", fmt.Errorf("path is inside a .git directory: %s", path)
+	}
+	return target, nil
+}
+
+// inGitDir 判断 base 下的 path 是否为 .git 或在 .git 目录中
+func inGitDir(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		if skipDir(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func within(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// repoRoot 返回 path 所在的仓库目录，即 base 下的 <group>/<repo>
+func repoRoot(base, path string) (string, error) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || rel == "." {
+		return "", fmt.Errorf("path must be inside a repository, e.g. group/repo")
+	}
+	return filepath.Join(base, parts[0], parts[1]), nil
+}
+
+// output 收集工具输出，超过 maxOutputSize 后截断
+type output struct {
+	bytes.Buffer
+	truncated bool
+}
+
+// line 追加一行，输出已满时返回 false
+func (o *output) line(format string, args ...any) bool {
+	if o.truncated {
+		return false
+	}
+	s := fmt.Sprintf(format, args...)
+	if o.Len()+len(s)+1 > maxOutputSize {
+		o.truncated = true
+		return false
+	}
+	o.WriteString(s)
+	o.WriteByte('\n')
+	return true
+}
+
+func (o *output) limit(n int) {
+	if n > maxOutputSize {
+		o.truncated = true
+		o.Truncate(maxOutputSize)
+	}
+}
+
+func skipDir(name string) bool {
+	return name == ".git"
+}
+
+// tree 列出 target 下 depth 层以内的文件，目录以 / 结尾
+func tree(base, target string, depth, limit int) (*output, error) {
+	if depth <= 0 {
+		depth = defaultTreeDepth
+	}
+	if limit <= 0 {
+		limit = defaultTreeLimit
+	}
+
+	out := &output{}
+	count := 0
+	err := filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == target {
+			return nil
+		}
+		if d.IsDir() && skipDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(target, path)
+		level := strings.Count(filepath.ToSlash(rel), "/")
+		if count >= limit {
+			out.truncated = true
+			return fs.SkipAll
+		}
+		count++
+
+		name := d.Name()
+		if d.IsDir() {
+			name += "/"
+		}
+		if !out.line("%s%s", strings.Repeat("  ", level), name) {
+			return fs.SkipAll
+		}
+		if d.IsDir() && level+1 >= depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", relTo(base, target), err)
+	}
+	return out, nil
+}
+
+// readLines 读取 target 的 [start, end] 行，每行带行号
+func readLines(target string, start, end int) (*output, error) {
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 {
+		end = start + defaultReadLines - 1
+	}
+	if end < start {
+		return nil, fmt.Errorf("end_line %d is before start_line %d", end, start)
+	}
+	if end-start+1 > maxReadLines {
+		end = start + maxReadLines - 1
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		return nil, fmt.Errorf("path is a directory, use tree to list it")
+	}
+	if isBinary(file) {
+		return nil, fmt.Errorf("file is binary")
+	}
+
+	out := &output{}
+	scanner := bufio.NewScanner(file)
+	// 压缩或生成的文件可能有很长的行
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for n := 1; scanner.Scan(); n++ {
+		if n < start {
+			continue
+		}
+		if n > end {
+			// 还有更多行
+			out.truncated = true
+			break
+		}
+		if !out.line("%6d\t%s", n, scanner.Text()) {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	return out, nil
+}
+
+// isBinary 根据文件开头是否有 NUL 字节判断是否为二进制文件，并将读取位置重置到开头
+func isBinary(file *os.File) bool {
+	buf := make([]byte, binarySniffLength)
+	n, _ := file.Read(buf)
+	_, _ = file.Seek(0, 0)
+	return bytes.IndexByte(buf[:n], 0) >= 0
+}
+
+// grep 在 target 下的文本文件中搜索 pattern，输出 路径:行号: 内容
+func grep(base, target, pattern, glob string, limit int) (*output, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is required for grep action")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %v", err)
+	}
+	if glob != "" {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob: %v", err)
+		}
+	}
+	if limit <= 0 {
+		limit = defaultGrepLimit
+	}
+
+	out := &output{}
+	matches := 0
+	err = filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != target && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if glob != "" {
+			if ok, _ := filepath.Match(glob, d.Name()); !ok {
+				return nil
+			}
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxGrepFileSize {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(data[:min(len(data), binarySniffLength)], 0) >= 0 {
+			return nil
+		}
+		rel := relTo(base, path)
+		for n, line := range strings.Split(string(data), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches >= limit {
+				out.truncated = true
+				return fs.SkipAll
+			}
+			matches++
+			if !out.line("%s:%d: %s", rel, n+1, strings.TrimRight(line, "\r")) {
+				return fs.SkipAll
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to grep %s: %v", relTo(base, target), err)
+	}
+	if matches == 0 {
+		out.line("no matches")
+	}
+	return out, nil
+}
+
+// gitCommand 在 target 所在的仓库中执行 git log、blame 或 diff，路径放在 -- 之后
+func gitCommand(ctx context.Context, base, target string, req *RepoWorkspaceRequest) (*output, error) {
+	root, err := repoRoot(base, target)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return nil, err
+	}
+	rel = filepath.ToSlash(rel)
+
+	args := []string{"-C", root}
+	switch req.Action {
+	case RepoWorkspaceActionLog:
+		limit := req.Limit
+		if limit <= 0 {
+			limit = defaultLogLimit
+		}
+		args = append(args, "log", "--max-count="+strconv.Itoa(limit), "--date=short", "--pretty=format:%h %ad %an %s", "--stat")
+
+	case RepoWorkspaceActionBlame:
+		if info, err := os.Stat(target); err != nil || info.IsDir() {
+			return nil, fmt.Errorf("blame requires a file")
+		}
+		start, end := req.StartLine, req.EndLine
+		if start <= 0 {
+			start = 1
+		}
+		if end <= 0 {
+			end = start + defaultReadLines - 1
+		}
+		if end < start {
+			return nil, fmt.Errorf("end_line %d is before start_line %d", end, start)
+		}
+		// -L 超出文件行数时 git 会报错，将结束行限制在文件行数以内，空文件没有可以 blame 的行
+		lines, err := countLines(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %v", err)
+		}
+		if lines == 0 {
+			return &output{}, nil
+		}
+		if end > lines {
+			end = max(lines, start)
+		}
+		args = append(args, "blame", "--date=short", fmt.Sprintf("-L%d,%d", start, end))
+
+	case RepoWorkspaceActionDiff:
+		args = append(args, "diff")
+		if req.Rev != "" {
+			if !revRe.MatchString(req.Rev) {
+				return nil, fmt.Errorf("invalid rev: %s", req.Rev)
+			}
+			args = append(args, req.Rev)
+		}
+	}
+	args = append(args, "--", rel)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %v, output: %s", req.Action, err, strings.TrimSpace(stderr.String()))
+	}
+
+	out := &output{}
+	out.Write(data)
+	out.limit(len(data))
+	if out.Len() == 0 {
+		out.line("no changes")
+	}
+	return out, nil
+}
+
+// revRe 匹配 git 的版本和范围，不能以 - 开头，防止被当作参数
+var revRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./~^@{}-]*$`)
+
+func countLines(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n, nil
+}
+
+func relTo(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
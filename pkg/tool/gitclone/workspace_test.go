@@ -0,0 +1,153 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gitclone
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoWorkspace(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	base := t.TempDir()
+	repo := filepath.Join(base, "cloudwego", "demo")
+	assert.NoError(t, os.MkdirAll(filepath.Join(repo, "pkg"), 0755))
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=tester", "-c", "user.email=tester@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(repo, name), []byte(content), 0644))
+	}
+	git("init", "-q")
+	write("README.md", "# demo\n\nhello world\n")
+	write("pkg/main.go", "package pkg\n\nfunc Hello() string {\n\treturn \"hello\"\n}\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial commit")
+	write("pkg/main.go", "package pkg\n\nfunc Hello() string {\n\treturn \"hi\"\n}\n")
+
+	w := &RepoWorkspaceImpl{config: &GitCloneFileConfig{BaseDir: base}}
+	invoke := func(req *RepoWorkspaceRequest) *RepoWorkspaceResponse {
+		res, err := w.Invoke(context.Background(), req)
+		assert.NoError(t, err)
+		return res
+	}
+
+	t.Run("目录树", func(t *testing.T) {
+		res := invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionTree})
+		assert.Empty(t, res.Error)
+		assert.Equal(t, "cloudwego/\n  demo/\n", res.Output)
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionTree, Path: "cloudwego/demo"})
+		assert.Equal(t, "README.md\npkg/\n  main.go\n", res.Output)
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionTree, Path: "cloudwego/demo", Limit: 1})
+		assert.Equal(t, "README.md\n", res.Output)
+		assert.True(t, res.Truncated)
+	})
+
+	t.Run("读取行范围", func(t *testing.T) {
+		res := invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionRead, Path: "cloudwego/demo/pkg/main.go", StartLine: 3, EndLine: 4})
+		assert.Empty(t, res.Error)
+		assert.Equal(t, "     3\tfunc Hello() string {\n     4\t\treturn \"hi\"\n", res.Output)
+		assert.True(t, res.Truncated)
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionRead, Path: "cloudwego/demo/pkg"})
+		assert.Contains(t, res.Error, "directory")
+
+		// 超过 64KB 的长行
+		write("min.js", strings.Repeat("var a=1;", 20000)+"\nvar b=2;\n")
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionRead, Path: "cloudwego/demo/min.js", StartLine: 2})
+		assert.Empty(t, res.Error)
+		assert.Equal(t, "     2\tvar b=2;\n", res.Output)
+	})
+
+	t.Run("正则搜索", func(t *testing.T) {
+		res := invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionGrep, Path: "cloudwego", Pattern: `h(ello|i)\b`})
+		assert.Empty(t, res.Error)
+		assert.Equal(t, "cloudwego/demo/README.md:3: hello world\ncloudwego/demo/pkg/main.go:4: \treturn \"hi\"\n", res.Output)
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionGrep, Path: "cloudwego/demo", Pattern: "hello", Glob: "*.go"})
+		assert.Equal(t, "no matches\n", res.Output)
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionGrep, Pattern: "("})
+		assert.Contains(t, res.Error, "invalid pattern")
+	})
+
+	t.Run("git log、blame 和 diff", func(t *testing.T) {
+		res := invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionLog, Path: "cloudwego/demo/README.md"})
+		assert.Empty(t, res.Error)
+		assert.Contains(t, res.Output, "tester initial commit")
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionBlame, Path: "cloudwego/demo/README.md", StartLine: 3, EndLine: 100})
+		assert.Empty(t, res.Error)
+		assert.Contains(t, res.Output, "hello world")
+		assert.Equal(t, 1, strings.Count(res.Output, "\n"))
+
+		write("empty.txt", "")
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionBlame, Path: "cloudwego/demo/empty.txt"})
+		assert.Empty(t, res.Error)
+		assert.Empty(t, res.Output)
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionDiff, Path: "cloudwego/demo/pkg"})
+		assert.Empty(t, res.Error)
+		assert.Contains(t, res.Output, "+\treturn \"hi\"")
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionDiff, Path: "cloudwego/demo", Rev: "HEAD"})
+		assert.Contains(t, res.Output, "-\treturn \"hello\"")
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionDiff, Path: "cloudwego/demo", Rev: "--output=/tmp/x"})
+		assert.Contains(t, res.Error, "invalid rev")
+
+		res = invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionLog, Path: "cloudwego"})
+		assert.Contains(t, res.Error, "inside a repository")
+	})
+
+	t.Run("限制在仓库目录内", func(t *testing.T) {
+		outside := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret\n"), 0644))
+		assert.NoError(t, os.Symlink(outside, filepath.Join(repo, "escape")))
+
+		for _, path := range []string{"../secret.txt", "cloudwego/../../secret.txt", filepath.Join(outside, "secret.txt"), "cloudwego/demo/escape/secret.txt"} {
+			res := invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionRead, Path: path})
+			assert.NotEmpty(t, res.Error, path)
+			assert.Empty(t, res.Output, path)
+		}
+
+		res := invoke(&RepoWorkspaceRequest{Action: RepoWorkspaceActionGrep, Path: "cloudwego", Pattern: "secret"})
+		assert.Equal(t, "no matches\n", res.Output)
+
+		// .git 目录中的 config 可能带有凭据
+		assert.NoError(t, os.Symlink(filepath.Join(repo, ".git"), filepath.Join(repo, "gitdir")))
+		for _, path := range []string{"cloudwego/demo/.git/config", "cloudwego/demo/.git", "cloudwego/demo/pkg/../.git/HEAD", "cloudwego/demo/gitdir/config"} {
+			for _, action := range []RepoWorkspaceAction{RepoWorkspaceActionRead, RepoWorkspaceActionTree, RepoWorkspaceActionLog} {
+				res := invoke(&RepoWorkspaceRequest{Action: action, Path: path})
+				assert.Contains(t, res.Error, ".git", path)
+				assert.Empty(t, res.Output, path)
+			}
+		}
+	})
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// defaultCompactEvery 是两次压缩之间最多追加的事件数
+	defaultCompactEvery = 1000
+	// defaultHistoryLimit 是压缩后每个 task 保留的事件数
+	defaultHistoryLimit = 50
+
+	maxEventSize = 16 * 1024 * 1024
+)
+
+type EventType string
+
+const (
+	EventCreated   EventType = "created"
+	EventUpdated   EventType = "updated"
+	EventCompleted EventType = "completed"
+	EventDeleted   EventType = "deleted"
+	EventReminded  EventType = "reminded"
+	EventUndone    EventType = "undone"
+	// EventMigrated 是从旧版 tasks.jsonl 导入的 task，作为不可撤销的初始状态
+	EventMigrated EventType = "migrated"
+	// EventCompacted 位于压缩后的日志开头，Change 及更早的变更不能再撤销，它不属于任何 task
+	EventCompacted EventType = "compacted"
+)
+
+// Event 是事件日志中的一条记录，Task 和 Prev 是变更后和变更前的完整快照，nil 表示 task 不存在。
+// 同一次操作产生的事件（如删除父任务和它的子任务）有相同的 Change，撤销时一起撤销
+type Event struct {
+	Seq    int64     `json:"seq" jsonschema_description:"sequence number of the event"`
+	Change int64     `json:"change" jsonschema_description:"events of one change share the same change number"`
+	Type   EventType `json:"type" jsonschema_description:"type of the event, enum:created,updated,completed,deleted,reminded,undone,migrated"`
+	TaskID string    `json:"task_id" jsonschema_description:"id of the task"`
+	At     time.Time `json:"at" jsonschema_description:"time of the event"`
+	Task   *Task     `json:"task,omitempty" jsonschema_description:"task after the event, empty if the task was removed"`
+	Prev   *Task     `json:"prev,omitempty" jsonschema_description:"task before the event, empty if the task did not exist"`
+	// Undoes 是 undone 事件撤销的 Change
+	Undoes int64 `json:"undoes,omitempty" jsonschema_description:"change undone by an undone event"`
+}
+
+// record 生成 id 从当前状态变为 next 的事件，next 为 nil 表示删除
+func (s *Storage) record(typ EventType, id string, next *Task) *Event {
+	return &Event{Type: typ, TaskID: id, Task: clone(next), Prev: clone(s.cache[id])}
+}
+
+// commit 将 events 作为一次变更追加到日志并应用到内存，追加的事件足够多时压缩日志
+func (s *Storage) commit(events ...*Event) error {
+	now := time.Now()
+	change := s.seq + 1
+	for i, event := range events {
+		event.Seq = change + int64(i)
+		event.Change = change
+		event.At = now
+	}
+
+	// 直接追加到文件末尾
+	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	var data []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %v", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write event: %v", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %v", err)
+	}
+
+	s.seq += int64(len(events))
+	for _, event := range events {
+		s.apply(event)
+		// 删除或内容变化的 task 需要重新计算向量
+		if s.index != nil && event.Prev != nil && (event.Task == nil || searchText(event.Task) != searchText(event.Prev)) {
+			s.index.invalidate(event.TaskID)
+		}
+	}
+	s.events = append(s.events, events...)
+
+	s.appended += len(events)
+	if s.appended >= s.compactEvery {
+		return s.compact()
+	}
+	return nil
+}
+
+// apply 将事件的结果应用到内存中的 task
+func (s *Storage) apply(event *Event) {
+	if event.Task == nil {
+		delete(s.cache, event.TaskID)
+		return
+	}
+	s.cache[event.TaskID] = clone(event.Task)
+}
+
+// compact 丢弃已删除 task 的事件，每个 task 只保留最近 historyLimit 个事件，
+// 最近 historyLimit 次变更的事件全部保留，压缩后仍可以依次撤销。
+// 事件被丢弃的变更不能再完整撤销，压缩后的日志以 compacted 事件开头，记录不能撤销的最后一次变更
+func (s *Storage) compact() error {
+	s.appended = 0
+
+	recent := make(map[int64]bool)
+	for i := len(s.events) - 1; i >= 0 && len(recent) < s.historyLimit; i-- {
+		recent[s.events[i].Change] = true
+	}
+	counts := make(map[string]int)
+	dropped := make(map[int64]bool)
+	kept := make([]*Event, 0, len(s.events))
+	for i := len(s.events) - 1; i >= 0; i-- {
+		event := s.events[i]
+		if !recent[event.Change] {
+			if _, exists := s.cache[event.TaskID]; !exists || counts[event.TaskID] >= s.historyLimit {
+				dropped[event.Change] = true
+				continue
+			}
+		}
+		counts[event.TaskID]++
+		kept = append(kept, event)
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	compacted := s.compacted
+	for change := range dropped {
+		if change > compacted {
+			compacted = change
+		}
+	}
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	marker := &Event{Type: EventCompacted, Change: compacted, At: time.Now()}
+	if err := s.rewrite(append([]*Event{marker}, kept...)); err != nil {
+		return err
+	}
+	s.events, s.compacted = kept, compacted
+	return nil
+}
+
+// History 返回 task 的变更历史，按时间先后排序，已删除的 task 在压缩前仍可查询
+func (s *Storage) History(id string) ([]*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []*Event
+	for _, event := range s.events {
+		if event.TaskID == id {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+	return events, nil
+}
+
+// Undo 撤销最近一次尚未撤销的变更，返回撤销产生的 undone 事件。
+// 提醒记录、旧数据迁移和撤销本身不算变更，连续调用会依次撤销更早的变更，直到压缩丢弃了事件的变更
+func (s *Storage) Undo() ([]*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change := s.lastChange()
+	if change == 0 {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+	if change <= s.compacted {
+		return nil, fmt.Errorf("cannot undo changes before the history was compacted")
+	}
+
+	var events []*Event
+	for i := len(s.events) - 1; i >= 0; i-- {
+		event := s.events[i]
+		if event.Change != change {
+			continue
+		}
+		undo := s.record(EventUndone, event.TaskID, event.Prev)
+		undo.Undoes = change
+		events = append(events, undo)
+	}
+	if err := s.commit(events...); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// lastChange 返回最近一次尚未撤销的变更，0 表示没有可以撤销的变更
+func (s *Storage) lastChange() int64 {
+	undone := make(map[int64]bool)
+	for _, event := range s.events {
+		if event.Type == EventUndone {
+			undone[event.Undoes] = true
+		}
+	}
+
+	for i := len(s.events) - 1; i >= 0; i-- {
+		event := s.events[i]
+		switch event.Type {
+		case EventUndone, EventReminded, EventMigrated:
+			continue
+		}
+		if !undone[event.Change] {
+			return event.Change
+		}
+	}
+	return 0
+}
+
+// clone 复制 task，避免事件快照和内存中的 task 互相影响
+func clone(task *Task) *Task {
+	if task == nil {
+		return nil
+	}
+	t := *task
+	if task.Tags != nil {
+		t.Tags = append([]string{}, task.Tags...)
+	}
+	return &t
+}
@@ -0,0 +1,417 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	icsTimeLayout = "20060102T150405Z"
+	icsLocalTime  = "20060102T150405"
+	icsDate       = "20060102"
+
+	// icsRepeatProp 保存原始的循环规则，cron 等无法用 RRULE 表示的规则也能原样导回
+	icsRepeatProp = "X-EINO-REPEAT"
+)
+
+// ExportICS 将有截止时间的 task 导出为 iCalendar 的 VTODO
+func ExportICS(tasks []*Task, now time.Time) []byte {
+	var b bytes.Buffer
+	w := func(name, value string) {
+		writeICSLine(&b, name+":"+value)
+	}
+
+	w("BEGIN", "VCALENDAR")
+	w("VERSION", "2.0")
+	w("PRODID", "-//CloudWeGo//Eino Task Manager//EN")
+	w("CALSCALE", "GREGORIAN")
+	for _, task := range tasks {
+		if task.DueAt == nil {
+			continue
+		}
+		w("BEGIN", "VTODO")
+		w("UID", escapeICS(task.ID))
+		w("DTSTAMP", now.UTC().Format(icsTimeLayout))
+		if t, err := time.Parse(time.RFC3339, task.CreatedAt); err == nil {
+			w("CREATED", t.UTC().Format(icsTimeLayout))
+		}
+		if t, err := time.Parse(time.RFC3339, task.updatedAt()); err == nil {
+			w("LAST-MODIFIED", t.UTC().Format(icsTimeLayout))
+		}
+		w("SUMMARY", escapeICS(task.Title))
+		if task.Content != "" {
+			w("DESCRIPTION", escapeICS(task.Content))
+		}
+		w("DUE", task.DueAt.UTC().Format(icsTimeLayout))
+		if task.Completed {
+			w("STATUS", "COMPLETED")
+		} else {
+			w("STATUS", "NEEDS-ACTION")
+		}
+		w("PRIORITY", strconv.Itoa(icsPriority(task.Priority)))
+		if len(task.Tags) > 0 {
+			tags := make([]string, len(task.Tags))
+			for i, tag := range task.Tags {
+				tags[i] = escapeICS(tag)
+			}
+			w("CATEGORIES", strings.Join(tags, ","))
+		}
+		if task.ParentID != "" {
+			w("RELATED-TO;RELTYPE=PARENT", escapeICS(task.ParentID))
+		}
+		if task.Repeat != "" {
+			if rrule := toRRule(task.Repeat); rrule != "" {
+				w("RRULE", rrule)
+			}
+			w(icsRepeatProp, escapeICS(task.Repeat))
+		}
+		w("END", "VTODO")
+	}
+	w("END", "VCALENDAR")
+	return b.Bytes()
+}
+
+// writeICSLine 写入一行内容，超过 75 字节时按 RFC 5545 折行，不拆开多字节字符
+func writeICSLine(b *bytes.Buffer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8Start(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// 续行开头的空格占一个字节
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func utf8Start(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICS(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+func unescapeICS(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// icsPriority 将优先级映射到 iCalendar 的 1（最高）到 9（最低）
+func icsPriority(p Priority) int {
+	switch p {
+	case PriorityUrgent:
+		return 1
+	case PriorityHigh:
+		return 3
+	case PriorityLow:
+		return 9
+	default:
+		return 5
+	}
+}
+
+func fromICSPriority(v int) Priority {
+	switch {
+	case v <= 0:
+		return ""
+	case v <= 2:
+		return PriorityUrgent
+	case v <= 4:
+		return PriorityHigh
+	case v <= 6:
+		return PriorityMedium
+	default:
+		return PriorityLow
+	}
+}
+
+var icsEveryRe = regexp.MustCompile(`^every\s+(\d+)\s*(day|week|month)s?$`)
+
+// toRRule 将循环规则转换为 RRULE，cron 表达式等无法表示的规则返回空
+func toRRule(repeat string) string {
+	switch strings.ToLower(strings.TrimSpace(repeat)) {
+	case "daily", "every day", "每天":
+		return "FREQ=DAILY"
+	case "weekdays", "every weekday", "工作日", "每个工作日":
+		return "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"
+	case "weekly", "every week", "每周":
+		return "FREQ=WEEKLY"
+	case "monthly", "every month", "每月":
+		return "FREQ=MONTHLY"
+	}
+	if m := icsEveryRe.FindStringSubmatch(strings.ToLower(repeat)); m != nil {
+		freq := map[string]string{"day": "DAILY", "week": "WEEKLY", "month": "MONTHLY"}[m[2]]
+		return fmt.Sprintf("FREQ=%s;INTERVAL=%s", freq, m[1])
+	}
+	return ""
+}
+
+// fromRRule 将 RRULE 转换为循环规则，不支持的 RRULE 返回空
+func fromRRule(rrule string) string {
+	parts := make(map[string]string)
+	for _, kv := range strings.Split(rrule, ";") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			parts[strings.ToUpper(k)] = strings.ToUpper(v)
+		}
+	}
+	// 有次数或结束时间限制的循环无法表示
+	if parts["COUNT"] != "" || parts["UNTIL"] != "" {
+		return ""
+	}
+	interval := 1
+	if v, err := strconv.Atoi(parts["INTERVAL"]); err == nil && v > 0 {
+		interval = v
+	}
+	unit := map[string]string{"DAILY": "day", "WEEKLY": "week", "MONTHLY": "month"}[parts["FREQ"]]
+	switch {
+	case unit == "":
+		return ""
+	case parts["FREQ"] == "WEEKLY" && parts["BYDAY"] == "MO,TU,WE,TH,FR" && interval == 1:
+		return "weekdays"
+	case parts["BYDAY"] != "" || parts["BYMONTHDAY"] != "":
+		return ""
+	case interval == 1:
+		return map[string]string{"day": "daily", "week": "weekly", "month": "monthly"}[unit]
+	default:
+		return fmt.Sprintf("every %d %ss", interval, unit)
+	}
+}
+
+// icsProp 是一个属性行
+type icsProp struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseICS 解析 iCalendar 中的 VTODO 和 VEVENT 为 task，VTODO 以 DUE 作为截止时间，
+// VEVENT 以 DTSTART 作为截止时间。task 的 ID 取自 UID，没有 UID 的条目 ID 为空
+func ParseICS(data []byte, loc *time.Location) ([]*Task, error) {
+	props, err := readICSProps(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*Task
+	var current map[string]*icsProp
+	var categories []string
+	var kind string
+	for _, p := range props {
+		switch {
+		case p.name == "BEGIN" && (p.value == "VTODO" || p.value == "VEVENT"):
+			kind, current, categories = p.value, make(map[string]*icsProp), nil
+		case p.name == "END" && p.value == kind && current != nil:
+			task, err := icsTask(kind, current, categories, loc)
+			if err != nil {
+				return nil, err
+			}
+			if task != nil {
+				tasks = append(tasks, task)
+			}
+			kind, current = "", nil
+		case current != nil && p.name == "CATEGORIES":
+			for _, tag := range splitICSList(p.value) {
+				categories = append(categories, unescapeICS(tag))
+			}
+		case current != nil:
+			if _, exists := current[p.name]; !exists {
+				current[p.name] = p
+			}
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unterminated %s", kind)
+	}
+	return tasks, nil
+}
+
+func icsTask(kind string, props map[string]*icsProp, categories []string, loc *time.Location) (*Task, error) {
+	// 循环实例的单独修改不作为独立的 task
+	if props["RECURRENCE-ID"] != nil {
+		return nil, nil
+	}
+
+	task := &Task{Tags: categories}
+	if p := props["UID"]; p != nil {
+		task.ID = unescapeICS(p.value)
+	}
+	if p := props["SUMMARY"]; p != nil {
+		task.Title = unescapeICS(p.value)
+	}
+	if task.Title == "" {
+		task.Title = "(untitled)"
+	}
+	if p := props["DESCRIPTION"]; p != nil {
+		task.Content = unescapeICS(p.value)
+	}
+
+	dueProp := props["DUE"]
+	if kind == "VEVENT" || dueProp == nil {
+		dueProp = props["DTSTART"]
+	}
+	if dueProp != nil {
+		due, err := parseICSTime(dueProp, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of %q: %v", dueProp.name, task.Title, err)
+		}
+		task.DueAt = &due
+		task.Deadline = due.Format(time.RFC3339)
+	}
+
+	if p := props["STATUS"]; p != nil && strings.EqualFold(p.value, "COMPLETED") {
+		task.Completed = true
+	}
+	if p := props["PRIORITY"]; p != nil {
+		v, _ := strconv.Atoi(p.value)
+		task.Priority = fromICSPriority(v)
+	}
+	if p := props["RELATED-TO"]; p != nil && !strings.EqualFold(p.params["RELTYPE"], "CHILD") && !strings.EqualFold(p.params["RELTYPE"], "SIBLING") {
+		task.ParentID = unescapeICS(p.value)
+	}
+	if p := props[icsRepeatProp]; p != nil {
+		task.Repeat = unescapeICS(p.value)
+	} else if p := props["RRULE"]; p != nil {
+		task.Repeat = fromRRule(p.value)
+	}
+	if task.Repeat != "" {
+		if _, err := ParseRecurrence(task.Repeat); err != nil {
+			task.Repeat = ""
+		}
+	}
+	return task, nil
+}
+
+// parseICSTime 解析 UTC 时间、带 TZID 的本地时间、浮动时间和全天日期，全天日期使用 DefaultDeadlineHour
+func parseICSTime(p *icsProp, loc *time.Location) (time.Time, error) {
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	value := p.value
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse(icsTimeLayout, value)
+	case len(value) == len(icsDate):
+		t, err := time.ParseInLocation(icsDate, value, loc)
+		return t.Add(DefaultDeadlineHour * time.Hour), err
+	default:
+		return time.ParseInLocation(icsLocalTime, value, loc)
+	}
+}
+
+// readICSProps 展开折行并解析每一行属性
+func readICSProps(data []byte) ([]*icsProp, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar file")
+	}
+
+	props := make([]*icsProp, 0, len(lines))
+	for _, line := range lines {
+		head, value, ok := cutICSValue(line)
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		fields := strings.Split(head, ";")
+		p := &icsProp{name: strings.ToUpper(fields[0]), params: make(map[string]string), value: value}
+		for _, param := range fields[1:] {
+			if k, v, ok := strings.Cut(param, "="); ok {
+				p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+			}
+		}
+		if p.name == "BEGIN" || p.name == "END" {
+			p.value = strings.ToUpper(p.value)
+		}
+		props = append(props, p)
+	}
+	return props, nil
+}
+
+// cutICSValue 在第一个不在引号中的冒号处分开属性名和值
+func cutICSValue(line string) (string, string, bool) {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				return line[:i], line[i+1:], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// splitICSList 按未转义的逗号拆分列表值
+func splitICSList(value string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, value[start:])
+}
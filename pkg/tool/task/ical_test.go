@@ -0,0 +1,150 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICSRoundTrip(t *testing.T) {
+	due := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	tasks := []*Task{
+		{
+			ID:        "t1",
+			Title:     "周报, 第 11 周; 草稿",
+			Content:   "第一行\n第二行：" + strings.Repeat("很长的内容", 20),
+			DueAt:     &due,
+			Priority:  PriorityHigh,
+			Tags:      []string{"work", "a,b"},
+			Repeat:    "weekly",
+			ParentID:  "t0",
+			CreatedAt: "2025-03-01T10:00:00Z",
+		},
+		{ID: "t2", Title: "cron", DueAt: &due, Repeat: "0 9 * * 1-5", Completed: true},
+		{ID: "t3", Title: "no deadline"},
+	}
+
+	data := ExportICS(tasks, due)
+	for _, line := range strings.Split(string(data), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "行需要折行")
+	}
+	assert.Contains(t, string(data), "RRULE:FREQ=WEEKLY\r\n")
+	assert.NotContains(t, string(data), "no deadline")
+
+	parsed, err := ParseICS(data, time.UTC)
+	assert.NoError(t, err)
+	assert.Len(t, parsed, 2)
+	got := parsed[0]
+	assert.Equal(t, "t1", got.ID)
+	assert.Equal(t, tasks[0].Title, got.Title)
+	assert.Equal(t, tasks[0].Content, got.Content)
+	assert.True(t, due.Equal(*got.DueAt))
+	assert.Equal(t, PriorityHigh, got.Priority)
+	assert.Equal(t, []string{"work", "a,b"}, got.Tags)
+	assert.Equal(t, "weekly", got.Repeat)
+	assert.Equal(t, "t0", got.ParentID)
+	assert.Equal(t, "0 9 * * 1-5", parsed[1].Repeat)
+	assert.True(t, parsed[1].Completed)
+}
+
+func TestParseICSCalendarEvents(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:event-1@example.com",
+		"SUMMARY:Team sync\\, weekly",
+		"DESCRIPTION:Discuss the road",
+		" map",
+		"DTSTART;TZID=UTC:20250317T020000",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:event-1@example.com",
+		"RECURRENCE-ID:20250318T020000Z",
+		"SUMMARY:moved instance",
+		"DTSTART:20250318T030000Z",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:todo-1",
+		"SUMMARY:All day",
+		"DUE;VALUE=DATE:20250320",
+		"PRIORITY:1",
+		"RRULE:FREQ=DAILY;COUNT=3",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	tasks, err := ParseICS([]byte(ics), loc)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	assert.Equal(t, "Team sync, weekly", tasks[0].Title)
+	assert.Equal(t, "Discuss the roadmap", tasks[0].Content)
+	assert.True(t, time.Date(2025, 3, 17, 2, 0, 0, 0, time.UTC).Equal(*tasks[0].DueAt))
+	assert.Equal(t, "weekdays", tasks[0].Repeat)
+
+	assert.True(t, time.Date(2025, 3, 20, DefaultDeadlineHour, 0, 0, 0, loc).Equal(*tasks[1].DueAt))
+	assert.Equal(t, PriorityUrgent, tasks[1].Priority)
+	assert.Empty(t, tasks[1].Repeat, "有次数限制的 RRULE 不导入")
+
+	_, err = ParseICS([]byte("hello"), loc)
+	assert.Error(t, err)
+}
+
+func TestStorageImport(t *testing.T) {
+	s, err := NewStorage(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, s.Add(&Task{ID: "t1", Title: "old title", Tags: []string{"keep"}}))
+
+	due := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	imported, err := s.Import([]*Task{
+		{ID: "t1", Title: "new title", DueAt: &due, Deadline: due.Format(time.RFC3339)},
+		{ID: "t2", Title: "created", ParentID: "t1"},
+		{ID: "t3", Title: "orphan", ParentID: "missing"},
+		{Title: "no uid"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, imported, 4)
+
+	task, _ := s.Get("t1")
+	assert.Equal(t, "new title", task.Title)
+	assert.Equal(t, []string{"keep"}, task.Tags, "没有 CATEGORIES 时保留原有标签")
+	task, _ = s.Get("t2")
+	assert.Equal(t, "t1", task.ParentID)
+	task, _ = s.Get("t3")
+	assert.Empty(t, task.ParentID)
+
+	// 再次导入相同内容不产生变更
+	imported, err = s.Import([]*Task{{ID: "t1", Title: "new title", DueAt: &due, Deadline: due.Format(time.RFC3339)}})
+	assert.NoError(t, err)
+	assert.Empty(t, imported)
+
+	// 一次导入作为一次变更撤销
+	_, err = s.Undo()
+	assert.NoError(t, err)
+	tasks, err := s.List(&ListParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"t1"}, taskIDs(tasks))
+	task, _ = s.Get("t1")
+	assert.Equal(t, "old title", task.Title)
+}
@@ -0,0 +1,410 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"Eino-example/pkg/auth"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/google/uuid"
+)
+
+// ErrForbidden 表示调用者不是 project 的成员或角色权限不足
+var ErrForbidden = errors.New("permission denied")
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+
+	// RoleNone 在 share 时移除成员
+	RoleNone Role = "none"
+)
+
+// rank 返回角色的权限等级，owner 可以管理成员，editor 可以修改 task，viewer 只能查看
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Project 是多个用户共享的 task 列表
+type Project struct {
+	ID        string          `json:"id" jsonschema_description:"id of the project"`
+	Name      string          `json:"name" jsonschema_description:"name of the project"`
+	Members   map[string]Role `json:"members" jsonschema_description:"roles of the members by user id"`
+	CreatedAt string          `json:"created_at" jsonschema_description:"created time of the project"`
+}
+
+func (p *Project) clone() *Project {
+	c := *p
+	c.Members = make(map[string]Role, len(p.Members))
+	for user, role := range p.Members {
+		c.Members[user] = role
+	}
+	return &c
+}
+
+// Namespace 是一个用户自己的 task 或一个 project 的 task
+type Namespace struct {
+	Storage *Storage
+	// User 是个人 task 的所有者，Project 为空时有效
+	User    string
+	Project *Project
+}
+
+// Members 返回可以查看该 namespace 的用户
+func (ns *Namespace) Members() []string {
+	if ns.Project == nil {
+		return []string{ns.User}
+	}
+	members := make([]string, 0, len(ns.Project.Members))
+	for user := range ns.Project.Members {
+		members = append(members, user)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// Namespaces 按用户隔离 task：用户自己的 task 保存在 <dataDir>/users/<user>，
+// project 的 task 保存在 <dataDir>/projects/<id>，project 和成员保存在 projects.json。
+// auth.DefaultUser 使用 dataDir 本身，未开启认证时兼容之前的数据
+type Namespaces struct {
+	dir string
+
+	mu       sync.Mutex
+	storages map[string]*Storage
+	projects map[string]*Project
+	embedder embedding.Embedder
+}
+
+var defaultNamespaces *Namespaces
+
+// GetDefaultNamespaces 返回 ./data/task 下的 namespaces
+func GetDefaultNamespaces() *Namespaces {
+	if defaultNamespaces == nil {
+		InitDefaultStorage("./data/task")
+	}
+	return defaultNamespaces
+}
+
+// GetDefaultStorage 返回默认 namespaces 中 auth.DefaultUser 的 task 存储
+func GetDefaultStorage() *Storage {
+	n := GetDefaultNamespaces()
+	if n == nil {
+		return nil
+	}
+	s, _ := n.Personal(auth.DefaultUser)
+	return s
+}
+
+func InitDefaultStorage(dataDir string) error {
+	n, err := NewNamespaces(dataDir)
+	if err != nil {
+		return err
+	}
+	defaultNamespaces = n
+	return nil
+}
+
+func NewNamespaces(dataDir string) (*Namespaces, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+	n := &Namespaces{
+		dir:      dataDir,
+		storages: make(map[string]*Storage),
+		projects: make(map[string]*Project),
+	}
+
+	data, err := os.ReadFile(n.projectsPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read projects: %v", err)
+	}
+	if len(data) > 0 {
+		var projects []*Project
+		if err := json.Unmarshal(data, &projects); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal projects: %v", err)
+		}
+		for _, p := range projects {
+			n.projects[p.ID] = p
+		}
+	}
+	return n, nil
+}
+
+func (n *Namespaces) projectsPath() string {
+	return filepath.Join(n.dir, "projects.json")
+}
+
+// EnableSearch 为已打开和之后打开的所有存储开启语义搜索
+func (n *Namespaces) EnableSearch(embedder embedding.Embedder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.embedder = embedder
+	for _, s := range n.storages {
+		if err := s.EnableSearch(embedder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// open 返回 dir 下的存储，同一目录只打开一次。调用者需要持有 n.mu
+func (n *Namespaces) open(dir string) (*Storage, error) {
+	if s, ok := n.storages[dir]; ok {
+		return s, nil
+	}
+	s, err := NewStorage(dir)
+	if err != nil {
+		return nil, err
+	}
+	if n.embedder != nil {
+		if err := s.EnableSearch(n.embedder); err != nil {
+			return nil, err
+		}
+	}
+	n.storages[dir] = s
+	return s, nil
+}
+
+func (n *Namespaces) userDir(user string) (string, error) {
+	if user == auth.DefaultUser {
+		return n.dir, nil
+	}
+	name := url.PathEscape(user)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid user: %q", user)
+	}
+	return filepath.Join(n.dir, "users", name), nil
+}
+
+// Personal 返回 user 自己的 task 存储
+func (n *Namespaces) Personal(user string) (*Storage, error) {
+	dir, err := n.userDir(user)
+	if err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.open(dir)
+}
+
+// Resolve 返回 ctx 中的用户可以访问的存储：projectID 为空时是用户自己的 task，
+// 否则是 project 的 task，用户在 project 中的角色不能低于 need
+func (n *Namespaces) Resolve(ctx context.Context, projectID string, need Role) (*Storage, error) {
+	user := auth.UserFrom(ctx)
+	if projectID == "" {
+		return n.Personal(user)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	p, ok := n.projects[projectID]
+	if !ok {
+		return nil, fmt.Errorf("project not found: %s", projectID)
+	}
+	role, ok := p.Members[user]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a member of project %s", ErrForbidden, user, projectID)
+	}
+	if role.rank() < need.rank() {
+		return nil, fmt.Errorf("%w: %s is %s of project %s, %s required", ErrForbidden, user, role, projectID, need)
+	}
+	return n.open(filepath.Join(n.dir, "projects", p.ID))
+}
+
+// CreateProject 创建 project，ctx 中的用户成为 owner
+func (n *Namespaces) CreateProject(ctx context.Context, name string) (*Project, error) {
+	if name == "" {
+		return nil, fmt.Errorf("project name is required")
+	}
+	p := &Project{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Members:   map[string]Role{auth.UserFrom(ctx): RoleOwner},
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.projects[p.ID] = p
+	if err := n.saveProjects(); err != nil {
+		delete(n.projects, p.ID)
+		return nil, err
+	}
+	return p.clone(), nil
+}
+
+// Share 设置 member 在 project 中的角色，RoleNone 移除成员。只有 owner 可以管理成员，
+// project 至少保留一个 owner
+func (n *Namespaces) Share(ctx context.Context, projectID, member string, role Role) (*Project, error) {
+	if member == "" {
+		return nil, fmt.Errorf("member is required")
+	}
+	if role != RoleNone && role.rank() == 0 {
+		return nil, fmt.Errorf("unknown role: %s", role)
+	}
+	user := auth.UserFrom(ctx)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	p, ok := n.projects[projectID]
+	if !ok {
+		return nil, fmt.Errorf("project not found: %s", projectID)
+	}
+	if p.Members[user] != RoleOwner {
+		return nil, fmt.Errorf("%w: only owners can share project %s", ErrForbidden, projectID)
+	}
+
+	next := p.clone()
+	if role == RoleNone {
+		delete(next.Members, member)
+	} else {
+		next.Members[member] = role
+	}
+	owners := 0
+	for _, r := range next.Members {
+		if r == RoleOwner {
+			owners++
+		}
+	}
+	if owners == 0 {
+		return nil, fmt.Errorf("project %s must keep an owner", projectID)
+	}
+
+	n.projects[projectID] = next
+	if err := n.saveProjects(); err != nil {
+		n.projects[projectID] = p
+		return nil, err
+	}
+	return next.clone(), nil
+}
+
+// Projects 返回 ctx 中的用户参与的 project，按创建时间排序
+func (n *Namespaces) Projects(ctx context.Context) []*Project {
+	user := auth.UserFrom(ctx)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var projects []*Project
+	for _, p := range n.projects {
+		if _, ok := p.Members[user]; ok {
+			projects = append(projects, p.clone())
+		}
+	}
+	sortProjects(projects)
+	return projects
+}
+
+// All 打开数据目录下所有用户和 project 的存储，用于后台扫描截止提醒
+func (n *Namespaces) All() ([]*Namespace, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s, err := n.open(n.dir)
+	if err != nil {
+		return nil, err
+	}
+	all := []*Namespace{{Storage: s, User: auth.DefaultUser}}
+
+	entries, err := os.ReadDir(filepath.Join(n.dir, "users"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read users: %v", err)
+	}
+	for _, entry := range entries {
+		user, err := url.PathUnescape(entry.Name())
+		if !entry.IsDir() || err != nil {
+			continue
+		}
+		s, err := n.open(filepath.Join(n.dir, "users", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, &Namespace{Storage: s, User: user})
+	}
+
+	projects := make([]*Project, 0, len(n.projects))
+	for _, p := range n.projects {
+		projects = append(projects, p)
+	}
+	sortProjects(projects)
+	for _, p := range projects {
+		s, err := n.open(filepath.Join(n.dir, "projects", p.ID))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, &Namespace{Storage: s, Project: p.clone()})
+	}
+	return all, nil
+}
+
+func sortProjects(projects []*Project) {
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].CreatedAt != projects[j].CreatedAt {
+			return projects[i].CreatedAt < projects[j].CreatedAt
+		}
+		return projects[i].ID < projects[j].ID
+	})
+}
+
+// saveProjects 原子地写入 projects.json。调用者需要持有 n.mu
+func (n *Namespaces) saveProjects() error {
+	projects := make([]*Project, 0, len(n.projects))
+	for _, p := range n.projects {
+		projects = append(projects, p)
+	}
+	sortProjects(projects)
+
+	data, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal projects: %v", err)
+	}
+	tmp := n.projectsPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write projects: %v", err)
+	}
+	if err := os.Rename(tmp, n.projectsPath()); err != nil {
+		return fmt.Errorf("failed to write projects: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"Eino-example/pkg/auth"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	n, err := NewNamespaces(dir)
+	assert.NoError(t, err)
+	tool, err := NewTaskToolImpl(context.Background(), &TaskToolConfig{Namespaces: n})
+	assert.NoError(t, err)
+
+	alice := auth.WithUser(context.Background(), "alice")
+	bob := auth.WithUser(context.Background(), "bob")
+	carol := auth.WithUser(context.Background(), "carol")
+
+	invoke := func(ctx context.Context, req *TaskRequest) *TaskResponse {
+		res, err := tool.Invoke(ctx, req)
+		assert.NoError(t, err)
+		return res
+	}
+	titles := func(ctx context.Context, projectID string) []string {
+		res := invoke(ctx, &TaskRequest{Action: ActionList, ProjectID: projectID})
+		assert.Equal(t, "success", res.Status, res.Error)
+		titles := []string{}
+		for _, task := range res.TaskList {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	t.Run("用户只能看到自己的 task", func(t *testing.T) {
+		assert.Equal(t, "success", invoke(alice, &TaskRequest{Action: ActionAdd, Task: &Task{Title: "alice task"}}).Status)
+		assert.Equal(t, "success", invoke(bob, &TaskRequest{Action: ActionAdd, Task: &Task{Title: "bob task"}}).Status)
+
+		assert.Equal(t, []string{"alice task"}, titles(alice, ""))
+		assert.Equal(t, []string{"bob task"}, titles(bob, ""))
+		assert.Equal(t, []string{}, titles(context.Background(), ""))
+
+		// 其他用户的 task id 也无法访问
+		id := invoke(alice, &TaskRequest{Action: ActionList}).TaskList[0].ID
+		res := invoke(bob, &TaskRequest{Action: ActionDelete, Task: &Task{ID: id}})
+		assert.Equal(t, "error", res.Status)
+		assert.Equal(t, []string{"alice task"}, titles(alice, ""))
+	})
+
+	res := invoke(alice, &TaskRequest{Action: ActionCreateProject, Project: &Project{Name: "trip"}})
+	assert.Equal(t, "success", res.Status, res.Error)
+	project := res.Projects[0]
+	assert.Equal(t, map[string]Role{"alice": RoleOwner}, project.Members)
+
+	t.Run("按角色共享 project", func(t *testing.T) {
+		res := invoke(bob, &TaskRequest{Action: ActionList, ProjectID: project.ID})
+		assert.Equal(t, "error", res.Status)
+		assert.Contains(t, res.Error, "not a member")
+
+		res = invoke(bob, &TaskRequest{Action: ActionShare, ProjectID: project.ID, Share: &ShareParams{User: "bob", Role: RoleOwner}})
+		assert.Equal(t, "error", res.Status)
+
+		res = invoke(alice, &TaskRequest{Action: ActionShare, ProjectID: project.ID, Share: &ShareParams{User: "bob", Role: RoleEditor}})
+		assert.Equal(t, "success", res.Status, res.Error)
+		res = invoke(alice, &TaskRequest{Action: ActionShare, ProjectID: project.ID, Share: &ShareParams{User: "carol", Role: RoleViewer}})
+		assert.Equal(t, "success", res.Status, res.Error)
+
+		res = invoke(bob, &TaskRequest{Action: ActionAdd, ProjectID: project.ID, Task: &Task{Title: "book hotel"}})
+		assert.Equal(t, "success", res.Status, res.Error)
+		assert.Equal(t, []string{"book hotel"}, titles(alice, project.ID))
+		assert.Equal(t, []string{"book hotel"}, titles(carol, project.ID))
+
+		// viewer 只能查看
+		res = invoke(carol, &TaskRequest{Action: ActionAdd, ProjectID: project.ID, Task: &Task{Title: "rent car"}})
+		assert.Equal(t, "error", res.Status)
+		assert.Contains(t, res.Error, "viewer")
+		res = invoke(carol, &TaskRequest{Action: ActionUndo, ProjectID: project.ID})
+		assert.Equal(t, "error", res.Status)
+
+		// project 的 task 不出现在成员自己的 task 中
+		assert.Equal(t, []string{"bob task"}, titles(bob, ""))
+
+		projects := invoke(carol, &TaskRequest{Action: ActionProjects}).Projects
+		assert.Len(t, projects, 1)
+		assert.Equal(t, "trip", projects[0].Name)
+		assert.Empty(t, invoke(context.Background(), &TaskRequest{Action: ActionProjects}).Projects)
+	})
+
+	t.Run("移除成员和保留 owner", func(t *testing.T) {
+		res := invoke(alice, &TaskRequest{Action: ActionShare, ProjectID: project.ID, Share: &ShareParams{User: "carol", Role: RoleNone}})
+		assert.Equal(t, "success", res.Status, res.Error)
+		assert.Equal(t, "error", invoke(carol, &TaskRequest{Action: ActionList, ProjectID: project.ID}).Status)
+
+		res = invoke(alice, &TaskRequest{Action: ActionShare, ProjectID: project.ID, Share: &ShareParams{User: "alice", Role: RoleEditor}})
+		assert.Equal(t, "error", res.Status)
+		assert.Contains(t, res.Error, "owner")
+
+		_, err := n.Share(bob, project.ID, "bob", RoleOwner)
+		assert.True(t, errors.Is(err, ErrForbidden))
+	})
+
+	t.Run("重新打开后保留 project 和成员", func(t *testing.T) {
+		reopened, err := NewNamespaces(dir)
+		assert.NoError(t, err)
+		projects := reopened.Projects(bob)
+		assert.Len(t, projects, 1)
+		assert.Equal(t, map[string]Role{"alice": RoleOwner, "bob": RoleEditor}, projects[0].Members)
+
+		all, err := reopened.All()
+		assert.NoError(t, err)
+		members := map[string][]string{}
+		for _, ns := range all {
+			tasks, err := ns.Storage.List(&ListParams{})
+			assert.NoError(t, err)
+			for _, task := range tasks {
+				members[task.Title] = ns.Members()
+			}
+		}
+		assert.Equal(t, map[string][]string{
+			"alice task": {"alice"},
+			"bob task":   {"bob"},
+			"book hotel": {"alice", "bob"},
+		}, members)
+	})
+}
@@ -0,0 +1,428 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDeadlineHour 是只给出日期的截止时间所用的钟点
+const DefaultDeadlineHour = 9
+
+var absoluteLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday, "日": time.Sunday, "天": time.Sunday, "7": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday, "一": time.Monday, "1": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "二": time.Tuesday, "2": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "三": time.Wednesday, "3": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "四": time.Thursday, "4": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday, "五": time.Friday, "5": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday, "六": time.Saturday, "6": time.Saturday,
+}
+
+var (
+	clockRe    = regexp.MustCompile(`^(.*?)\s*(at\s*)?(\d{1,2})(?:[:：](\d{2}))?\s*(am|pm)?$`)
+	zhClockRe  = regexp.MustCompile(`^(.*?)(上午|早上|中午|下午|晚上)?(\d{1,2})(?:[点:：](\d{1,2})?分?|点半)$`)
+	relativeRe = regexp.MustCompile(`^in\s+(\d+)\s*(minutes?|mins?|hours?|hrs?|h|days?|d|weeks?|w)$`)
+	zhRelRe    = regexp.MustCompile(`^(\d+)\s*(分钟|小时|个小时|天|周|星期)(?:后|以后|之后)$`)
+	weekdayRe  = regexp.MustCompile(`^(?:(next|this)\s+)?(sunday|sun|monday|mon|tuesday|tue|wednesday|wed|thursday|thu|friday|fri|saturday|sat)$`)
+	zhWeekRe   = regexp.MustCompile(`^(下|这|本)?(?:周|星期|礼拜)([一二三四五六日天1-7])$`)
+)
+
+// ParseDeadline 相对于 now 解析截止时间，支持 RFC3339 和常见的日期时间格式（只有日期时取
+// DefaultDeadlineHour），以及中英文的自然语言日期："today"、"tomorrow"、"next friday 18:00"、
+// "in 3 days"、"明天下午3点"、"下周一"、"2小时后"。没有时区的时间按 now 的时区解析
+func ParseDeadline(s string, now time.Time) (time.Time, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return time.Time{}, fmt.Errorf("empty deadline")
+	}
+	loc := now.Location()
+
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t, nil
+		}
+	}
+	text := strings.ToLower(raw)
+
+	if m := relativeRe.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return addRelative(now, n, m[2][:1]), nil
+	}
+	if m := zhRelRe.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]string{"分钟": "m", "小时": "h", "个小时": "h", "天": "d", "周": "w", "星期": "w"}[m[2]]
+		return addRelative(now, n, unit), nil
+	}
+
+	datePart, hour, minute, hasClock, err := splitClock(text)
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, ok := resolveDay(strings.TrimSpace(datePart), now)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unrecognized deadline %q, use RFC3339 such as %s", s, now.Format(time.RFC3339))
+	}
+	if !hasClock {
+		hour, minute = DefaultDeadlineHour, 0
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc), nil
+}
+
+func addRelative(now time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "m":
+		return now.Add(time.Duration(n) * time.Minute)
+	case "h":
+		return now.Add(time.Duration(n) * time.Hour)
+	case "w":
+		return now.AddDate(0, 0, 7*n)
+	default:
+		return now.AddDate(0, 0, n)
+	}
+}
+
+// splitClock 从 text 末尾拆出 "18:00"、"6pm"、"下午3点" 这样的钟点
+func splitClock(text string) (rest string, hour, minute int, ok bool, err error) {
+	if m := zhClockRe.FindStringSubmatch(text); m != nil {
+		hour, _ = strconv.Atoi(m[3])
+		if m[4] != "" {
+			minute, _ = strconv.Atoi(m[4])
+		} else if strings.HasSuffix(text, "半") {
+			minute = 30
+		}
+		if (m[2] == "下午" || m[2] == "晚上") && hour < 12 {
+			hour += 12
+		}
+		rest = m[1]
+	} else if m := clockRe.FindStringSubmatch(text); m != nil && (m[2] != "" || m[4] != "" || m[5] != "") {
+		hour, _ = strconv.Atoi(m[3])
+		if m[4] != "" {
+			minute, _ = strconv.Atoi(m[4])
+		}
+		switch {
+		case m[5] == "pm" && hour < 12:
+			hour += 12
+		case m[5] == "am" && hour == 12:
+			hour = 0
+		}
+		rest = m[1]
+	} else {
+		return text, 0, 0, false, nil
+	}
+	if hour > 23 || minute > 59 {
+		return "", 0, 0, false, fmt.Errorf("invalid time of day in %q", text)
+	}
+	return rest, hour, minute, true, nil
+}
+
+// resolveDay 解析自然语言截止时间中的日期部分
+func resolveDay(text string, now time.Time) (time.Time, bool) {
+	switch text {
+	case "", "today", "tonight", "今天", "今晚":
+		return now, true
+	case "tomorrow", "明天", "明晚":
+		return now.AddDate(0, 0, 1), true
+	case "day after tomorrow", "后天":
+		return now.AddDate(0, 0, 2), true
+	case "next week", "下周":
+		return now.AddDate(0, 0, 7), true
+	case "next month", "下个月":
+		return now.AddDate(0, 1, 0), true
+	}
+
+	for _, layout := range []string{"2006-01-02", "2006/01/02"} {
+		if t, err := time.ParseInLocation(layout, text, now.Location()); err == nil {
+			return t, true
+		}
+	}
+	if m := weekdayRe.FindStringSubmatch(text); m != nil {
+		return nextWeekday(now, weekdays[m[2]]), true
+	}
+	if m := zhWeekRe.FindStringSubmatch(text); m != nil {
+		wd := weekdays[m[2]]
+		if m[1] != "下" {
+			return nextWeekday(now, wd), true
+		}
+		// 下周X 指下一个自然周（周一开始）中的那一天
+		offset := (int(now.Weekday()) + 6) % 7
+		monday := now.AddDate(0, 0, 7-offset)
+		return monday.AddDate(0, 0, (int(wd)+6)%7), true
+	}
+	return time.Time{}, false
+}
+
+// nextWeekday 返回 now 之后第一个星期为 wd 的日期
+func nextWeekday(now time.Time, wd time.Weekday) time.Time {
+	days := (int(wd) - int(now.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return now.AddDate(0, 0, days)
+}
+
+// advance 为没有截止时间的重复 task 设置下一次的时间，
+// 并将已完成的重复 task 顺延到截止时间之后的下一次
+func (t *Task) advance(now time.Time) {
+	if t.Repeat == "" {
+		t.RepeatDay = 0
+		return
+	}
+	rec, err := ParseRecurrence(t.Repeat)
+	if err != nil {
+		return
+	}
+	if t.DueAt == nil {
+		t.setDue(rec.Next(now, now))
+	}
+	t.RepeatDay = rec.repeatDay(t.DueAt, t.RepeatDay)
+	if t.Completed && t.DueAt != nil {
+		if next := rec.next(*t.DueAt, t.RepeatDay, now); !next.IsZero() {
+			t.setDue(next)
+			t.Completed = false
+		}
+	}
+}
+
+func (t *Task) setDue(due time.Time) {
+	if due.IsZero() {
+		return
+	}
+	t.DueAt = &due
+	t.Deadline = due.Format(time.RFC3339)
+}
+
+// Recurrence 是解析后的 task 重复规则
+type Recurrence struct {
+	days, months int
+	weekdaysOnly bool
+	cron         *cronSchedule
+}
+
+var everyRe = regexp.MustCompile(`^every\s+(\d+)\s*(days?|weeks?|months?)$`)
+
+// ParseRecurrence 解析重复规则："daily"、"weekdays"、"weekly"、"monthly"、
+// "every N days|weeks|months" 及其中文形式（每天、工作日、每周、每月），
+// 或者五个字段的 cron 表达式 "分 时 日 月 星期"，支持 "*"、列表、范围和步长
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	text := strings.ToLower(strings.TrimSpace(rule))
+	switch text {
+	case "daily", "every day", "每天":
+		return &Recurrence{days: 1}, nil
+	case "weekdays", "every weekday", "工作日", "每个工作日":
+		return &Recurrence{days: 1, weekdaysOnly: true}, nil
+	case "weekly", "every week", "每周":
+		return &Recurrence{days: 7}, nil
+	case "monthly", "every month", "每月":
+		return &Recurrence{months: 1}, nil
+	}
+	if m := everyRe.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid recurrence %q", rule)
+		}
+		switch m[2][0] {
+		case 'd':
+			return &Recurrence{days: n}, nil
+		case 'w':
+			return &Recurrence{days: 7 * n}, nil
+		default:
+			return &Recurrence{months: n}, nil
+		}
+	}
+	if fields := strings.Fields(text); len(fields) == 5 {
+		c, err := parseCron(fields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence %q: %v", rule, err)
+		}
+		return &Recurrence{cron: c}, nil
+	}
+	return nil, fmt.Errorf("unrecognized recurrence %q, use daily, weekdays, weekly, monthly, every N days or a cron expression", rule)
+}
+
+// Next 返回同时晚于当前截止时间 due 和 now 的下一次时间。
+// 间隔规则从 due 开始计算，保留原来的钟点
+func (r *Recurrence) Next(due, now time.Time) time.Time {
+	return r.next(due, 0, now)
+}
+
+// next 与 Next 相同，按月重复时取每月的 day 日，0 表示 due 的日期
+func (r *Recurrence) next(due time.Time, day int, now time.Time) time.Time {
+	if due.IsZero() {
+		due = now
+	}
+	if r.cron != nil {
+		from := due
+		if now.After(from) {
+			from = now
+		}
+		return r.cron.next(from)
+	}
+	// 按月重复时取每月的 day 日，31 日之后依次是 2 月 28 日、3 月 31 日，不会逐月提前
+	if r.months > 0 {
+		if day == 0 {
+			day = due.Day()
+		}
+		for n := 1; ; n++ {
+			if t := addMonths(due, n*r.months, day); t.After(now) {
+				return t
+			}
+		}
+	}
+	t := r.step(due)
+	for !t.After(now) {
+		t = r.step(t)
+	}
+	return t
+}
+
+// repeatDay 返回按月重复的 task 每月的日期，不是按月重复时返回 0。
+// due 不在 day 日（小月时为月末）时说明截止时间被修改过，改为从 due 的日期重复
+func (r *Recurrence) repeatDay(due *time.Time, day int) int {
+	if r.months == 0 || due == nil {
+		return 0
+	}
+	if day == 0 || addMonths(*due, 0, day).Day() != due.Day() {
+		return due.Day()
+	}
+	return day
+}
+
+func (r *Recurrence) step(t time.Time) time.Time {
+	t = t.AddDate(0, 0, r.days)
+	for r.weekdaysOnly && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// addMonths 将 t 后移 n 个月到该月的 day 日，该月没有 day 日时取该月最后一天，
+// 而不是像 AddDate 那样溢出到下个月
+func addMonths(t time.Time, n, day int) time.Time {
+	year, month, _ := t.Date()
+	first := time.Date(year, month+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+func parseCron(fields []string) (*cronSchedule, error) {
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var sets [5]uint64
+	for i, f := range fields {
+		set, err := parseCronField(f, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	// 7 和 0 都表示周日
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &cronSchedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid range %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value out of range %q", part)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	// 与 cron 一致：日期和星期都有限制时满足其一即可
+	if !c.domAny && !c.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// next 返回 t 之后第一个匹配的分钟，五年内没有匹配时返回零值
+func (c *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeadline(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// 2025-03-12 是周三
+	now := time.Date(2025, 3, 12, 10, 30, 0, 0, loc)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, loc)
+	}
+
+	cases := map[string]time.Time{
+		"2025-03-20T18:00:00Z":   time.Date(2025, 3, 20, 18, 0, 0, 0, time.UTC),
+		"2025-03-20T18:00":       at(3, 20, 18, 0),
+		"2025-03-20":             at(3, 20, DefaultDeadlineHour, 0),
+		"today":                  at(3, 12, DefaultDeadlineHour, 0),
+		"Tomorrow 18:00":         at(3, 13, 18, 0),
+		"tomorrow at 6pm":        at(3, 13, 18, 0),
+		"day after tomorrow 9am": at(3, 14, 9, 0),
+		"friday":                 at(3, 14, DefaultDeadlineHour, 0),
+		"next wednesday 14:30":   at(3, 19, 14, 30),
+		"saturday 6pm":           at(3, 15, 18, 0),
+		"in 2 hours":             at(3, 12, 12, 30),
+		"in 3 days":              at(3, 15, 10, 30),
+		"明天下午3点":                 at(3, 13, 15, 0),
+		"后天上午10点半":               at(3, 14, 10, 30),
+		"周五":                     at(3, 14, DefaultDeadlineHour, 0),
+		"下周一 8:00":               at(3, 17, 8, 0),
+		"下周三":                    at(3, 19, DefaultDeadlineHour, 0),
+		"30分钟后":                  at(3, 12, 11, 0),
+	}
+	for input, want := range cases {
+		got, err := ParseDeadline(input, now)
+		if assert.NoError(t, err, input) {
+			assert.True(t, want.Equal(got), "%s: want %v, got %v", input, want, got)
+		}
+	}
+
+	for _, input := range []string{"", "someday", "tomorrow 25:00"} {
+		_, err := ParseDeadline(input, now)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestRecurrence(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	due := time.Date(2025, 3, 7, 9, 0, 0, 0, loc) // 周五
+	now := time.Date(2025, 3, 12, 10, 0, 0, 0, loc)
+
+	t.Run("间隔规则从截止时间滚动到 now 之后", func(t *testing.T) {
+		cases := map[string]time.Time{
+			"daily":        time.Date(2025, 3, 13, 9, 0, 0, 0, loc),
+			"weekly":       time.Date(2025, 3, 14, 9, 0, 0, 0, loc),
+			"every 2 days": time.Date(2025, 3, 13, 9, 0, 0, 0, loc),
+			"monthly":      time.Date(2025, 4, 7, 9, 0, 0, 0, loc),
+			"每天":           time.Date(2025, 3, 13, 9, 0, 0, 0, loc),
+		}
+		for rule, want := range cases {
+			rec, err := ParseRecurrence(rule)
+			if assert.NoError(t, err, rule) {
+				assert.True(t, want.Equal(rec.Next(due, now)), rule)
+			}
+		}
+
+		rec, err := ParseRecurrence("weekdays")
+		assert.NoError(t, err)
+		friday := time.Date(2025, 3, 14, 9, 0, 0, 0, loc)
+		assert.Equal(t, time.Monday, rec.Next(friday, friday).Weekday())
+	})
+
+	t.Run("按月重复在月末取当月最后一天", func(t *testing.T) {
+		rec, err := ParseRecurrence("monthly")
+		assert.NoError(t, err)
+		jan31 := time.Date(2025, 1, 31, 9, 0, 0, 0, loc)
+		assert.True(t, time.Date(2025, 2, 28, 9, 0, 0, 0, loc).Equal(rec.Next(jan31, jan31)))
+		// 从原来的截止时间计算，跳过短月后回到 31 日
+		assert.True(t, time.Date(2025, 3, 31, 9, 0, 0, 0, loc).Equal(rec.Next(jan31, time.Date(2025, 3, 1, 0, 0, 0, 0, loc))))
+		// 闰年的 2 月
+		assert.True(t, time.Date(2024, 2, 29, 9, 0, 0, 0, loc).Equal(rec.Next(time.Date(2024, 1, 30, 9, 0, 0, 0, loc), jan31.AddDate(-1, 0, 0))))
+
+		rec, err = ParseRecurrence("every 3 months")
+		assert.NoError(t, err)
+		nov30 := time.Date(2024, 11, 30, 9, 0, 0, 0, loc)
+		assert.True(t, time.Date(2025, 2, 28, 9, 0, 0, 0, loc).Equal(rec.Next(nov30, nov30)))
+	})
+
+	t.Run("cron 表达式", func(t *testing.T) {
+		rec, err := ParseRecurrence("30 8 * * 1-5")
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2025, 3, 13, 8, 30, 0, 0, loc).Equal(rec.Next(due, now)))
+
+		rec, err = ParseRecurrence("*/15 * * * *")
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2025, 3, 12, 10, 15, 0, 0, loc).Equal(rec.Next(due, now)))
+
+		rec, err = ParseRecurrence("0 9 1 * 0")
+		assert.NoError(t, err)
+		// 日期和星期都有限制时满足其一即可：3 月 16 日是周日
+		assert.True(t, time.Date(2025, 3, 16, 9, 0, 0, 0, loc).Equal(rec.Next(due, now)))
+	})
+
+	t.Run("无效规则", func(t *testing.T) {
+		for _, rule := range []string{"sometimes", "every 0 days", "60 * * * *", "* * * *"} {
+			_, err := ParseRecurrence(rule)
+			assert.Error(t, err, rule)
+		}
+	})
+}
+
+func TestStorageSchedule(t *testing.T) {
+	s, err := NewStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	now := time.Now()
+	past, soon, later := now.Add(-time.Hour), now.Add(10*time.Minute), now.Add(72*time.Hour)
+	for _, task := range []*Task{
+		{ID: "overdue", Title: "overdue", DueAt: &past, Deadline: past.Format(time.RFC3339)},
+		{ID: "soon", Title: "soon", DueAt: &soon, Deadline: soon.Format(time.RFC3339)},
+		{ID: "later", Title: "later", DueAt: &later, Deadline: later.Format(time.RFC3339), Repeat: "daily"},
+		{ID: "none", Title: "none"},
+	} {
+		assert.NoError(t, s.Add(task))
+	}
+
+	overdue, err := s.List(&ListParams{Due: DueOverdue})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"overdue"}, taskIDs(overdue))
+
+	upcoming, err := s.List(&ListParams{Due: DueUpcoming})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"soon"}, taskIDs(upcoming))
+
+	within := 96
+	upcoming, err = s.List(&ListParams{Due: DueUpcoming, WithinHours: &within})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"soon", "later"}, taskIDs(upcoming))
+
+	// 每个截止时间只提醒一次
+	due := s.DueTasks(now.Add(15 * time.Minute))
+	assert.Equal(t, []string{"overdue", "soon"}, taskIDs(due))
+	assert.NoError(t, s.MarkReminded("overdue", past))
+	assert.Equal(t, []string{"soon"}, taskIDs(s.DueTasks(now.Add(15*time.Minute))))
+
+	// 循环任务完成后滚动到下一次截止时间
+	assert.NoError(t, s.Update(&Task{ID: "later", Completed: true}))
+	task, err := s.Get("later")
+	assert.NoError(t, err)
+	assert.False(t, task.Completed)
+	assert.True(t, later.AddDate(0, 0, 1).Equal(*task.DueAt))
+
+	// 重新加载后数据不变
+	reloaded, err := NewStorage(filepath.Dir(s.filePath))
+	assert.NoError(t, err)
+	task, err = reloaded.Get("later")
+	assert.NoError(t, err)
+	assert.True(t, later.AddDate(0, 0, 1).Equal(*task.DueAt))
+	assert.Equal(t, []string{"soon"}, taskIDs(reloaded.DueTasks(now.Add(15*time.Minute))))
+
+	// 按月重复的 31 日在小月取月末，之后仍回到 31 日
+	year := now.Year() + 1
+	jan31 := time.Date(year, 1, 31, 9, 0, 0, 0, now.Location())
+	assert.NoError(t, s.Add(&Task{ID: "monthly", Title: "monthly", DueAt: &jan31, Deadline: jan31.Format(time.RFC3339), Repeat: "monthly"}))
+	for _, want := range []time.Time{
+		time.Date(year, 3, 0, 9, 0, 0, 0, now.Location()),
+		time.Date(year, 3, 31, 9, 0, 0, 0, now.Location()),
+		time.Date(year, 4, 30, 9, 0, 0, 0, now.Location()),
+		time.Date(year, 5, 31, 9, 0, 0, 0, now.Location()),
+	} {
+		assert.NoError(t, s.Update(&Task{ID: "monthly", Completed: true}))
+		task, err := s.Get("monthly")
+		assert.NoError(t, err)
+		assert.True(t, want.Equal(*task.DueAt), "want %s, got %s", want, task.DueAt)
+	}
+
+	// 修改截止时间后从新的日期重复
+	mid := time.Date(year, 6, 15, 9, 0, 0, 0, now.Location())
+	assert.NoError(t, s.Update(&Task{ID: "monthly", DueAt: &mid, Deadline: mid.Format(time.RFC3339)}))
+	assert.NoError(t, s.Update(&Task{ID: "monthly", Completed: true}))
+	task, err = s.Get("monthly")
+	assert.NoError(t, err)
+	assert.True(t, time.Date(year, 7, 15, 9, 0, 0, 0, now.Location()).Equal(*task.DueAt))
+}
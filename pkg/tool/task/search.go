@@ -0,0 +1,244 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"Eino-example/pkg/vectorstore"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// defaultSearchLimit 是语义搜索没有指定 limit 时返回的数量
+const defaultSearchLimit = 10
+
+// SearchIndex 保存 task 标题、内容和标签的向量，用于语义搜索。
+// task 变更时只丢弃对应的向量，搜索时为缺少向量的 task 批量计算，向量持久化在 embeddings.json 中
+type SearchIndex struct {
+	mu       sync.Mutex
+	path     string
+	embedder embedding.Embedder
+	vectors  map[string]*taskVector
+}
+
+type taskVector struct {
+	Hash   string    `json:"hash"`
+	Vector []float64 `json:"vector"`
+}
+
+func newSearchIndex(path string, embedder embedding.Embedder) (*SearchIndex, error) {
+	idx := &SearchIndex{
+		path:     path,
+		embedder: embedder,
+		vectors:  make(map[string]*taskVector),
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search index: %v", err)
+	}
+	if err := json.Unmarshal(data, &idx.vectors); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal search index: %v", err)
+	}
+	return idx, nil
+}
+
+// searchText 返回参与语义搜索的文本
+func searchText(task *Task) string {
+	return strings.Join([]string{task.Title, task.Content, strings.Join(task.Tags, " ")}, "\n")
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// invalidate 丢弃 task 的向量，下次搜索时重新计算
+func (idx *SearchIndex) invalidate(ids ...string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, id := range ids {
+		delete(idx.vectors, id)
+	}
+}
+
+// scores 返回 tasks 与 query 的余弦相似度，先为缺少向量或内容已变化的 task 计算向量，
+// 计算向量时不持有锁，避免阻塞 task 的变更
+func (idx *SearchIndex) scores(ctx context.Context, query string, tasks []*Task) ([]float64, error) {
+	hashes := make([]string, len(tasks))
+	vectors := make([][]float64, len(tasks))
+	var missing []int
+	var texts []string
+
+	idx.mu.Lock()
+	for i, task := range tasks {
+		text := searchText(task)
+		hashes[i] = textHash(text)
+		if v, ok := idx.vectors[task.ID]; ok && v.Hash == hashes[i] {
+			vectors[i] = v.Vector
+			continue
+		}
+		missing = append(missing, i)
+		texts = append(texts, text)
+	}
+	idx.mu.Unlock()
+
+	embedded, err := idx.embedder.EmbedStrings(ctx, append(texts, query))
+	if err != nil {
+		return nil, fmt.Errorf("embed tasks failed: %v", err)
+	}
+	if len(embedded) != len(texts)+1 {
+		return nil, fmt.Errorf("invalid embedding result, expected %d vectors, got %d", len(texts)+1, len(embedded))
+	}
+
+	if len(missing) > 0 {
+		idx.mu.Lock()
+		for j, i := range missing {
+			vectors[i] = embedded[j]
+			idx.vectors[tasks[i].ID] = &taskVector{Hash: hashes[i], Vector: embedded[j]}
+		}
+		err := idx.save()
+		idx.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	queryVector := embedded[len(texts)]
+	scores := make([]float64, len(tasks))
+	for i := range tasks {
+		scores[i] = vectorstore.CosineSimilarity(queryVector, vectors[i])
+	}
+	return scores, nil
+}
+
+// save 原子地写入向量文件，调用方需持有锁
+func (idx *SearchIndex) save() error {
+	data, err := json.Marshal(idx.vectors)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %v", err)
+	}
+	tmpFile := idx.path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write search index: %v", err)
+	}
+	if err := os.Rename(tmpFile, idx.path); err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("failed to rename search index: %v", err)
+	}
+	return nil
+}
+
+// localScores 使用本地的特征哈希向量计算相似度，不需要网络，但只能匹配相同的词
+func localScores(ctx context.Context, query string, tasks []*Task) []float64 {
+	texts := make([]string, 0, len(tasks)+1)
+	for _, task := range tasks {
+		texts = append(texts, searchText(task))
+	}
+	vectors, _ := vectorstore.NewHashingEmbedder(0).EmbedStrings(ctx, append(texts, query))
+
+	queryVector := vectors[len(tasks)]
+	scores := make([]float64, len(tasks))
+	for i := range tasks {
+		scores[i] = vectorstore.CosineSimilarity(queryVector, vectors[i])
+	}
+	return scores
+}
+
+// EnableSearch 用 embedder 为 task 开启语义搜索，向量保存在数据目录的 embeddings.json 中。
+// 重复调用时保留已有的索引
+func (s *Storage) EnableSearch(embedder embedding.Embedder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.index != nil {
+		return nil
+	}
+	idx, err := newSearchIndex(filepath.Join(filepath.Dir(s.filePath), "embeddings.json"), embedder)
+	if err != nil {
+		return err
+	}
+	s.index = idx
+	return nil
+}
+
+// Search 按与 params.Query 的语义相似度排序返回 task，其余筛选条件与 List 相同。
+// 没有开启语义搜索或 embedder 调用失败时使用本地的特征哈希向量
+func (s *Storage) Search(ctx context.Context, params *ListParams) ([]*Task, error) {
+	if strings.TrimSpace(params.Query) == "" {
+		return s.List(params)
+	}
+
+	filter := *params
+	filter.Query, filter.Limit, filter.Offset = "", nil, 0
+	candidates, err := s.List(&filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return []*Task{}, nil
+	}
+
+	s.mu.RLock()
+	idx := s.index
+	s.mu.RUnlock()
+
+	var scores []float64
+	if idx != nil {
+		scores, err = idx.scores(ctx, params.Query, candidates)
+		if err != nil {
+			log.Printf("[task] semantic search failed, fall back to local search, err=%v", err)
+		}
+	}
+	if scores == nil {
+		scores = localScores(ctx, params.Query, candidates)
+	}
+
+	order := make([]int, 0, len(candidates))
+	for i := range candidates {
+		if scores[i] > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	tasks := make([]*Task, 0, len(order))
+	for _, i := range order {
+		tasks = append(tasks, candidates[i])
+	}
+
+	limit := defaultSearchLimit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	return page(tasks, params.Offset, limit), nil
+}
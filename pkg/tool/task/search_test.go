@@ -0,0 +1,126 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/stretchr/testify/assert"
+)
+
+// topicEmbedder 将同一主题的关键词映射到同一维度，模拟语义相近的文本，并记录计算过的文本
+type topicEmbedder struct {
+	topics   [][]string
+	embedded []string
+	err      error
+}
+
+func (e *topicEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	vectors := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		e.embedded = append(e.embedded, text)
+		v := make([]float64, len(e.topics))
+		for i, words := range e.topics {
+			for _, w := range words {
+				if strings.Contains(strings.ToLower(text), w) {
+					v[i]++
+				}
+			}
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+func TestStorageSearch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewStorage(dir)
+	assert.NoError(t, err)
+
+	embedder := &topicEmbedder{topics: [][]string{
+		{"es", "elasticsearch", "index", "migration"},
+		{"milk", "grocery", "shopping"},
+		{"cluster", "budget"},
+	}}
+	assert.NoError(t, s.EnableSearch(embedder))
+
+	for _, task := range []*Task{
+		{ID: "1", Title: "Move search cluster to Elasticsearch 8", Content: "reindex everything"},
+		{ID: "2", Title: "Buy milk", Tags: []string{"shopping"}},
+		{ID: "3", Title: "ES migration runbook", Content: "index aliases", Completed: true},
+		{ID: "4", Title: "Quarterly review"},
+	} {
+		assert.NoError(t, s.Add(task))
+	}
+
+	ids := func(params *ListParams) []string {
+		tasks, err := s.Search(ctx, params)
+		assert.NoError(t, err)
+		return taskIDs(tasks)
+	}
+
+	t.Run("按语义相似度排序", func(t *testing.T) {
+		assert.Equal(t, []string{"3", "1"}, ids(&ListParams{Query: "anything about the ES migration"}))
+		assert.Len(t, embedder.embedded, 5, "4 个 task 和 1 个查询")
+
+		done := false
+		assert.Equal(t, []string{"1"}, ids(&ListParams{Query: "elasticsearch", IsDone: &done}))
+
+		// 模型给出的负数不会越界
+		negative, one := -1, 1
+		assert.Equal(t, []string{}, ids(&ListParams{Query: "elasticsearch", Limit: &negative}))
+		assert.Equal(t, []string{"3"}, ids(&ListParams{Query: "anything about the ES migration", Offset: -1, Limit: &one}))
+	})
+
+	t.Run("只为新增和修改的 task 计算向量", func(t *testing.T) {
+		embedder.embedded = nil
+		assert.NoError(t, s.Update(&Task{ID: "4", Content: "grocery budget"}))
+		assert.NoError(t, s.Delete("2"))
+		assert.NoError(t, s.Add(&Task{ID: "5", Title: "Grocery list"}))
+
+		assert.Equal(t, []string{"5", "4"}, ids(&ListParams{Query: "shopping"}))
+		assert.Len(t, embedder.embedded, 3, "task 4、task 5 和查询")
+
+		// 向量持久化后重新打开不需要重新计算
+		reopened, err := NewStorage(dir)
+		assert.NoError(t, err)
+		reopenedEmbedder := &topicEmbedder{topics: embedder.topics}
+		assert.NoError(t, reopened.EnableSearch(reopenedEmbedder))
+		_, err = reopened.Search(ctx, &ListParams{Query: "shopping"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"shopping"}, reopenedEmbedder.embedded)
+	})
+
+	t.Run("embedder 不可用时使用本地向量", func(t *testing.T) {
+		embedder.err = errors.New("network unreachable")
+		assert.Equal(t, []string{"5"}, ids(&ListParams{Query: "grocery list"})[:1])
+	})
+
+	t.Run("没有查询时与 List 相同", func(t *testing.T) {
+		tasks, err := s.List(&ListParams{})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, taskIDs(tasks), ids(&ListParams{}))
+	})
+}
@@ -18,6 +18,7 @@ package task
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -26,31 +27,26 @@ import (
 	"strings"
 	"sync"
 	"time"
-)
 
-var defaultStorage *Storage
+	"github.com/google/uuid"
+)
 
+// Storage 将 task 的变更以追加写的事件日志保存在 events.jsonl 中，
+// 内存中保存重放日志得到的 task 和事件，追加的事件数达到 compactEvery 后压缩日志
 type Storage struct {
 	filePath string
 	mu       sync.RWMutex
 	cache    map[string]*Task
-	dirty    bool
-}
-
-func GetDefaultStorage() *Storage {
-	if defaultStorage == nil {
-		InitDefaultStorage("./data/task")
-	}
-	return defaultStorage
-}
-
-func InitDefaultStorage(dataDir string) error {
-	s, err := NewStorage(dataDir)
-	if err != nil {
-		return err
-	}
-	defaultStorage = s
-	return nil
+	events   []*Event
+	seq      int64
+	// compacted 是压缩时丢弃了事件的最后一次变更，它及更早的变更不能撤销
+	compacted int64
+	// index 是可选的语义搜索索引
+	index *SearchIndex
+
+	compactEvery int
+	historyLimit int
+	appended     int
 }
 
 func NewStorage(dataDir string) (*Storage, error) {
@@ -58,14 +54,24 @@ func NewStorage(dataDir string) (*Storage, error) {
 		return nil, fmt.Errorf("failed to create data directory: %v", err)
 	}
 	s := &Storage{
-		filePath: filepath.Join(dataDir, "tasks.jsonl"),
-		cache:    make(map[string]*Task),
+		filePath:     filepath.Join(dataDir, "events.jsonl"),
+		cache:        make(map[string]*Task),
+		compactEvery: defaultCompactEvery,
+		historyLimit: defaultHistoryLimit,
 	}
 
 	if err := s.loadFromDisk(); err != nil {
 		return nil, fmt.Errorf("failed to load from disk: %v", err)
 	}
 
+	if err := s.migrateLegacy(filepath.Join(dataDir, "tasks.jsonl")); err != nil {
+		return nil, fmt.Errorf("failed to migrate tasks: %v", err)
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, fmt.Errorf("failed to compact events: %v", err)
+	}
+
 	return s, nil
 }
 
@@ -77,58 +83,135 @@ func (s *Storage) loadFromDisk() error {
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("failed to unmarshal event: %v", err)
+		}
+		if event.Type == EventCompacted {
+			s.compacted = event.Change
+			continue
+		}
+		s.apply(&event)
+		s.events = append(s.events, &event)
+		if event.Seq > s.seq {
+			s.seq = event.Seq
+		}
+	}
+
+	return scanner.Err()
+}
+
+// migrateLegacy 将旧版 tasks.jsonl 中未删除的 task 导入为 migrated 事件，导入后重命名旧文件。
+// 导入作为事件日志的初始状态，不能撤销
+func (s *Storage) migrateLegacy(path string) error {
+	if len(s.events) > 0 {
+		return nil
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	var events []*Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
 	for scanner.Scan() {
 		var task Task
 		if err := json.Unmarshal(scanner.Bytes(), &task); err != nil {
 			return fmt.Errorf("failed to unmarshal task: %v", err)
 		}
-		s.cache[task.ID] = &task
+		if task.IsDeleted {
+			continue
+		}
+		event := &Event{Type: EventMigrated, TaskID: task.ID, Task: &task}
+		created, err := time.Parse(time.RFC3339, task.CreatedAt)
+		if err == nil {
+			event.At = created
+		} else {
+			created = time.Now()
+		}
+		// 兼容旧数据：只有 Deadline 字符串的 task 补上解析后的截止时间，
+		// "明天" 这样的相对时间相对于 task 的创建时间解析
+		if task.DueAt == nil && task.Deadline != "" {
+			if due, err := ParseDeadline(task.Deadline, created); err == nil {
+				task.DueAt = &due
+			}
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
 	}
 
-	return scanner.Err()
+	change := s.seq + 1
+	for _, event := range events {
+		s.seq++
+		event.Seq, event.Change = s.seq, change
+		s.apply(event)
+		s.events = append(s.events, event)
+	}
+	if err := s.rewrite(s.events); err != nil {
+		return err
+	}
+	file.Close()
+	return os.Rename(path, path+".migrated")
 }
 
 func (s *Storage) Add(task *Task) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	task.CreatedAt = time.Now().Format(time.RFC3339)
-	task.IsDeleted = false
-	s.cache[task.ID] = task
-
-	// 直接追加到文件末尾
-	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+	if task.Repeat == RepeatNone {
+		task.Repeat = ""
 	}
-	defer file.Close()
-
-	data, err := json.Marshal(task)
-	if err != nil {
-		return fmt.Errorf("failed to marshal task: %v", err)
+	if task.ParentID == NoParent {
+		task.ParentID = ""
 	}
-
-	if _, err := file.Write(append(data, '\n')); err != nil {
-		return fmt.Errorf("failed to write task: %v", err)
+	if err := s.checkParent(task.ID, task.ParentID); err != nil {
+		return err
 	}
 
-	if err := file.Sync(); err != nil {
-		return fmt.Errorf("failed to sync file: %v", err)
+	if _, exists := s.cache[task.ID]; exists {
+		return fmt.Errorf("task already exists: %s", task.ID)
 	}
 
-	return nil
+	now := time.Now()
+	task.CreatedAt = now.Format(time.RFC3339)
+	task.UpdatedAt = task.CreatedAt
+	task.advance(now)
+
+	return s.commit(s.record(EventCreated, task.ID, task))
+}
+
+func (s *Storage) Get(id string) (*Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, exists := s.cache[id]
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+	return task, nil
 }
 
 func (s *Storage) List(params *ListParams) ([]*Task, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := time.Now()
+	within := time.Duration(defaultUpcomingHours) * time.Hour
+	if params.WithinHours != nil {
+		within = time.Duration(*params.WithinHours) * time.Hour
+	}
+
 	var activeTasks, completedTasks []*Task
 	for _, task := range s.cache {
-		if task.IsDeleted {
-			continue
-		}
-
 		if params.Query != "" && !contains(task.Title, params.Query) && !contains(task.Content, params.Query) {
 			continue
 		}
@@ -139,6 +222,27 @@ func (s *Storage) List(params *ListParams) ([]*Task, error) {
 			}
 		}
 
+		if params.Due != "" {
+			if task.Completed || task.DueAt == nil {
+				continue
+			}
+			overdue := task.DueAt.Before(now)
+			if params.Due == DueOverdue && !overdue {
+				continue
+			}
+			if params.Due == DueUpcoming && (overdue || task.DueAt.After(now.Add(within))) {
+				continue
+			}
+		}
+
+		if !matchTags(task, params.Tags) || !matchPriority(task, params.Priorities) {
+			continue
+		}
+
+		if params.ParentID != nil && task.ParentID != *params.ParentID {
+			continue
+		}
+
 		if task.Completed {
 			completedTasks = append(completedTasks, task)
 		} else {
@@ -146,22 +250,105 @@ func (s *Storage) List(params *ListParams) ([]*Task, error) {
 		}
 	}
 
-	// 按创建时间排序（最新的在前面）
-	sort.Slice(activeTasks, func(i, j int) bool {
-		return activeTasks[i].CreatedAt > activeTasks[j].CreatedAt
-	})
-	sort.Slice(completedTasks, func(i, j int) bool {
-		return completedTasks[i].CreatedAt > completedTasks[j].CreatedAt
-	})
+	// 默认按创建时间排序（最新的在前面），按截止时间筛选时默认最早截止的在前面
+	sortBy := params.SortBy
+	if sortBy == "" && params.Due != "" {
+		sortBy = SortDeadline
+	}
+	sortTasks(activeTasks, sortBy)
+	sortTasks(completedTasks, sortBy)
 
 	// 合并列表：未完成的在前，已完成的在后
 	tasks := append(activeTasks, completedTasks...)
 
-	if params.Limit != nil && len(tasks) > *params.Limit {
-		tasks = tasks[:*params.Limit]
+	limit := len(tasks)
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	return page(tasks, params.Offset, limit), nil
+}
+
+// page 返回跳过 offset 个之后的至多 limit 个 task，offset 和 limit 为负数时视为 0
+func page(tasks []*Task, offset, limit int) []*Task {
+	offset, limit = max(offset, 0), max(limit, 0)
+	if offset >= len(tasks) {
+		return []*Task{}
 	}
+	tasks = tasks[offset:]
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks
+}
+
+// sortTasks 按 key 排序，相同时按创建时间倒序
+func sortTasks(tasks []*Task, key SortKey) {
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		switch key {
+		case SortDeadline:
+			// 没有截止时间的排在最后
+			if (a.DueAt == nil) != (b.DueAt == nil) {
+				return a.DueAt != nil
+			}
+			if a.DueAt != nil && !a.DueAt.Equal(*b.DueAt) {
+				return a.DueAt.Before(*b.DueAt)
+			}
+		case SortPriority:
+			if a.Priority.rank() != b.Priority.rank() {
+				return a.Priority.rank() > b.Priority.rank()
+			}
+		case SortUpdated:
+			if a.updatedAt() != b.updatedAt() {
+				return a.updatedAt() > b.updatedAt()
+			}
+		}
+		return a.CreatedAt > b.CreatedAt
+	})
+}
+
+// updatedAt 返回最后更新时间，旧数据没有 UpdatedAt 时使用创建时间
+func (t *Task) updatedAt() string {
+	if t.UpdatedAt != "" {
+		return t.UpdatedAt
+	}
+	return t.CreatedAt
+}
+
+func matchTags(task *Task, tags []string) bool {
+	for _, tag := range tags {
+		if !hasTag(task.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
 
-	return tasks, nil
+func matchPriority(task *Task, priorities []Priority) bool {
+	if len(priorities) == 0 {
+		return true
+	}
+	for _, p := range priorities {
+		if p.rank() == task.Priority.rank() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkParent 检查 parentID 是存在的 task，且不是 id 本身或 id 的子任务
+func (s *Storage) checkParent(id, parentID string) error {
+	for p := parentID; p != ""; {
+		if p == id {
+			return fmt.Errorf("task %s cannot be a subtask of itself", id)
+		}
+		parent, exists := s.cache[p]
+		if !exists {
+			return fmt.Errorf("parent task not found: %s", p)
+		}
+		p = parent.ParentID
+	}
+	return nil
 }
 
 func (s *Storage) Update(task *Task) error {
@@ -169,7 +356,7 @@ func (s *Storage) Update(task *Task) error {
 	defer s.mu.Unlock()
 
 	existing, exists := s.cache[task.ID]
-	if !exists || existing.IsDeleted {
+	if !exists {
 		return fmt.Errorf("task not found: %s", task.ID)
 	}
 
@@ -183,39 +370,212 @@ func (s *Storage) Update(task *Task) error {
 	}
 	if task.Deadline != "" {
 		updated.Deadline = task.Deadline
+		updated.DueAt = task.DueAt
+	}
+	if task.Repeat == RepeatNone {
+		updated.Repeat = ""
+	} else if task.Repeat != "" {
+		updated.Repeat = task.Repeat
+	}
+	if task.Priority != "" {
+		updated.Priority = task.Priority
+	}
+	// Tags 为 nil 时不更新，空列表表示清空
+	if task.Tags != nil {
+		updated.Tags = task.Tags
+	}
+	if task.ParentID == NoParent {
+		updated.ParentID = ""
+	} else if task.ParentID != "" {
+		if err := s.checkParent(task.ID, task.ParentID); err != nil {
+			return err
+		}
+		updated.ParentID = task.ParentID
 	}
 	// Completed 字段需要特殊处理，因为它是布尔值
+	eventType := EventUpdated
 	if task.Completed != existing.Completed {
 		updated.Completed = task.Completed
+		if task.Completed {
+			eventType = EventCompleted
+		}
 	}
+	// 循环任务完成后滚动到下一次截止时间
+	now := time.Now()
+	updated.advance(now)
+	updated.UpdatedAt = now.Format(time.RFC3339)
 
-	s.cache[task.ID] = &updated
-	s.dirty = true
+	return s.commit(s.record(eventType, task.ID, &updated))
+}
 
-	return s.syncToDisk()
+// DueTasks returns copies of the unfinished tasks due no later than before that
+// have not been reminded of their current deadline, earliest first.
+func (s *Storage) DueTasks(before time.Time) []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tasks []*Task
+	for _, task := range s.cache {
+		if task.Completed || task.DueAt == nil || task.DueAt.After(before) {
+			continue
+		}
+		if task.RemindedFor != nil && task.RemindedFor.Equal(*task.DueAt) {
+			continue
+		}
+		t := *task
+		tasks = append(tasks, &t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].DueAt.Before(*tasks[j].DueAt)
+	})
+	return tasks
 }
 
-func (s *Storage) Delete(id string) error {
+// MarkReminded records that the task has been reminded of the deadline due.
+func (s *Storage) MarkReminded(id string, due time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	task, exists := s.cache[id]
-	if !exists || task.IsDeleted {
+	existing, exists := s.cache[id]
+	if !exists {
 		return fmt.Errorf("task not found: %s", id)
 	}
 
-	// 标记删除
-	task.IsDeleted = true
-	s.dirty = true
+	updated := *existing
+	updated.RemindedFor = &due
 
-	return s.syncToDisk()
+	return s.commit(s.record(EventReminded, id, &updated))
 }
 
-func (s *Storage) syncToDisk() error {
-	if !s.dirty {
-		return nil
+// Import 按 ID 合并导入的 task，作为一次变更：ID 已存在的更新，ID 不存在或为空的新建，
+// 内容没有变化的跳过。父任务不存在或形成环时导入为顶层 task。返回新建和更新后的 task
+func (s *Storage) Import(tasks []*Task) ([]*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	merged := make(map[string]*Task, len(tasks))
+	order := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		t := clone(task)
+		if t.ID == "" {
+			t.ID = uuid.New().String()
+		}
+		if _, exists := merged[t.ID]; !exists {
+			order = append(order, t.ID)
+		}
+		merged[t.ID] = t
+	}
+
+	lookup := func(id string) *Task {
+		if t, exists := merged[id]; exists {
+			return t
+		}
+		return s.cache[id]
+	}
+	for _, id := range order {
+		t := merged[id]
+		if t.ParentID != "" && (lookup(t.ParentID) == nil || hasParentCycle(id, lookup)) {
+			t.ParentID = ""
+		}
+	}
+
+	var events []*Event
+	var imported []*Task
+	for _, id := range order {
+		t := merged[id]
+		existing, exists := s.cache[id]
+		if !exists {
+			t.CreatedAt = now.Format(time.RFC3339)
+			t.UpdatedAt = t.CreatedAt
+			t.advance(now)
+			events = append(events, s.record(EventCreated, id, t))
+			imported = append(imported, t)
+			continue
+		}
+
+		updated := clone(existing)
+		updated.Title, updated.Content, updated.ParentID = t.Title, t.Content, t.ParentID
+		updated.Deadline, updated.DueAt, updated.Repeat = t.Deadline, t.DueAt, t.Repeat
+		if t.Priority != "" {
+			updated.Priority = t.Priority
+		}
+		if t.Tags != nil {
+			updated.Tags = t.Tags
+		}
+		eventType := EventUpdated
+		if t.Completed && !existing.Completed {
+			eventType = EventCompleted
+		}
+		updated.Completed = t.Completed
+		updated.advance(now)
+		if sameTask(existing, updated) {
+			continue
+		}
+		updated.UpdatedAt = now.Format(time.RFC3339)
+		events = append(events, s.record(eventType, id, updated))
+		imported = append(imported, updated)
+	}
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+	if err := s.commit(events...); err != nil {
+		return nil, err
 	}
+	return imported, nil
+}
+
+// hasParentCycle 判断沿着父任务向上查找是否会回到 id 或陷入环
+func hasParentCycle(id string, lookup func(string) *Task) bool {
+	seen := map[string]bool{id: true}
+	for t := lookup(id); t != nil && t.ParentID != ""; t = lookup(t.ParentID) {
+		if seen[t.ParentID] {
+			return true
+		}
+		seen[t.ParentID] = true
+	}
+	return false
+}
+
+// sameTask 比较两个 task 的内容，忽略更新时间
+func sameTask(a, b *Task) bool {
+	x, y := *a, *b
+	x.UpdatedAt, y.UpdatedAt = "", ""
+	dx, _ := json.Marshal(&x)
+	dy, _ := json.Marshal(&y)
+	return bytes.Equal(dx, dy)
+}
+
+func (s *Storage) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	if _, exists := s.cache[id]; !exists {
+		return fmt.Errorf("task not found: %s", id)
+	}
+
+	// 子任务一并删除，作为同一次变更
+	var events []*Event
+	for _, taskID := range s.descendants(id) {
+		events = append(events, s.record(EventDeleted, taskID, nil))
+	}
+	return s.commit(events...)
+}
+
+// descendants 返回 id 及其所有子任务的 id
+func (s *Storage) descendants(id string) []string {
+	ids := []string{id}
+	for _, task := range s.cache {
+		if task.ParentID == id {
+			ids = append(ids, s.descendants(task.ID)...)
+		}
+	}
+	return ids
+}
+
+// rewrite 用 events 原子地替换事件日志文件
+func (s *Storage) rewrite(events []*Event) error {
 	// 创建临时文件
 	tmpFile := s.filePath + ".tmp"
 	file, err := os.Create(tmpFile)
@@ -225,16 +585,16 @@ func (s *Storage) syncToDisk() error {
 	defer file.Close()
 
 	// 写入数据到临时文件
-	for _, task := range s.cache {
-		data, err := json.Marshal(task)
+	for _, event := range events {
+		data, err := json.Marshal(event)
 		if err != nil {
 			os.Remove(tmpFile) // 清理临时文件
-			return fmt.Errorf("failed to marshal task: %v", err)
+			return fmt.Errorf("failed to marshal event: %v", err)
 		}
 
 		if _, err := file.Write(append(data, '\n')); err != nil {
 			os.Remove(tmpFile) // 清理临时文件
-			return fmt.Errorf("failed to write task: %v", err)
+			return fmt.Errorf("failed to write event: %v", err)
 		}
 	}
 
@@ -271,7 +631,6 @@ func (s *Storage) syncToDisk() error {
 	// 删除备份文件
 	os.Remove(s.filePath + ".bak")
 
-	s.dirty = false
 	return nil
 }
 
@@ -0,0 +1,307 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func taskIDs(tasks []*Task) []string {
+	ids := []string{}
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestStorageList(t *testing.T) {
+	s, err := NewStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	base := time.Date(2025, 3, 12, 10, 0, 0, 0, time.UTC)
+	due := func(hours int) *time.Time {
+		d := base.Add(time.Duration(hours) * time.Hour)
+		return &d
+	}
+	for i, task := range []*Task{
+		{ID: "a", Title: "write report", Priority: PriorityHigh, Tags: []string{"work"}, DueAt: due(48)},
+		{ID: "b", Title: "buy milk", Priority: PriorityLow, Tags: []string{"home", "shopping"}, DueAt: due(2)},
+		{ID: "c", Title: "fix bug", Priority: PriorityUrgent, Tags: []string{"work", "urgent"}},
+		{ID: "d", Title: "old task"},
+		{ID: "a1", Title: "collect data", ParentID: "a", Tags: []string{"work"}},
+	} {
+		assert.NoError(t, s.Add(task))
+		// 固定创建和更新时间，便于断言排序
+		s.cache[task.ID].CreatedAt = base.Add(time.Duration(i) * time.Minute).Format(time.RFC3339)
+		s.cache[task.ID].UpdatedAt = base.Add(time.Duration(10-i) * time.Minute).Format(time.RFC3339)
+	}
+	s.cache["d"].Completed = true
+
+	list := func(params *ListParams) []string {
+		tasks, err := s.List(params)
+		assert.NoError(t, err)
+		return taskIDs(tasks)
+	}
+
+	t.Run("默认按创建时间倒序，未完成的在前", func(t *testing.T) {
+		assert.Equal(t, []string{"a1", "c", "b", "a", "d"}, list(&ListParams{}))
+	})
+
+	t.Run("排序", func(t *testing.T) {
+		// 未设置优先级视为 medium
+		assert.Equal(t, []string{"c", "a", "a1", "b", "d"}, list(&ListParams{SortBy: SortPriority}))
+		assert.Equal(t, []string{"b", "a", "a1", "c", "d"}, list(&ListParams{SortBy: SortDeadline}))
+		assert.Equal(t, []string{"a", "b", "c", "a1", "d"}, list(&ListParams{SortBy: SortUpdated}))
+	})
+
+	t.Run("按标签和优先级筛选", func(t *testing.T) {
+		assert.Equal(t, []string{"a1", "c", "a"}, list(&ListParams{Tags: []string{"Work"}}))
+		assert.Equal(t, []string{"c"}, list(&ListParams{Tags: []string{"work", "urgent"}}))
+		assert.Equal(t, []string{"c", "a"}, list(&ListParams{Priorities: []Priority{PriorityHigh, PriorityUrgent}}))
+		assert.Equal(t, []string{"a1", "d"}, list(&ListParams{Priorities: []Priority{PriorityMedium}}))
+	})
+
+	t.Run("子任务", func(t *testing.T) {
+		parent, top := "a", ""
+		assert.Equal(t, []string{"a1"}, list(&ListParams{ParentID: &parent}))
+		assert.Equal(t, []string{"c", "b", "a", "d"}, list(&ListParams{ParentID: &top}))
+
+		assert.Error(t, s.Add(&Task{ID: "x", Title: "orphan", ParentID: "missing"}))
+		assert.Error(t, s.Update(&Task{ID: "a", ParentID: "a1"}), "不能形成环")
+		assert.NoError(t, s.Update(&Task{ID: "a1", ParentID: NoParent}))
+		assert.Equal(t, []string{}, list(&ListParams{ParentID: &parent}))
+		assert.NoError(t, s.Update(&Task{ID: "a1", ParentID: "a"}))
+
+		// 删除父任务时子任务一并删除
+		assert.NoError(t, s.Delete("a"))
+		assert.Equal(t, []string{"c", "b", "d"}, list(&ListParams{}))
+	})
+
+	t.Run("分页", func(t *testing.T) {
+		limit := 1
+		assert.Equal(t, []string{"b"}, list(&ListParams{Offset: 1, Limit: &limit}))
+		assert.Equal(t, []string{}, list(&ListParams{Offset: 10}))
+
+		// 模型给出的负数不会越界
+		negative := -1
+		assert.Equal(t, []string{}, list(&ListParams{Limit: &negative}))
+		assert.Equal(t, []string{"c"}, list(&ListParams{Offset: -1, Limit: &limit}))
+	})
+}
+
+func TestStorageLegacyTasks(t *testing.T) {
+	dir := t.TempDir()
+
+	// 旧版 tasks.jsonl 导入为事件日志，已删除的 task 不再保留
+	legacy := `{"id":"1","title":"legacy","content":"","completed":false,"deadline":"2025-03-20T18:00","is_deleted":false,"created_at":"2025-03-01T10:00:00Z"}` + "\n" +
+		`{"id":"2","title":"deleted","content":"","completed":false,"deadline":"","is_deleted":true,"created_at":"2025-03-01T10:00:00Z"}` + "\n" +
+		`{"id":"3","title":"relative","content":"","completed":false,"deadline":"in 3 days","is_deleted":false,"created_at":"2025-03-01T10:00:00Z"}` + "\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "tasks.jsonl"), []byte(legacy), 0644))
+	s, err := NewStorage(dir)
+	assert.NoError(t, err)
+
+	task, err := s.Get("1")
+	assert.NoError(t, err)
+	assert.NotNil(t, task.DueAt)
+	_, err = s.Get("2")
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "tasks.jsonl.migrated"))
+	assert.NoError(t, err)
+	assert.Equal(t, Priority(""), task.Priority)
+	assert.Equal(t, "2025-03-01T10:00:00Z", task.updatedAt())
+
+	// 相对时间的截止时间相对于创建时间解析
+	relative, err := s.Get("3")
+	assert.NoError(t, err)
+	if assert.NotNil(t, relative.DueAt) {
+		assert.True(t, relative.DueAt.Equal(time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)))
+	}
+
+	// 导入是不可撤销的初始状态
+	history, err := s.History("1")
+	assert.NoError(t, err)
+	assert.Equal(t, EventMigrated, history[0].Type)
+	_, err = s.Undo()
+	assert.Error(t, err)
+
+	assert.NoError(t, s.Update(&Task{ID: "1", Priority: PriorityHigh, Tags: []string{"legacy"}}))
+	s, err = NewStorage(dir)
+	assert.NoError(t, err)
+	task, err = s.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, PriorityHigh, task.Priority)
+	assert.Equal(t, []string{"legacy"}, task.Tags)
+}
+
+func TestStorageEvents(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStorage(dir)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Add(&Task{ID: "p", Title: "parent"}))
+	assert.NoError(t, s.Add(&Task{ID: "c", Title: "child", ParentID: "p"}))
+	assert.NoError(t, s.Update(&Task{ID: "p", Title: "renamed"}))
+	assert.NoError(t, s.Update(&Task{ID: "p", Completed: true}))
+
+	history, err := s.History("p")
+	assert.NoError(t, err)
+	var types []EventType
+	for _, event := range history {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []EventType{EventCreated, EventUpdated, EventCompleted}, types)
+	assert.Equal(t, "parent", history[1].Prev.Title)
+	assert.Equal(t, "renamed", history[1].Task.Title)
+
+	t.Run("撤销删除时子任务一起恢复", func(t *testing.T) {
+		assert.NoError(t, s.Delete("p"))
+		_, err := s.Get("c")
+		assert.Error(t, err)
+
+		events, err := s.Undo()
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		_, err = s.Get("c")
+		assert.NoError(t, err)
+		task, err := s.Get("p")
+		assert.NoError(t, err)
+		assert.True(t, task.Completed)
+	})
+
+	t.Run("连续撤销依次回退更早的变更", func(t *testing.T) {
+		_, err := s.Undo()
+		assert.NoError(t, err)
+		task, _ := s.Get("p")
+		assert.False(t, task.Completed)
+
+		_, err = s.Undo()
+		assert.NoError(t, err)
+		task, _ = s.Get("p")
+		assert.Equal(t, "parent", task.Title)
+
+		// 撤销创建会移除 task
+		_, err = s.Undo()
+		assert.NoError(t, err)
+		_, err = s.Get("c")
+		assert.Error(t, err)
+	})
+
+	t.Run("重新加载后状态一致", func(t *testing.T) {
+		reloaded, err := NewStorage(dir)
+		assert.NoError(t, err)
+		tasks, err := reloaded.List(&ListParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"p"}, taskIDs(tasks))
+		task, _ := reloaded.Get("p")
+		assert.Equal(t, "parent", task.Title)
+	})
+
+	t.Run("压缩后仍可以连续撤销最近的变更", func(t *testing.T) {
+		assert.NoError(t, s.Add(&Task{ID: "x", Title: "removed"}))
+		assert.NoError(t, s.Delete("x"))
+		assert.NoError(t, s.Add(&Task{ID: "y", Title: "removed too"}))
+		assert.NoError(t, s.Delete("y"))
+		assert.NoError(t, s.compact())
+		_, err := s.History("x")
+		assert.NoError(t, err)
+
+		_, err = s.Undo()
+		assert.NoError(t, err)
+		reloaded, err := NewStorage(dir)
+		assert.NoError(t, err)
+		task, err := reloaded.Get("y")
+		assert.NoError(t, err)
+		assert.Equal(t, "removed too", task.Title)
+		task, _ = reloaded.Get("p")
+		assert.Equal(t, "parent", task.Title)
+
+		// 第二次撤销移除 y，第三次恢复更早删除的 x
+		_, err = s.Undo()
+		assert.NoError(t, err)
+		_, err = s.Get("y")
+		assert.Error(t, err)
+		_, err = s.Undo()
+		assert.NoError(t, err)
+		task, err = s.Get("x")
+		assert.NoError(t, err)
+		assert.Equal(t, "removed", task.Title)
+		_, err = s.Undo()
+		assert.NoError(t, err)
+		_, err = s.Get("x")
+		assert.Error(t, err)
+	})
+
+	t.Run("不能撤销压缩丢弃了事件的变更", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewStorage(dir)
+		assert.NoError(t, err)
+		s.historyLimit = 2
+		assert.NoError(t, s.Add(&Task{ID: "a", Title: "a"}))
+		assert.NoError(t, s.Add(&Task{ID: "d", Title: "d"}))
+		assert.NoError(t, s.Delete("d"))
+		assert.NoError(t, s.Add(&Task{ID: "b", Title: "b"}))
+		assert.NoError(t, s.Update(&Task{ID: "b", Content: "v1"}))
+		assert.NoError(t, s.compact())
+		// 压缩会丢弃较早删除的 task 的事件
+		_, err = s.History("d")
+		assert.Error(t, err)
+
+		_, err = s.Undo()
+		assert.NoError(t, err)
+		task, _ := s.Get("b")
+		assert.Empty(t, task.Content)
+		_, err = s.Undo()
+		assert.NoError(t, err)
+		_, err = s.Get("b")
+		assert.Error(t, err)
+
+		// 删除 d 的事件已被丢弃，撤销报错而不是跳过它回退创建 a
+		reloaded, err := NewStorage(dir)
+		assert.NoError(t, err)
+		for _, storage := range []*Storage{s, reloaded} {
+			_, err = storage.Undo()
+			assert.Error(t, err)
+			_, err = storage.Get("a")
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("压缩只保留最近的历史", func(t *testing.T) {
+		s.historyLimit, s.compactEvery, s.appended = 3, 4, 0
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, s.Update(&Task{ID: "p", Content: fmt.Sprintf("v%d", i)}))
+		}
+		history, err := s.History("p")
+		assert.NoError(t, err)
+		assert.Len(t, history, 3)
+		assert.Equal(t, "v3", history[2].Task.Content)
+
+		reloaded, err := NewStorage(dir)
+		assert.NoError(t, err)
+		task, _ := reloaded.Get("p")
+		assert.Equal(t, "v3", task.Content)
+	})
+
+	_, err = NewStorage(dir)
+	assert.NoError(t, err)
+}
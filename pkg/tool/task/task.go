@@ -19,7 +19,10 @@ package task
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/cloudwego/eino/components/embedding"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
 	"github.com/google/uuid"
@@ -28,11 +31,18 @@ import (
 type Action string
 
 const (
-	ActionAdd    Action = "add"
-	ActionGet    Action = "get"
-	ActionUpdate Action = "update"
-	ActionDelete Action = "delete"
-	ActionList   Action = "list"
+	ActionAdd     Action = "add"
+	ActionGet     Action = "get"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionList    Action = "list"
+	ActionHistory Action = "history"
+	ActionUndo    Action = "undo"
+	ActionImport  Action = "import"
+
+	ActionProjects      Action = "projects"
+	ActionCreateProject Action = "create_project"
+	ActionShare         Action = "share"
 )
 
 type Task struct {
@@ -40,22 +50,110 @@ type Task struct {
 	Title     string `json:"title" jsonschema_description:"title of the task"`
 	Content   string `json:"content" jsonschema_description:"content of the task"`
 	Completed bool   `json:"completed" jsonschema_description:"completed status of the task"`
-	Deadline  string `json:"deadline" jsonschema_description:"deadline of the task"`
-	IsDeleted bool   `json:"is_deleted" jsonschema:"-"`
+	Deadline  string `json:"deadline" jsonschema_description:"deadline of the task, RFC3339 or natural language such as 'tomorrow 18:00', 'next friday', 'in 2 hours', '明天下午3点'"`
+	Repeat    string `json:"repeat,omitempty" jsonschema_description:"recurrence rule: daily, weekdays, weekly, monthly, every N days/weeks/months, or a cron expression 'minute hour day month weekday'; none clears it"`
+	// IsDeleted 只用于读取旧版 tasks.jsonl，删除的 task 不再保留在存储中
+	IsDeleted bool `json:"is_deleted,omitempty" jsonschema:"-"`
+
+	Priority Priority `json:"priority,omitempty" jsonschema_description:"priority of the task, enum:low,medium,high,urgent, medium by default"`
+	Tags     []string `json:"tags,omitempty" jsonschema_description:"tags of the task, an empty list clears them on update"`
+	ParentID string   `json:"parent_id,omitempty" jsonschema_description:"id of the parent task if this is a subtask; none moves it to the top level on update"`
+
+	// DueAt 是工具解析 Deadline 得到的截止时间
+	DueAt *time.Time `json:"due_at,omitempty" jsonschema_description:"deadline resolved by the tool, read only"`
+	// RemindedFor 记录已经提醒过的截止时间，截止时间变化后会再次提醒
+	RemindedFor *time.Time `json:"reminded_for,omitempty" jsonschema:"-"`
+	// RepeatDay 是按月重复的 task 每月的日期，截止时间在小月被提前到月末后仍按它计算下一次
+	RepeatDay int `json:"repeat_day,omitempty" jsonschema:"-"`
 
 	CreatedAt string `json:"created_at" jsonschema_description:"created time of the task"`
+	UpdatedAt string `json:"updated_at,omitempty" jsonschema_description:"last updated time of the task"`
+}
+
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// rank 返回优先级的排序权重，未设置优先级的旧数据视为 medium
+func (p Priority) rank() int {
+	switch p {
+	case PriorityLow:
+		return 0
+	case PriorityHigh:
+		return 2
+	case PriorityUrgent:
+		return 3
+	default:
+		return 1
+	}
 }
 
+func (p Priority) valid() bool {
+	switch p {
+	case "", PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// NoParent moves a subtask to the top level on update.
+const NoParent = "none"
+
+// RepeatNone clears the recurrence rule of a task on update.
+const RepeatNone = "none"
+
+const (
+	DueOverdue  = "overdue"
+	DueUpcoming = "upcoming"
+
+	defaultUpcomingHours = 24
+)
+
+type SortKey string
+
+const (
+	SortCreated  SortKey = "created"
+	SortDeadline SortKey = "deadline"
+	SortPriority SortKey = "priority"
+	SortUpdated  SortKey = "updated"
+)
+
 type TaskRequest struct {
-	Action Action      `json:"action" jsonschema_description:"action to perform, enum:add,update,delete,list"`
-	Task   *Task       `json:"task" jsonschema_description:"task to add, update, or delete"`
+	Action Action      `json:"action" jsonschema_description:"action to perform, enum:add,get,update,delete,list,history,undo,import,projects,create_project,share; history returns the change history of a task, undo reverts the last change, import merges the VTODO and VEVENT entries of an iCalendar into tasks by UID, projects lists the shared projects of the user, create_project creates one owned by the user, share sets the role of a member"`
+	Task   *Task       `json:"task" jsonschema_description:"task to add, get, update, delete, or get history of"`
 	List   *ListParams `json:"list" jsonschema_description:"list parameters"`
+	ICS    string      `json:"ics,omitempty" jsonschema_description:"iCalendar content to import"`
+
+	ProjectID string       `json:"project_id,omitempty" jsonschema_description:"id of the shared project to operate on, the user's own tasks when empty"`
+	Project   *Project     `json:"project,omitempty" jsonschema_description:"project to create, only the name is used"`
+	Share     *ShareParams `json:"share,omitempty" jsonschema_description:"member to share the project with"`
+}
+
+type ShareParams struct {
+	User string `json:"user" jsonschema_description:"user id of the member"`
+	Role Role   `json:"role" jsonschema_description:"role of the member, enum:owner,editor,viewer,none; owners manage members, editors change tasks, viewers read them, none removes the member"`
 }
 
 type ListParams struct {
 	Query  string `json:"query" jsonschema_description:"query to search"`
 	IsDone *bool  `json:"is_done" jsonschema_description:"filter by completed status"`
 	Limit  *int   `json:"limit" jsonschema_description:"limit the number of results"`
+
+	Semantic bool `json:"semantic" jsonschema_description:"rank tasks by meaning of the query instead of substring match, e.g. to find anything about a topic; returns 10 results unless limit is set"`
+
+	Due         string `json:"due" jsonschema_description:"filter unfinished tasks by deadline, sorted by deadline, enum:overdue,upcoming"`
+	WithinHours *int   `json:"within_hours" jsonschema_description:"window of the upcoming filter in hours, 24 by default"`
+
+	Tags       []string   `json:"tags" jsonschema_description:"only tasks with all of these tags"`
+	Priorities []Priority `json:"priorities" jsonschema_description:"only tasks with one of these priorities"`
+	ParentID   *string    `json:"parent_id" jsonschema_description:"only subtasks of this task; an empty string lists top-level tasks"`
+	SortBy     SortKey    `json:"sort_by" jsonschema_description:"sort key, enum:created,deadline,priority,updated; unfinished tasks always come first; created by default, deadline with the due filter"`
+	Offset     int        `json:"offset" jsonschema_description:"number of results to skip, for pagination"`
 }
 
 type TaskResponse struct {
@@ -63,6 +161,10 @@ type TaskResponse struct {
 
 	TaskList []*Task `json:"task_list" jsonschema_description:"list of tasks"`
 
+	History []*Event `json:"history,omitempty" jsonschema_description:"change events of the history and undo actions"`
+
+	Projects []*Project `json:"projects,omitempty" jsonschema_description:"projects returned by the projects, create_project and share actions"`
+
 	Error string `json:"error" jsonschema_description:"error message"`
 }
 
@@ -71,12 +173,17 @@ type TaskToolImpl struct {
 }
 
 type TaskToolConfig struct {
+	// Storage 设置后所有请求都使用这个存储，不区分用户，也不支持 project
 	Storage *Storage
+	// Namespaces 按请求 ctx 中的用户和请求的 project 选择存储
+	Namespaces *Namespaces
+	// Embedder 为 task 开启语义搜索，为空时语义搜索使用本地的特征哈希向量
+	Embedder embedding.Embedder
 }
 
 func defaultTaskToolConfig(ctx context.Context) (*TaskToolConfig, error) {
 	config := &TaskToolConfig{
-		Storage: GetDefaultStorage(),
+		Namespaces: GetDefaultNamespaces(),
 	}
 	return config, nil
 }
@@ -90,9 +197,19 @@ func NewTaskToolImpl(ctx context.Context, config *TaskToolConfig) (*TaskToolImpl
 		}
 	}
 
-	if config.Storage == nil {
+	if config.Storage == nil && config.Namespaces == nil {
 		return nil, fmt.Errorf("storage cannot be empty")
 	}
+	if config.Embedder != nil {
+		if config.Storage != nil {
+			err = config.Storage.EnableSearch(config.Embedder)
+		} else {
+			err = config.Namespaces.EnableSearch(config.Embedder)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	t := &TaskToolImpl{config: config}
 
@@ -100,18 +217,10 @@ func NewTaskToolImpl(ctx context.Context, config *TaskToolConfig) (*TaskToolImpl
 }
 
 func NewTaskTool(ctx context.Context, config *TaskToolConfig) (tn tool.BaseTool, err error) {
-	if config == nil {
-		config, err = defaultTaskToolConfig(ctx)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	if config.Storage == nil {
-		return nil, fmt.Errorf("storage cannot be empty")
+	t, err := NewTaskToolImpl(ctx, config)
+	if err != nil {
+		return nil, err
 	}
-
-	t := &TaskToolImpl{config: config}
 	tn, err = t.ToEinoTool()
 	if err != nil {
 		return nil, err
@@ -120,11 +229,84 @@ func NewTaskTool(ctx context.Context, config *TaskToolConfig) (tn tool.BaseTool,
 }
 
 func (t *TaskToolImpl) ToEinoTool() (tool.BaseTool, error) {
-	return utils.InferTool("task_manager", "task manager tool, you can add, get, update, delete, list tasks", t.Invoke)
+	return utils.InferTool("task_manager", "task manager tool, you can add, get, update, delete, list tasks of the user and of the projects shared with the user", t.Invoke)
+}
+
+// storage 返回请求要访问的存储，只读的 action 需要 viewer 角色，其余需要 editor 角色
+func (t *TaskToolImpl) storage(ctx context.Context, req *TaskRequest) (*Storage, error) {
+	if t.config.Storage != nil {
+		if req.ProjectID != "" {
+			return nil, fmt.Errorf("projects are not supported by this task manager")
+		}
+		return t.config.Storage, nil
+	}
+	need := RoleEditor
+	switch req.Action {
+	case ActionGet, ActionList, ActionHistory:
+		need = RoleViewer
+	}
+	return t.config.Namespaces.Resolve(ctx, req.ProjectID, need)
+}
+
+// invokeProject 处理 project 相关的 action
+func (t *TaskToolImpl) invokeProject(ctx context.Context, req *TaskRequest) (*TaskResponse, error) {
+	res := &TaskResponse{}
+	if t.config.Namespaces == nil {
+		res.Status = "error"
+		res.Error = "projects are not supported by this task manager"
+		return res, nil
+	}
+
+	switch req.Action {
+	case ActionProjects:
+		res.Projects = t.config.Namespaces.Projects(ctx)
+
+	case ActionCreateProject:
+		if req.Project == nil || req.Project.Name == "" {
+			res.Status = "error"
+			res.Error = "project name is required for create_project action"
+			return res, nil
+		}
+		project, err := t.config.Namespaces.CreateProject(ctx, req.Project.Name)
+		if err != nil {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("failed to create project: %v", err)
+			return res, nil
+		}
+		res.Projects = []*Project{project}
+
+	case ActionShare:
+		if req.ProjectID == "" || req.Share == nil {
+			res.Status = "error"
+			res.Error = "project_id and share are required for share action"
+			return res, nil
+		}
+		project, err := t.config.Namespaces.Share(ctx, req.ProjectID, req.Share.User, req.Share.Role)
+		if err != nil {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("failed to share project: %v", err)
+			return res, nil
+		}
+		res.Projects = []*Project{project}
+	}
+
+	res.Status = "success"
+	return res, nil
 }
 
 func (t *TaskToolImpl) Invoke(ctx context.Context, req *TaskRequest) (res *TaskResponse, err error) {
+	switch req.Action {
+	case ActionProjects, ActionCreateProject, ActionShare:
+		return t.invokeProject(ctx, req)
+	}
+
 	res = &TaskResponse{}
+	storage, err := t.storage(ctx, req)
+	if err != nil {
+		res.Status = "error"
+		res.Error = err.Error()
+		return res, nil
+	}
 
 	switch req.Action {
 	case ActionAdd:
@@ -138,8 +320,18 @@ func (t *TaskToolImpl) Invoke(ctx context.Context, req *TaskRequest) (res *TaskR
 			res.Error = "title is required"
 			return res, nil
 		}
+		if err := resolveSchedule(req.Task, time.Now()); err != nil {
+			res.Status = "error"
+			res.Error = err.Error()
+			return res, nil
+		}
+		if err := normalizeTask(req.Task); err != nil {
+			res.Status = "error"
+			res.Error = err.Error()
+			return res, nil
+		}
 		req.Task.ID = uuid.New().String()
-		if err := t.config.Storage.Add(req.Task); err != nil {
+		if err := storage.Add(req.Task); err != nil {
 			res.Status = "error"
 			res.Error = fmt.Sprintf("failed to add task: %v", err)
 			return res, nil
@@ -157,12 +349,99 @@ func (t *TaskToolImpl) Invoke(ctx context.Context, req *TaskRequest) (res *TaskR
 			res.Error = "id is required"
 			return res, nil
 		}
-		if err := t.config.Storage.Update(req.Task); err != nil {
+		if err := resolveSchedule(req.Task, time.Now()); err != nil {
+			res.Status = "error"
+			res.Error = err.Error()
+			return res, nil
+		}
+		if err := normalizeTask(req.Task); err != nil {
+			res.Status = "error"
+			res.Error = err.Error()
+			return res, nil
+		}
+		if err := storage.Update(req.Task); err != nil {
 			res.Status = "error"
 			res.Error = fmt.Sprintf("failed to update task: %v", err)
 			return res, nil
 		}
-		res.TaskList = []*Task{req.Task}
+		// 返回更新后的完整 task，循环任务完成后会带上下一次的截止时间
+		updated, err := storage.Get(req.Task.ID)
+		if err != nil {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("failed to get task: %v", err)
+			return res, nil
+		}
+		res.TaskList = []*Task{updated}
+
+	case ActionGet:
+		if req.Task == nil || req.Task.ID == "" {
+			res.Status = "error"
+			res.Error = "task id is required for get action"
+			return res, nil
+		}
+		task, err := storage.Get(req.Task.ID)
+		if err != nil {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("failed to get task: %v", err)
+			return res, nil
+		}
+		res.TaskList = []*Task{task}
+
+	case ActionHistory:
+		if req.Task == nil || req.Task.ID == "" {
+			res.Status = "error"
+			res.Error = "task id is required for history action"
+			return res, nil
+		}
+		history, err := storage.History(req.Task.ID)
+		if err != nil {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("failed to get task history: %v", err)
+			return res, nil
+		}
+		res.History = history
+
+	case ActionUndo:
+		events, err := storage.Undo()
+		if err != nil {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("failed to undo: %v", err)
+			return res, nil
+		}
+		res.History = events
+		// 返回撤销后仍存在的 task
+		for _, event := range events {
+			if event.Task != nil {
+				res.TaskList = append(res.TaskList, event.Task)
+			}
+		}
+
+	case ActionImport:
+		if req.ICS == "" {
+			res.Status = "error"
+			res.Error = "ics is required for import action"
+			return res, nil
+		}
+		tasks, err := ParseICS([]byte(req.ICS), time.Local)
+		if err != nil {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("failed to parse ics: %v", err)
+			return res, nil
+		}
+		for _, task := range tasks {
+			if err := normalizeTask(task); err != nil {
+				res.Status = "error"
+				res.Error = err.Error()
+				return res, nil
+			}
+		}
+		imported, err := storage.Import(tasks)
+		if err != nil {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("failed to import tasks: %v", err)
+			return res, nil
+		}
+		res.TaskList = imported
 
 	case ActionDelete:
 		if req.Task == nil || req.Task.ID == "" {
@@ -170,7 +449,7 @@ func (t *TaskToolImpl) Invoke(ctx context.Context, req *TaskRequest) (res *TaskR
 			res.Error = "task id is required for delete action"
 			return res, nil
 		}
-		if err := t.config.Storage.Delete(req.Task.ID); err != nil {
+		if err := storage.Delete(req.Task.ID); err != nil {
 			res.Status = "error"
 			res.Error = fmt.Sprintf("failed to delete task: %v", err)
 			return res, nil
@@ -180,7 +459,24 @@ func (t *TaskToolImpl) Invoke(ctx context.Context, req *TaskRequest) (res *TaskR
 		if req.List == nil {
 			req.List = &ListParams{}
 		}
-		tasks, err := t.config.Storage.List(req.List)
+		if req.List.Due != "" && req.List.Due != DueOverdue && req.List.Due != DueUpcoming {
+			res.Status = "error"
+			res.Error = fmt.Sprintf("unknown due filter: %s", req.List.Due)
+			return res, nil
+		}
+		switch req.List.SortBy {
+		case "", SortCreated, SortDeadline, SortPriority, SortUpdated:
+		default:
+			res.Status = "error"
+			res.Error = fmt.Sprintf("unknown sort key: %s", req.List.SortBy)
+			return res, nil
+		}
+		var tasks []*Task
+		if req.List.Semantic {
+			tasks, err = storage.Search(ctx, req.List)
+		} else {
+			tasks, err = storage.List(req.List)
+		}
 		if err != nil {
 			res.Status = "error"
 			res.Error = fmt.Sprintf("failed to list tasks: %v", err)
@@ -191,8 +487,60 @@ func (t *TaskToolImpl) Invoke(ctx context.Context, req *TaskRequest) (res *TaskR
 	default:
 		res.Status = "error"
 		res.Error = fmt.Sprintf("unknown action: %s", req.Action)
+		return res, nil
 	}
 
 	res.Status = "success"
 	return res, nil
 }
+
+// resolveSchedule 将 task 的 Deadline 解析为 DueAt 并规范为 RFC3339，同时校验循环规则。
+func resolveSchedule(task *Task, now time.Time) error {
+	task.DueAt = nil
+	task.RemindedFor = nil
+	task.RepeatDay = 0
+	if task.Deadline != "" {
+		due, err := ParseDeadline(task.Deadline, now)
+		if err != nil {
+			return err
+		}
+		task.DueAt = &due
+		task.Deadline = due.Format(time.RFC3339)
+	}
+	if task.Repeat != "" && task.Repeat != RepeatNone {
+		if _, err := ParseRecurrence(task.Repeat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeTask 校验优先级，并去掉空白和重复的标签
+func normalizeTask(task *Task) error {
+	task.Priority = Priority(strings.ToLower(strings.TrimSpace(string(task.Priority))))
+	if !task.Priority.valid() {
+		return fmt.Errorf("unknown priority: %s", task.Priority)
+	}
+	if task.Tags == nil {
+		return nil
+	}
+	tags := make([]string, 0, len(task.Tags))
+	for _, tag := range task.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	task.Tags = tags
+	return nil
+}
+
+// hasTag 判断 tags 是否包含 tag，忽略大小写
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
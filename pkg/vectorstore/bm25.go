@@ -0,0 +1,105 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vectorstore
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Tokenize splits text into lower case terms. Latin letters, digits and '_'
+// form words, so identifiers like AddLambdaNode are kept as whole terms while
+// '.' splits qualified names, compose.AllPredecessor gives compose and
+// allpredecessor; CJK characters are indexed one term per character.
+func Tokenize(text string) []string {
+	var (
+		terms []string
+		word  strings.Builder
+	)
+	flush := func() {
+		if word.Len() > 0 {
+			terms = append(terms, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			terms = append(terms, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return terms
+}
+
+// bm25Scores scores each record against the query terms with Okapi BM25.
+func bm25Scores(queryTerms []string, records []*localRecord) []float64 {
+	scores := make([]float64, len(records))
+	if len(records) == 0 {
+		return scores
+	}
+
+	var totalLen int
+	df := make(map[string]int)
+	tfs := make([]map[string]int, len(records))
+	for i, rec := range records {
+		totalLen += len(rec.terms)
+		tf := make(map[string]int)
+		for _, term := range rec.terms {
+			tf[term]++
+		}
+		for term := range tf {
+			df[term]++
+		}
+		tfs[i] = tf
+	}
+	avgLen := float64(totalLen) / float64(len(records))
+	if avgLen == 0 {
+		return scores
+	}
+
+	n := float64(len(records))
+	for _, term := range queryTerms {
+		if df[term] == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+		for i, rec := range records {
+			tf := float64(tfs[i][term])
+			if tf == 0 {
+				continue
+			}
+			docLen := float64(len(rec.terms))
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*docLen/avgLen))
+		}
+	}
+
+	return scores
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	es8indexer "github.com/cloudwego/eino-ext/components/indexer/es8"
+	es8retriever "github.com/cloudwego/eino-ext/components/retriever/es8"
+	"github.com/cloudwego/eino-ext/components/retriever/es8/search_mode"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/create"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/exists"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+type esStore struct {
+	config *Config
+	client *elasticsearch.Client
+}
+
+func newESStore(ctx context.Context, config *Config) (*esStore, error) {
+	if config.ES == nil || config.ES.URL == "" {
+		return nil, fmt.Errorf("es url cannot be empty")
+	}
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{config.ES.URL},
+		Username:  config.ES.Username,
+		Password:  config.ES.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new es8 client failed: %w", err)
+	}
+
+	return &esStore{config: config, client: client}, nil
+}
+
+func (s *esStore) NewRetriever(ctx context.Context, config *RetrieverConfig) (retriever.Retriever, error) {
+	if config == nil {
+		config = &RetrieverConfig{}
+	}
+	if config.TopK <= 0 {
+		config.TopK = 5
+	}
+
+	var searchMode es8retriever.SearchMode
+	switch config.SearchMode {
+	case "", SearchModeDense:
+		searchMode = search_mode.SearchModeDenseVectorSimilarity(
+			search_mode.DenseVectorSimilarityTypeCosineSimilarity,
+			FieldContentVector,
+		)
+	case SearchModeKeyword:
+		// match query on a text field is scored by BM25
+		searchMode = search_mode.SearchModeExactMatch(FieldContent)
+	default:
+		return nil, fmt.Errorf("unknown search mode: %s", config.SearchMode)
+	}
+
+	return es8retriever.NewRetriever(ctx, &es8retriever.RetrieverConfig{
+		Client:       s.client,
+		Index:        s.config.Index,
+		TopK:         config.TopK,
+		SearchMode:   searchMode,
+		ResultParser: parseHit,
+		Embedding:    s.config.Embedding,
+	})
+}
+
+func (s *esStore) NewIndexer(ctx context.Context, config *IndexerConfig) (indexer.Indexer, error) {
+	if config == nil {
+		config = &IndexerConfig{}
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 10
+	}
+
+	// create index if needed.
+	if err := s.createIndex(ctx); err != nil {
+		return nil, fmt.Errorf("create es8 index failed: %w", err)
+	}
+
+	return es8indexer.NewIndexer(ctx, &es8indexer.IndexerConfig{
+		Client:    s.client,
+		Index:     s.config.Index,
+		BatchSize: config.BatchSize,
+		DocumentToFields: func(ctx context.Context, doc *schema.Document) (field2Value map[string]es8indexer.FieldValue, err error) {
+			metadataBytes, err := json.Marshal(doc.MetaData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+			}
+			return map[string]es8indexer.FieldValue{
+				FieldContent: {
+					Value:    doc.Content,
+					EmbedKey: FieldContentVector, // vectorize doc content and save vector to field "content_dense_vector"
+				},
+				FieldMeta: {
+					Value: string(metadataBytes),
+				},
+			}, nil
+		},
+		Embedding: s.config.Embedding,
+	})
+}
+
+// Delete removes documents by id, the ids are escaped since the client writes them into
+// the url path as is, and ids such as repo/path#0 would add path segments or a fragment.
+func (s *esStore) Delete(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		resp, err := s.client.Delete(s.config.Index, url.PathEscape(id), s.client.Delete.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("delete es8 document %s failed: %w", id, err)
+		}
+		resp.Body.Close()
+		if resp.IsError() && resp.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("delete es8 document %s failed: %s", id, resp.Status())
+		}
+	}
+	return nil
+}
+
+// createIndex creates the index with content, meta and a 1024 dims dense vector field
+// (same as the embedding dimensions) using cosine similarity.
+func (s *esStore) createIndex(ctx context.Context) error {
+	existsResp, err := exists.NewExistsFunc(s.client)(s.config.Index).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if existsResp {
+		return nil
+	}
+
+	_, err = create.NewCreateFunc(s.client)(s.config.Index).Request(&create.Request{
+		Mappings: &types.TypeMapping{
+			Properties: map[string]types.Property{
+				FieldContent: types.NewTextProperty(),
+				FieldMeta:    types.NewTextProperty(),
+				FieldContentVector: &types.DenseVectorProperty{
+					Dims:       of(1024), // same as embedding dimensions
+					Index:      of(true),
+					Similarity: of("cosine"),
+				},
+			},
+		},
+	}).Do(ctx)
+
+	return err
+}
+
+// parseHit converts an es hit to schema.Document.
+func parseHit(ctx context.Context, hit types.Hit) (doc *schema.Document, err error) {
+	doc = &schema.Document{
+		ID:       *hit.Id_,
+		Content:  "",
+		MetaData: map[string]any{},
+	}
+
+	var src map[string]any
+	if err = json.Unmarshal(hit.Source_, &src); err != nil {
+		return nil, err
+	}
+
+	for field, val := range src {
+		switch field {
+		case FieldContent:
+			doc.Content = val.(string)
+
+		case FieldContentVector:
+			var v []float64
+			for _, item := range val.([]interface{}) {
+				v = append(v, item.(float64))
+			}
+			doc.WithDenseVector(v)
+
+		case FieldMeta:
+			metadata, err := StringToMap(val.(string))
+			if err != nil {
+				return nil, err
+			}
+			doc.MetaData = metadata
+
+		default:
+			return nil, fmt.Errorf("unexpected field=%s, val=%v", field, val)
+		}
+	}
+
+	if hit.Score_ != nil {
+		doc.WithScore(float64(*hit.Score_))
+	}
+
+	return doc, nil
+}
+
+// StringToMap converts a json string to map[string]any
+func StringToMap(s string) (map[string]any, error) {
+	var result map[string]any
+	err := json.Unmarshal([]byte(s), &result)
+	return result, err
+}
+
+func of[T any](v T) *T {
+	return &v
+}
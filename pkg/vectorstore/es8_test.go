@@ -0,0 +1,63 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vectorstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestESStoreDelete(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		mu.Unlock()
+
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.EscapedPath(), "missing"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.Contains(r.URL.EscapedPath(), "broken"):
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	store, err := newESStore(context.Background(), &Config{Index: "idx", ES: &ESConfig{URL: server.URL}})
+	assert.NoError(t, err)
+
+	// 仓库文档的 id 包含 / 和 #，需要转义为一个路径段
+	assert.NoError(t, store.Delete(context.Background(), []string{"cloudwego/demo/README.md#0", "missing"}))
+	assert.Equal(t, []string{
+		"DELETE /idx/_doc/cloudwego%2Fdemo%2FREADME.md%230",
+		"DELETE /idx/_doc/missing",
+	}, paths)
+
+	assert.Error(t, store.Delete(context.Background(), []string{"broken"}))
+}
@@ -0,0 +1,27 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashingEmbedder(t *testing.T) {
+	ctx := context.Background()
+	e := NewHashingEmbedder(0)
+
+	vectors, err := e.EmbedStrings(ctx, []string{"graph branch", "graph branch", "graph loop", "chain lambda", ""})
+	assert.NoError(t, err)
+	assert.Len(t, vectors[0], DefaultHashingDimensions)
+	// 相同文本得到相同的单位向量
+	assert.Equal(t, vectors[0], vectors[1])
+	assert.InDelta(t, 1, CosineSimilarity(vectors[0], vectors[0]), 1e-9)
+	// 共享词项的文本更相似
+	assert.Greater(t, CosineSimilarity(vectors[0], vectors[2]), CosineSimilarity(vectors[0], vectors[3]))
+	assert.Len(t, vectors[4], DefaultHashingDimensions)
+
+	vectors, err = NewHashingEmbedder(8).EmbedStrings(ctx, []string{"graph"})
+	assert.NoError(t, err)
+	assert.Len(t, vectors[0], 8)
+}
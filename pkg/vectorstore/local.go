@@ -0,0 +1,383 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vectorstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+)
+
+var (
+	localIndexesMu sync.Mutex
+	// opened local indexes, keyed by file path, so that the retriever and
+	// the indexer in the same process share one in-memory copy.
+	localIndexes = map[string]*localIndex{}
+)
+
+// localStore is an embedded vector store, documents and vectors are kept in
+// memory and persisted as a jsonl file under Config.Dir, search is a brute
+// force cosine similarity (or BM25 for keyword search) scan which is good
+// enough for a few thousand chunks.
+type localStore struct {
+	config *Config
+	index  *localIndex
+}
+
+func newLocalStore(ctx context.Context, config *Config) (*localStore, error) {
+	if config.Dir == "" {
+		config.Dir = DefaultDir
+	}
+
+	idx, err := openLocalIndex(filepath.Join(config.Dir, config.Index+".jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &localStore{config: config, index: idx}, nil
+}
+
+func (s *localStore) NewRetriever(ctx context.Context, config *RetrieverConfig) (retriever.Retriever, error) {
+	if config == nil {
+		config = &RetrieverConfig{}
+	}
+	if config.TopK <= 0 {
+		config.TopK = 5
+	}
+	switch config.SearchMode {
+	case "", SearchModeDense, SearchModeKeyword:
+	default:
+		return nil, fmt.Errorf("unknown search mode: %s", config.SearchMode)
+	}
+	return &localRetriever{index: s.index, topK: config.TopK, searchMode: config.SearchMode, embedder: s.config.Embedding}, nil
+}
+
+func (s *localStore) Delete(ctx context.Context, ids []string) error {
+	records := make([]*localRecord, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, &localRecord{ID: id, Deleted: true})
+	}
+	return s.index.put(records)
+}
+
+func (s *localStore) NewIndexer(ctx context.Context, config *IndexerConfig) (indexer.Indexer, error) {
+	if config == nil {
+		config = &IndexerConfig{}
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 10
+	}
+	return &localIndexer{index: s.index, batchSize: config.BatchSize, embedder: s.config.Embedding}, nil
+}
+
+type localRecord struct {
+	ID      string         `json:"id"`
+	Content string         `json:"content"`
+	Meta    map[string]any `json:"meta"`
+	Vector  []float64      `json:"vector"`
+	// Deleted marks a tombstone line that removes the earlier record with the same id
+	Deleted bool `json:"deleted,omitempty"`
+
+	// terms of content, used by keyword search
+	terms []string
+}
+
+type localIndex struct {
+	mu       sync.RWMutex
+	filePath string
+	records  map[string]*localRecord
+}
+
+func openLocalIndex(filePath string) (*localIndex, error) {
+	localIndexesMu.Lock()
+	defer localIndexesMu.Unlock()
+
+	if idx, ok := localIndexes[filePath]; ok {
+		return idx, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	idx := &localIndex{
+		filePath: filePath,
+		records:  make(map[string]*localRecord),
+	}
+	if err := idx.load(); err != nil {
+		return nil, err
+	}
+
+	localIndexes[filePath] = idx
+	return idx, nil
+}
+
+func (idx *localIndex) load() error {
+	file, err := os.OpenFile(idx.filePath, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	// vectors make the lines long
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec localRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("failed to unmarshal record: %w", err)
+		}
+		// later lines overwrite earlier ones with the same id
+		if rec.Deleted {
+			delete(idx.records, rec.ID)
+			continue
+		}
+		rec.terms = Tokenize(rec.Content)
+		idx.records[rec.ID] = &rec
+	}
+
+	return scanner.Err()
+}
+
+func (idx *localIndex) put(records []*localRecord) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	file, err := os.OpenFile(idx.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("failed to marshal record: %w", err)
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+		if rec.Deleted {
+			delete(idx.records, rec.ID)
+			continue
+		}
+		rec.terms = Tokenize(rec.Content)
+		idx.records[rec.ID] = rec
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush file: %w", err)
+	}
+
+	return file.Sync()
+}
+
+func (idx *localIndex) search(vector []float64, topK int, threshold *float64) []*schema.Document {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	docs := make([]*schema.Document, 0, len(idx.records))
+	for _, rec := range idx.records {
+		score := CosineSimilarity(vector, rec.Vector)
+		if threshold != nil && score < *threshold {
+			continue
+		}
+		docs = append(docs, rec.toDocument().WithScore(score))
+	}
+
+	return topDocuments(docs, topK)
+}
+
+// searchKeyword scores records by BM25 of the query terms.
+func (idx *localIndex) searchKeyword(query string, topK int, threshold *float64) []*schema.Document {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	queryTerms := Tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	records := make([]*localRecord, 0, len(idx.records))
+	for _, rec := range idx.records {
+		records = append(records, rec)
+	}
+	scores := bm25Scores(queryTerms, records)
+
+	docs := make([]*schema.Document, 0, len(records))
+	for i, rec := range records {
+		if scores[i] <= 0 {
+			continue
+		}
+		if threshold != nil && scores[i] < *threshold {
+			continue
+		}
+		docs = append(docs, rec.toDocument().WithScore(scores[i]))
+	}
+
+	return topDocuments(docs, topK)
+}
+
+func topDocuments(docs []*schema.Document, topK int) []*schema.Document {
+	sort.SliceStable(docs, func(i, j int) bool {
+		if docs[i].Score() != docs[j].Score() {
+			return docs[i].Score() > docs[j].Score()
+		}
+		return docs[i].ID < docs[j].ID
+	})
+	if len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs
+}
+
+func (rec *localRecord) toDocument() *schema.Document {
+	meta := make(map[string]any, len(rec.Meta))
+	for k, v := range rec.Meta {
+		meta[k] = v
+	}
+	return &schema.Document{
+		ID:       rec.ID,
+		Content:  rec.Content,
+		MetaData: meta,
+	}
+}
+
+type localRetriever struct {
+	index      *localIndex
+	topK       int
+	searchMode SearchMode
+	embedder   embedding.Embedder
+}
+
+func (r *localRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	options := retriever.GetCommonOptions(&retriever.Options{
+		TopK:      &r.topK,
+		Embedding: r.embedder,
+	}, opts...)
+
+	if r.searchMode == SearchModeKeyword {
+		return r.index.searchKeyword(query, *options.TopK, options.ScoreThreshold), nil
+	}
+
+	if options.Embedding == nil {
+		return nil, fmt.Errorf("embedding cannot be empty")
+	}
+
+	vectors, err := options.Embedding.EmbedStrings(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query failed: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("invalid embedding result, expected 1 vector, got %d", len(vectors))
+	}
+
+	return r.index.search(vectors[0], *options.TopK, options.ScoreThreshold), nil
+}
+
+func (r *localRetriever) GetType() string {
+	return "LocalVectorStore"
+}
+
+type localIndexer struct {
+	index     *localIndex
+	batchSize int
+	embedder  embedding.Embedder
+}
+
+func (i *localIndexer) Store(ctx context.Context, docs []*schema.Document, opts ...indexer.Option) (ids []string, err error) {
+	options := indexer.GetCommonOptions(&indexer.Options{
+		Embedding: i.embedder,
+	}, opts...)
+	if options.Embedding == nil {
+		return nil, fmt.Errorf("embedding cannot be empty")
+	}
+
+	ids = make([]string, 0, len(docs))
+	for start := 0; start < len(docs); start += i.batchSize {
+		end := start + i.batchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		batch := docs[start:end]
+
+		texts := make([]string, 0, len(batch))
+		for _, doc := range batch {
+			texts = append(texts, doc.Content)
+		}
+		vectors, err := options.Embedding.EmbedStrings(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("embed documents failed: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return nil, fmt.Errorf("invalid embedding result, expected %d vectors, got %d", len(batch), len(vectors))
+		}
+
+		records := make([]*localRecord, 0, len(batch))
+		for j, doc := range batch {
+			if doc.ID == "" {
+				doc.ID = uuid.New().String()
+			}
+			records = append(records, &localRecord{
+				ID:      doc.ID,
+				Content: doc.Content,
+				Meta:    doc.MetaData,
+				Vector:  vectors[j],
+			})
+			ids = append(ids, doc.ID)
+		}
+		if err := i.index.put(records); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+func (i *localIndexer) GetType() string {
+	return "LocalVectorStore"
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, 0 if their
+// lengths differ or one of them is zero.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,108 @@
+package vectorstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// keywordEmbedder 按关键词出现与否生成向量，用于测试
+type keywordEmbedder struct {
+	keywords []string
+}
+
+func (e *keywordEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		v := make([]float64, len(e.keywords))
+		for i, kw := range e.keywords {
+			if strings.Contains(strings.ToLower(text), kw) {
+				v[i] = 1
+			}
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	embedder := &keywordEmbedder{keywords: []string{"graph", "chain", "retriever"}}
+
+	store, err := NewStore(ctx, &Config{Backend: BackendLocal, Dir: dir, Embedding: embedder})
+	assert.NoError(t, err)
+
+	idr, err := store.NewIndexer(ctx, &IndexerConfig{BatchSize: 2})
+	assert.NoError(t, err)
+	ids, err := idr.Store(ctx, []*schema.Document{
+		{ID: "1", Content: "build a graph", MetaData: map[string]any{"title": "Graph"}},
+		{ID: "2", Content: "build a chain"},
+		{ID: "3", Content: "use a retriever in a chain"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+	rtr, err := store.NewRetriever(ctx, &RetrieverConfig{TopK: 2})
+	assert.NoError(t, err)
+	docs, err := rtr.Retrieve(ctx, "how to use graph")
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+	assert.Equal(t, "1", docs[0].ID)
+	assert.Equal(t, "Graph", docs[0].MetaData["title"])
+	assert.InDelta(t, 1.0, docs[0].Score(), 1e-9)
+
+	// 重新打开同一目录，数据应从文件中恢复
+	localIndexesMu.Lock()
+	delete(localIndexes, (store.(*localStore)).index.filePath)
+	localIndexesMu.Unlock()
+
+	store, err = NewStore(ctx, &Config{Backend: BackendLocal, Dir: dir, Embedding: embedder})
+	assert.NoError(t, err)
+	rtr, err = store.NewRetriever(ctx, nil)
+	assert.NoError(t, err)
+	docs, err = rtr.Retrieve(ctx, "chain")
+	assert.NoError(t, err)
+	assert.Len(t, docs, 3)
+	assert.Contains(t, []string{"2", "3"}, docs[0].ID)
+
+	// 关键词检索应命中精确标识符
+	idr, err = store.NewIndexer(ctx, nil)
+	assert.NoError(t, err)
+	_, err = idr.Store(ctx, []*schema.Document{
+		{ID: "4", Content: "使用 compose.AllPredecessor 作为节点触发模式"},
+	})
+	assert.NoError(t, err)
+	rtr, err = store.NewRetriever(ctx, &RetrieverConfig{TopK: 1, SearchMode: SearchModeKeyword})
+	assert.NoError(t, err)
+	docs, err = rtr.Retrieve(ctx, "AllPredecessor 是什么")
+	assert.NoError(t, err)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, "4", docs[0].ID)
+
+	// 删除的文档不再被检索到，重新打开后也不会恢复
+	assert.NoError(t, store.Delete(ctx, []string{"2", "4", "missing"}))
+	localIndexesMu.Lock()
+	delete(localIndexes, (store.(*localStore)).index.filePath)
+	localIndexesMu.Unlock()
+	store, err = NewStore(ctx, &Config{Backend: BackendLocal, Dir: dir, Embedding: embedder})
+	assert.NoError(t, err)
+	rtr, err = store.NewRetriever(ctx, &RetrieverConfig{TopK: 10})
+	assert.NoError(t, err)
+	docs, err = rtr.Retrieve(ctx, "chain")
+	assert.NoError(t, err)
+	ids = nil
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	assert.ElementsMatch(t, []string{"1", "3"}, ids)
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"compose", "allpredecessor", "触", "发"}, Tokenize("compose.AllPredecessor 触发"))
+	assert.Equal(t, []string{"add_lambda_node", "v2"}, Tokenize("add_lambda_node(v2)"))
+}
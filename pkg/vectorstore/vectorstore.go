@@ -0,0 +1,129 @@
+/*
+ * Copyright 2025 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+)
+
+const (
+	DefaultIndexName = "eino_example_index"
+	DefaultDir       = "data/vectorstore"
+
+	FieldContent       = "content"
+	FieldContentVector = "content_dense_vector"
+	FieldMeta          = "meta"
+)
+
+type Backend string
+
+const (
+	BackendES8   Backend = "es8"
+	BackendLocal Backend = "local"
+)
+
+// Store is a vector store backend, it creates the retriever and indexer
+// components that read and write the same index.
+type Store interface {
+	NewRetriever(ctx context.Context, config *RetrieverConfig) (retriever.Retriever, error)
+	NewIndexer(ctx context.Context, config *IndexerConfig) (indexer.Indexer, error)
+	// Delete removes the documents with the given ids, unknown ids are ignored.
+	Delete(ctx context.Context, ids []string) error
+}
+
+type Config struct {
+	Backend Backend
+	Index   string
+
+	// Embedding is used to vectorize documents on store and queries on retrieve.
+	Embedding embedding.Embedder
+
+	// ES is required by BackendES8.
+	ES *ESConfig
+	// Dir is where BackendLocal persists its index files.
+	Dir string
+}
+
+type ESConfig struct {
+	URL      string
+	Username string
+	Password string
+}
+
+type SearchMode string
+
+const (
+	// SearchModeDense searches by cosine similarity of the content dense vector.
+	SearchModeDense SearchMode = "dense"
+	// SearchModeKeyword searches by BM25 full text match on the content field.
+	SearchModeKeyword SearchMode = "keyword"
+)
+
+type RetrieverConfig struct {
+	TopK       int
+	SearchMode SearchMode
+}
+
+type IndexerConfig struct {
+	BatchSize int
+}
+
+// ConfigFromEnv reads the vector store config from environment variables:
+//   - VECTOR_STORE_BACKEND: es8 (default) or local
+//   - VECTOR_STORE_INDEX: index name, default eino_example_index
+//   - VECTOR_STORE_DIR: data dir of the local backend, default data/vectorstore
+//   - ES_URL / ES_USERNAME / ES_PASSWORD: connection of the es8 backend
+func ConfigFromEnv() *Config {
+	config := &Config{
+		Backend: Backend(os.Getenv("VECTOR_STORE_BACKEND")),
+		Index:   os.Getenv("VECTOR_STORE_INDEX"),
+		Dir:     os.Getenv("VECTOR_STORE_DIR"),
+		ES: &ESConfig{
+			URL:      os.Getenv("ES_URL"),
+			Username: os.Getenv("ES_USERNAME"),
+			Password: os.Getenv("ES_PASSWORD"),
+		},
+	}
+	return config
+}
+
+func NewStore(ctx context.Context, config *Config) (Store, error) {
+	if config == nil {
+		config = ConfigFromEnv()
+	}
+	if config.Embedding == nil {
+		return nil, fmt.Errorf("embedding cannot be empty")
+	}
+	if config.Index == "" {
+		config.Index = DefaultIndexName
+	}
+
+	switch config.Backend {
+	case "", BackendES8:
+		return newESStore(ctx, config)
+	case BackendLocal:
+		return newLocalStore(ctx, config)
+	default:
+		return nil, fmt.Errorf("unknown vector store backend: %s", config.Backend)
+	}
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
+	"Eino-example/pkg/chatmodel"
 	"bufio"
 	"context"
 	"fmt"
-	"github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/schema"
 	"github.com/joho/godotenv"
@@ -142,15 +142,11 @@ func generateOutline(volumeNumber int) {
 	if err != nil {
 		panic(err)
 	}
-	// 读取环境变量
-	baseUrl := os.Getenv("OPENAI_BASE_URL")
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	modelName := os.Getenv("MODEL_NAME")
-	chatModel, err := openai.NewChatModel(context.Background(), &openai.ChatModelConfig{
-		Model:   modelName, // 使用的模型版本
-		APIKey:  apiKey,    // OpenAI API 密钥
-		BaseURL: baseUrl,
-	})
+	// 按 MODEL_PROFILES 创建聊天模型，主模型限流时切换到备用模型
+	chatModel, err := chatmodel.NewFromEnv(context.Background())
+	if err != nil {
+		panic(err)
+	}
 
 	streamResult, err := chatModel.Stream(context.Background(), messages)
 	reportStream(streamResult, volumeNumber)